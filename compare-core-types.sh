@@ -3,6 +3,7 @@
 set -xv
 set -euo pipefail
 
-go run cmd/json-finder/main.go compare \
+# specs are downloaded by kube/collect-swagger-data.sh into ./swagger-specs
+go run cmd/api-inspector/main.go swagger compare \
   --version=1.18.19,1.23.0 \
   --type="Service,ClusterRole,ClusterRoleBinding,ConfigMap,CronJob,CustomResourceDefinition,Deployment,Ingress,Job,Role,RoleBinding,Secret,ServiceAccount,StatefulSet"
\ No newline at end of file
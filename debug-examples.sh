@@ -4,9 +4,15 @@ set -xv
 set -euo pipefail
 
 
-# parse
-go run cmd/api-inspector/main.go swagger-debug parse --version 1.18.19
+# specs are downloaded by kube/collect-swagger-data.sh into ./swagger-specs
+go run cmd/api-inspector/main.go swagger kinds --version 1.18.19
 
-# go run cmd/api-inspector/main.go swagger-debug analyze-schema
+go run cmd/api-inspector/main.go swagger fields --version 1.18.19 --type apps/v1.Deployment --depth 3
 
-# go run cmd/api-inspector/main.go swagger-debug test-schema-parser
+go run cmd/api-inspector/main.go swagger resolve-ref --version 1.18.19 --ref '#/definitions/io.k8s.api.apps.v1.DeploymentSpec'
+
+go run cmd/api-inspector/main.go explain deployment.spec.strategy --version 1.18.19
+
+go run cmd/api-inspector/main.go explain apps/v1.Deployment spec --version 1.18.19 --recursive --depth 2
+
+go run cmd/api-inspector/main.go explain Deployment --version 1.18.19 -o markdown
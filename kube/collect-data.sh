@@ -11,7 +11,7 @@ for version in "${kind_images[@]}"
 do
   name="api-resources-$version"
   kind create cluster --image="kindest/node:$version" --name="$name"
-  kubectl api-resources -o wide > data/"$version"-api-resources.txt
+  kubectl api-resources -o wide > ./kind-data/"$version"-api-resources.txt
   kubectl api-versions > ./kind-data/"$version"-api-versions.txt
   kind delete cluster --name="$name"
 done
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+type ExplainArgs struct {
+	Spec       *SwaggerSpecArgs
+	Kind       string
+	FieldPath  string
+	ApiVersion string
+	Recursive  bool
+	Depth      int
+	Output     string
+}
+
+func SetupExplainCommand() *cobra.Command {
+	args := &ExplainArgs{Spec: &SwaggerSpecArgs{}}
+
+	command := &cobra.Command{
+		Use:   "explain <kind> [field.path]",
+		Short: "document a kind's fields from a stored swagger spec, like kubectl explain",
+		Long: `document a kind's fields from a stored swagger spec, like kubectl explain.
+
+The field path may also be given with the kind, kubectl style: 'deployment.spec.replicas'.`,
+		Example: `  explain Deployment spec.template --version 1.25
+  explain apps/v1.Deployment spec --version 1.25 --recursive
+  explain Deployment --version 1.25 -o markdown > deployment.md`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, as []string) {
+			args.Kind = as[0]
+			if len(as) > 1 {
+				args.FieldPath = as[1]
+			}
+			RunExplain(args)
+		},
+	}
+
+	args.Spec.AddFlags(command)
+
+	command.Flags().StringVar(&args.ApiVersion, "api-version", "", "apiVersion of the kind, example: apps/v1; needed when several group versions serve the kind")
+	command.Flags().BoolVar(&args.Recursive, "recursive", false, "if true, prints the names and types of all nested fields (text output only)")
+	command.Flags().IntVar(&args.Depth, "depth", -1, "maximum depth of nested fields to print with --recursive or markdown output; negative for no limit")
+	command.Flags().StringVarP(&args.Output, "output", "o", "text", "output format; one of [text, markdown]")
+
+	return command
+}
+
+func RunExplain(args *ExplainArgs) {
+	index, err := args.Spec.ReadIndex()
+	utils.DoOrDie(err)
+
+	kind, fieldPath := splitKindFieldPath(args.Kind, args.FieldPath)
+	if args.ApiVersion != "" {
+		kind = fmt.Sprintf("%s.%s", args.ApiVersion, kind)
+	}
+	depth := args.Depth
+	if args.Output == "text" && !args.Recursive {
+		depth = 1
+	}
+	explanation, err := index.Explain(kind, fieldPath, depth)
+	utils.DoOrDie(err)
+
+	switch args.Output {
+	case "text":
+		fmt.Printf("%s\n", explanation.RenderText(args.Recursive))
+	case "markdown":
+		fmt.Printf("%s", explanation.RenderMarkdown())
+	default:
+		utils.DoOrDie(errors.Errorf("invalid output format '%s'", args.Output))
+	}
+}
+
+// splitKindFieldPath accepts kubectl's `deployment.spec.replicas` form, as well
+// as a kind qualified by an apiVersion: `apps/v1.Deployment`
+func splitKindFieldPath(kind string, fieldPath string) (string, string) {
+	if fieldPath != "" || strings.Contains(kind, "/") {
+		return kind, fieldPath
+	}
+	pieces := strings.SplitN(kind, ".", 2)
+	if len(pieces) == 1 {
+		return kind, ""
+	}
+	return pieces[0], pieces[1]
+}
@@ -29,6 +29,18 @@ func SetupRootCommand() *cobra.Command {
 
 	command.AddCommand(SetupVersionCommand())
 	command.AddCommand(SetupAnalyzeYamlCommand())
+	command.AddCommand(SetupResourceReportCommand())
+	command.AddCommand(SetupImageReportCommand())
+	command.AddCommand(SetupDriftCommand())
+	command.AddCommand(SetupInstallOrderCommand())
+	command.AddCommand(SetupImpactCommand())
+	command.AddCommand(SetupGraphCommand())
+	command.AddCommand(SetupExploreCommand())
+	command.AddCommand(SetupServeCommand())
+	command.AddCommand(SetupSwaggerCommand())
+	command.AddCommand(SetupExplainCommand())
+	command.AddCommand(SetupValuesDocCommand())
+	command.AddCommand(SetupValuesUsageCommand())
 
 	return command
 }
@@ -0,0 +1,261 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/kube-utils/pkg/swagger"
+	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+// SwaggerSpecArgs locates a swagger spec: either an explicit path, or a version
+// within a directory populated by kube/collect-swagger-data.sh
+type SwaggerSpecArgs struct {
+	SwaggerPath string
+	SwaggerDir  string
+	Version     string
+}
+
+func (s *SwaggerSpecArgs) AddFlags(command *cobra.Command) {
+	command.Flags().StringVar(&s.SwaggerPath, "swagger-path", "", "path to a kubernetes swagger.json; takes precedence over --version")
+	command.Flags().StringVar(&s.SwaggerDir, "swagger-dir", "./swagger-specs", "directory containing <version>-swagger-spec.json files")
+	command.Flags().StringVar(&s.Version, "version", "", "kubernetes version of the spec to load from --swagger-dir, example: 1.23.0; for a minor version such as 1.23, the latest patch found is used")
+}
+
+func (s *SwaggerSpecArgs) GetPath() (string, error) {
+	if s.SwaggerPath != "" {
+		return s.SwaggerPath, nil
+	}
+	if s.Version == "" {
+		return "", errors.Errorf("one of --swagger-path or --version is required")
+	}
+	return swagger.FindSpecPath(s.SwaggerDir, s.Version)
+}
+
+func (s *SwaggerSpecArgs) ReadIndex() (*swagger.Index, error) {
+	path, err := s.GetPath()
+	if err != nil {
+		return nil, err
+	}
+	return swagger.ReadIndex(path)
+}
+
+func SetupSwaggerCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "swagger",
+		Short: "inspect a kubernetes swagger spec",
+	}
+
+	command.AddCommand(setupSwaggerKindsCommand())
+	command.AddCommand(setupSwaggerFieldsCommand())
+	command.AddCommand(setupSwaggerResolveRefCommand())
+	command.AddCommand(setupSwaggerCompareCommand())
+
+	return command
+}
+
+func setupSwaggerKindsCommand() *cobra.Command {
+	args := &SwaggerSpecArgs{}
+
+	command := &cobra.Command{
+		Use:   "kinds",
+		Short: "list kinds with their group, version and definition",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			RunSwaggerKinds(args)
+		},
+	}
+
+	args.AddFlags(command)
+
+	return command
+}
+
+func RunSwaggerKinds(args *SwaggerSpecArgs) {
+	index, err := args.ReadIndex()
+	utils.DoOrDie(err)
+
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetAutoMergeCells(true)
+	table.SetHeader([]string{"Kind", "Group", "Version", "Definition"})
+	for _, kind := range index.Kinds() {
+		table.Append([]string{kind.GVK.Kind, kind.GVK.Group, kind.GVK.Version, kind.Definition})
+	}
+	table.Render()
+	fmt.Printf("%s\n", tableString)
+}
+
+type SwaggerFieldsArgs struct {
+	Spec  *SwaggerSpecArgs
+	Type  string
+	Depth int
+}
+
+func setupSwaggerFieldsCommand() *cobra.Command {
+	args := &SwaggerFieldsArgs{Spec: &SwaggerSpecArgs{}}
+
+	command := &cobra.Command{
+		Use:   "fields",
+		Short: "print a type's fields as a tree",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			RunSwaggerFields(args)
+		},
+	}
+
+	args.Spec.AddFlags(command)
+
+	command.Flags().StringVar(&args.Type, "type", "", "kind (Deployment), kind with apiVersion (apps/v1.Deployment) or definition name")
+	utils.DoOrDie(command.MarkFlagRequired("type"))
+
+	command.Flags().IntVar(&args.Depth, "depth", -1, "maximum depth of nested fields to print; negative for no limit")
+
+	return command
+}
+
+func RunSwaggerFields(args *SwaggerFieldsArgs) {
+	index, err := args.Spec.ReadIndex()
+	utils.DoOrDie(err)
+
+	definition, err := index.FindDefinition(args.Type)
+	utils.DoOrDie(err)
+
+	tree, err := index.BuildFieldTree(definition, args.Depth)
+	utils.DoOrDie(err)
+
+	fmt.Printf("%s\n", tree.RenderTree())
+}
+
+type SwaggerResolveRefArgs struct {
+	Spec  *SwaggerSpecArgs
+	Ref   string
+	Depth int
+}
+
+func setupSwaggerResolveRefCommand() *cobra.Command {
+	args := &SwaggerResolveRefArgs{Spec: &SwaggerSpecArgs{}}
+
+	command := &cobra.Command{
+		Use:   "resolve-ref",
+		Short: "print the schema a $ref points to, with nested $refs inlined",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			RunSwaggerResolveRef(args)
+		},
+	}
+
+	args.Spec.AddFlags(command)
+
+	command.Flags().StringVar(&args.Ref, "ref", "", "$ref to resolve, example: #/definitions/io.k8s.api.apps.v1.DeploymentSpec")
+	utils.DoOrDie(command.MarkFlagRequired("ref"))
+
+	command.Flags().IntVar(&args.Depth, "depth", 1, "levels of nested $refs to inline; negative for no limit")
+
+	return command
+}
+
+func RunSwaggerResolveRef(args *SwaggerResolveRefArgs) {
+	index, err := args.Spec.ReadIndex()
+	utils.DoOrDie(err)
+
+	schema, err := index.Dereference(&swagger.Schema{Ref: args.Ref}, args.Depth)
+	utils.DoOrDie(err)
+
+	json.PrintOptions(schema, &json.MarshalOptions{EscapeHTML: false, Indent: true, Sort: false})
+}
+
+type SwaggerCompareArgs struct {
+	SwaggerDir string
+	Versions   []string
+	Types      []string
+	Output     string
+}
+
+func setupSwaggerCompareCommand() *cobra.Command {
+	args := &SwaggerCompareArgs{}
+
+	command := &cobra.Command{
+		Use:   "compare",
+		Short: "compare kinds across kubernetes versions: added/removed/changed fields and apiVersions",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			RunSwaggerCompare(args)
+		},
+	}
+
+	command.Flags().StringVar(&args.SwaggerDir, "swagger-dir", "./swagger-specs", "directory containing <version>-swagger-spec.json files")
+
+	command.Flags().StringSliceVar(&args.Versions, "version", []string{}, "kubernetes versions to compare, oldest first; each is compared to the next")
+	utils.DoOrDie(command.MarkFlagRequired("version"))
+
+	command.Flags().StringSliceVar(&args.Types, "type", []string{}, "kinds to compare")
+	utils.DoOrDie(command.MarkFlagRequired("type"))
+
+	command.Flags().StringVarP(&args.Output, "output", "o", "table", "output format; one of [table, json]")
+
+	return command
+}
+
+func RunSwaggerCompare(args *SwaggerCompareArgs) {
+	if len(args.Versions) < 2 {
+		utils.DoOrDie(errors.Errorf("at least 2 versions required, found %+v", args.Versions))
+	}
+
+	indexes := map[string]*swagger.Index{}
+	for _, version := range args.Versions {
+		path, err := swagger.FindSpecPath(args.SwaggerDir, version)
+		utils.DoOrDie(err)
+		index, err := swagger.ReadIndex(path)
+		utils.DoOrDie(err)
+		indexes[version] = index
+	}
+
+	var comparisons []*swagger.VersionComparison
+	for i := 0; i+1 < len(args.Versions); i++ {
+		from, to := args.Versions[i], args.Versions[i+1]
+		comparison, err := swagger.CompareVersions(from, indexes[from], to, indexes[to], args.Types)
+		utils.DoOrDie(err)
+		comparisons = append(comparisons, comparison)
+	}
+
+	switch args.Output {
+	case "table":
+		for _, comparison := range comparisons {
+			fmt.Printf("%s -> %s:\n%s\n\n", comparison.From, comparison.To, SwaggerComparisonTable(comparison))
+		}
+	case "json":
+		json.PrintOptions(comparisons, &json.MarshalOptions{EscapeHTML: false, Indent: true, Sort: false})
+	default:
+		utils.DoOrDie(errors.Errorf("invalid output format '%s'", args.Output))
+	}
+}
+
+func SwaggerComparisonTable(comparison *swagger.VersionComparison) string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetAutoMergeCells(true)
+	table.SetHeader([]string{"Kind", "ApiVersion", "Change", "Field", "Old", "New"})
+	for _, kind := range comparison.Kinds {
+		for _, apiVersion := range kind.RemovedApiVersions {
+			table.Append([]string{kind.Kind, apiVersion, utils.DiffTypeRemove.Short(), "(apiVersion removed)", "", ""})
+		}
+		for _, apiVersion := range kind.AddedApiVersions {
+			table.Append([]string{kind.Kind, apiVersion, utils.DiffTypeAdd.Short(), "(apiVersion added)", "", ""})
+		}
+		for _, apiVersion := range kind.ApiVersions {
+			for _, change := range apiVersion.Changes {
+				table.Append([]string{kind.Kind, apiVersion.ApiVersion, change.Type.Short(), change.Field, change.Old, change.New})
+			}
+		}
+	}
+	table.Render()
+	return tableString.String()
+}
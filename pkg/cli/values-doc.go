@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/file"
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/kube-utils/pkg/helm"
+	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type ValuesDocArgs struct {
+	ValuesPath string
+	Output     string
+	ReadmePath string
+	Check      bool
+}
+
+func SetupValuesDocCommand() *cobra.Command {
+	args := &ValuesDocArgs{}
+
+	command := &cobra.Command{
+		Use:   "values-doc",
+		Short: "document a chart's values.yaml -- key, type, default and comments -- as markdown or json",
+		Long: fmt.Sprintf(`document a chart's values.yaml -- key, type, default and comments -- as markdown or json.
+
+With --readme, the markdown table replaces the README's text between
+'%s' and '%s'.`, helm.ValuesDocStartMarker, helm.ValuesDocEndMarker),
+		Args: cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			RunValuesDoc(args)
+		},
+	}
+
+	command.Flags().StringVar(&args.ValuesPath, "values-path", "", "path to values.yaml")
+	utils.DoOrDie(command.MarkFlagRequired("values-path"))
+
+	command.Flags().StringVarP(&args.Output, "output", "o", "markdown", "output format; one of [markdown, json]")
+	command.Flags().StringVar(&args.ReadmePath, "readme", "", "path to a README whose values section is updated with the markdown table")
+	command.Flags().BoolVar(&args.Check, "check", false, "with --readme: don't write the README, and fail if its values section is out of date")
+
+	return command
+}
+
+func RunValuesDoc(args *ValuesDocArgs) {
+	docs, err := helm.ReadValuesDocs(args.ValuesPath)
+	utils.DoOrDie(err)
+
+	if args.ReadmePath != "" {
+		readme, err := file.ReadString(args.ReadmePath)
+		utils.DoOrDie(err)
+		updated, err := helm.ReplaceReadmeSection(readme, helm.ValuesDocsMarkdown(docs))
+		utils.DoOrDie(err)
+		if args.Check {
+			if updated != readme {
+				utils.DoOrDie(errors.Errorf("values section of %s is out of date with %s; rerun values-doc without --check to update it", args.ReadmePath, args.ValuesPath))
+			}
+			fmt.Printf("values section of %s is up to date\n", args.ReadmePath)
+		} else {
+			utils.DoOrDie(file.WriteString(args.ReadmePath, updated, 0644))
+		}
+		return
+	}
+
+	switch args.Output {
+	case "markdown":
+		fmt.Printf("%s", helm.ValuesDocsMarkdown(docs))
+	case "json":
+		json.PrintOptions(docs, &json.MarshalOptions{EscapeHTML: false, Indent: true, Sort: false})
+	default:
+		utils.DoOrDie(errors.Errorf("invalid output format '%s'", args.Output))
+	}
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/kube-utils/pkg/helm"
+	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+type ValuesUsageArgs struct {
+	ChartPath string
+	Output    string
+	Check     bool
+}
+
+func SetupValuesUsageCommand() *cobra.Command {
+	args := &ValuesUsageArgs{}
+
+	command := &cobra.Command{
+		Use:   "values-usage",
+		Short: "find values.yaml keys unused by a chart's templates, and template .Values references missing from values.yaml",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			RunValuesUsage(args)
+		},
+	}
+
+	command.Flags().StringVar(&args.ChartPath, "chart-path", "", "path to a chart directory containing values.yaml and templates/")
+	utils.DoOrDie(command.MarkFlagRequired("chart-path"))
+
+	command.Flags().StringVarP(&args.Output, "output", "o", "table", "output format; one of [table, json]")
+	command.Flags().BoolVar(&args.Check, "check", false, "if true, fails if any unused or undefined values are found")
+
+	return command
+}
+
+func RunValuesUsage(args *ValuesUsageArgs) {
+	usage, err := helm.AnalyzeChartValues(args.ChartPath)
+	utils.DoOrDie(err)
+
+	switch args.Output {
+	case "table":
+		fmt.Printf("unused values:\n%s\n\n", unusedValuesTable(usage.Unused))
+		fmt.Printf("undefined values:\n%s\n\n", undefinedValuesTable(usage.Undefined))
+	case "json":
+		json.PrintOptions(usage, &json.MarshalOptions{EscapeHTML: false, Indent: true, Sort: false})
+	default:
+		utils.DoOrDie(errors.Errorf("invalid output format '%s'", args.Output))
+	}
+
+	if args.Check && (len(usage.Unused) > 0 || len(usage.Undefined) > 0) {
+		utils.DoOrDie(errors.Errorf("found %d unused and %d undefined values", len(usage.Unused), len(usage.Undefined)))
+	}
+}
+
+func unusedValuesTable(keys []*helm.ValueKey) string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetHeader([]string{"Key", "Location"})
+	for _, key := range keys {
+		table.Append([]string{key.Key(), key.Location()})
+	}
+	table.Render()
+	return tableString.String()
+}
+
+func undefinedValuesTable(references []*helm.ValuesReference) string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetHeader([]string{"Key", "Reference", "Location"})
+	for _, reference := range references {
+		table.Append([]string{reference.Key(), reference.Source, reference.Location()})
+	}
+	table.Render()
+	return tableString.String()
+}
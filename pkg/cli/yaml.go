@@ -2,12 +2,15 @@ package cli
 
 import (
 	"github.com/mattfenwick/kube-utils/pkg/kubernetes"
+	"github.com/mattfenwick/kube-utils/pkg/tui"
 	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"github.com/mattfenwick/kube-utils/pkg/webui"
 	"github.com/spf13/cobra"
+	"time"
 )
 
 func SetupAnalyzeYamlCommand() *cobra.Command {
-	args := &kubernetes.YamlAnalysisArgs{}
+	args := &kubernetes.YamlAnalysisArgs{Cluster: &kubernetes.ClusterArgs{}}
 
 	command := &cobra.Command{
 		Use:   "analyze-yaml",
@@ -18,15 +21,202 @@ func SetupAnalyzeYamlCommand() *cobra.Command {
 		},
 	}
 
-	command.Flags().StringVar(&args.ChartPath, "chart-path", "", "path to yaml file")
-	utils.DoOrDie(command.MarkFlagRequired("chart-path"))
+	command.Flags().StringVar(&args.ChartPath, "chart-path", "", "path to yaml file; one of --chart-path or --from-cluster is required")
+
+	command.Flags().BoolVar(&args.FromCluster, "from-cluster", false, "if true, analyzes deployments, statefulsets, jobs, cronjobs, secrets and configmaps read from a cluster instead of from --chart-path")
+	command.Flags().StringVar(&args.Cluster.Kubeconfig, "kubeconfig", "", "path to kubeconfig, used with --from-cluster; if empty, $KUBECONFIG or ~/.kube/config is used")
+	command.Flags().StringVar(&args.Cluster.Context, "context", "", "kubeconfig context, used with --from-cluster; if empty, the current context is used")
+	command.Flags().StringVarP(&args.Cluster.Namespace, "namespace", "n", "", "namespace, used with --from-cluster; if empty, the context's namespace is used")
 
 	command.Flags().BoolVar(&args.PrintSkipped, "print-skipped", true, "if true, prints skipped resources")
 	command.Flags().StringSliceVar(&args.Resources, "resources", []string{}, "pod-owning resources to print; if empty, all are printed")
 
+	command.Flags().StringVar(&args.TargetVersion, "target-version", "", "kubernetes version, example: 1.25; if set, objects whose apiVersion isn't served by this version are reported, and removed apiVersions cause a non-zero exit")
+	command.Flags().StringVar(&args.SwaggerDir, "swagger-dir", "./swagger-specs", "directory containing <version>-swagger-spec.json files, used with --target-version")
+	command.Flags().StringVar(&args.SwaggerPath, "swagger-path", "", "path to a swagger 2.0 or OpenAPI v3 spec, or a directory of OpenAPI v3 specs; takes precedence over --swagger-dir and --target-version for --validate")
+	command.Flags().StringVar(&args.ApiResourcesPath, "api-resources-path", "", "path to `kubectl api-resources -o wide` output for the target version; takes precedence over --swagger-dir.  This only lists preferred versions, so other versions are reported as 'not preferred' -- a warning -- unless --api-versions-path shows that they aren't served")
+	command.Flags().StringVar(&args.ApiVersionsPath, "api-versions-path", "", "path to `kubectl api-versions` output for the target version, used with --api-resources-path; apiVersions not listed are reported as removed")
+
+	command.Flags().BoolVar(&args.Validate, "validate", false, "if true, validates objects against the spec from --swagger-path or --target-version -- plus schemas of CustomResourceDefinitions in the input -- and fails on errors")
+
+	command.Flags().StringVar(&args.VulnerabilityReportsDir, "vulnerability-reports-dir", "", "directory of trivy or CycloneDX JSON reports; if set, reports are matched to images by digest -- or tag, for images without one -- and critical and high vulnerabilities are shown per workload")
+
 	return command
 }
 
 func RunAnalyzeYaml(args *kubernetes.YamlAnalysisArgs) {
 	kubernetes.RunYamlAnalysis(args)
 }
+
+func SetupResourceReportCommand() *cobra.Command {
+	args := &kubernetes.ResourceReportArgs{}
+
+	command := &cobra.Command{
+		Use:   "resource-report",
+		Short: "total cpu, memory, ephemeral storage and extended resource requests and limits per workload, namespace and overall",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			kubernetes.RunResourceReport(args)
+		},
+	}
+
+	command.Flags().StringVar(&args.ChartPath, "chart-path", "", "path to yaml file")
+	utils.DoOrDie(command.MarkFlagRequired("chart-path"))
+
+	command.Flags().StringVarP(&args.Output, "output", "o", "table", "output format; one of [table, csv, json]; csv has cpu in cores and other resources in bytes")
+
+	return command
+}
+
+func SetupImageReportCommand() *cobra.Command {
+	args := &kubernetes.ImageReportArgs{Policy: &kubernetes.ImagePolicy{}}
+
+	command := &cobra.Command{
+		Use:   "image-report",
+		Short: "list images by registry, repository, tag and digest, and check them against a policy",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			kubernetes.RunImageReport(args)
+		},
+	}
+
+	command.Flags().StringVar(&args.ChartPath, "chart-path", "", "path to yaml file")
+	utils.DoOrDie(command.MarkFlagRequired("chart-path"))
+
+	command.Flags().StringSliceVar(&args.Policy.AllowedRegistries, "allowed-registry", []string{}, "registries -- or registry/repository prefixes -- images may come from; if empty, all are allowed.  Docker Hub is docker.io")
+	command.Flags().BoolVar(&args.Policy.RequireDigest, "require-digest", false, "if true, images must be pinned by digest")
+	command.Flags().BoolVar(&args.Policy.DisallowLatest, "disallow-latest", false, "if true, images may not use the latest tag, explicitly or by default")
+	command.Flags().BoolVar(&args.Policy.RequireConsistentVersions, "require-consistent-versions", false, "if true, a repository may not be used with different tags or digests")
+	command.Flags().StringVarP(&args.Output, "output", "o", "table", "output format; one of [table, json]")
+
+	return command
+}
+
+func SetupDriftCommand() *cobra.Command {
+	args := &kubernetes.DriftArgs{Cluster: &kubernetes.ClusterArgs{}}
+
+	command := &cobra.Command{
+		Use:   "drift",
+		Short: "compare rendered manifests to the live objects in a cluster",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			kubernetes.RunDrift(args)
+		},
+	}
+
+	command.Flags().StringVar(&args.ChartPath, "chart-path", "", "path to yaml file")
+	utils.DoOrDie(command.MarkFlagRequired("chart-path"))
+
+	command.Flags().StringVar(&args.Cluster.Kubeconfig, "kubeconfig", "", "path to kubeconfig; if empty, $KUBECONFIG or ~/.kube/config is used")
+	command.Flags().StringVar(&args.Cluster.Context, "context", "", "kubeconfig context; if empty, the current context is used")
+	command.Flags().StringVarP(&args.Cluster.Namespace, "namespace", "n", "", "namespace for objects which don't set one; if empty, the context's namespace is used")
+
+	command.Flags().StringVar(&args.ConfigPath, "config-path", "", "path to a yaml file with `ignorePaths`: a list of {path, kind, name}, where path is a json pointer in which `*` matches any key or index, and kind and name are optional")
+	command.Flags().BoolVar(&args.IncludeAdditions, "include-additions", false, "if true, reports fields only in live objects; these are usually defaults set by the api server")
+	command.Flags().StringVarP(&args.Output, "output", "o", "table", "output format; one of [table, json]")
+	command.Flags().BoolVar(&args.Check, "check", false, "if true, fails if any objects are drifted, missing or of an unknown kind")
+
+	return command
+}
+
+func SetupInstallOrderCommand() *cobra.Command {
+	args := &kubernetes.InstallOrderArgs{}
+
+	command := &cobra.Command{
+		Use:   "install-order",
+		Short: "order resources by helm hook phase and weight, and find hooks using resources which don't exist yet",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			kubernetes.RunInstallOrder(args)
+		},
+	}
+
+	command.Flags().StringVar(&args.ChartPath, "chart-path", "", "path to yaml file")
+	utils.DoOrDie(command.MarkFlagRequired("chart-path"))
+
+	command.Flags().StringVar(&args.Event, "event", "install", "helm event whose hooks to order; one of [install, upgrade, delete, rollback]")
+	command.Flags().StringVarP(&args.Output, "output", "o", "table", "output format; one of [table, json]")
+	command.Flags().BoolVar(&args.Check, "check", false, "if true, fails if any problems are found")
+
+	return command
+}
+
+func SetupImpactCommand() *cobra.Command {
+	args := &kubernetes.ImpactArgs{}
+
+	command := &cobra.Command{
+		Use:   "impact <kind/name>",
+		Short: "find the workloads and containers affected by changing a resource, such as Secret/my-secret",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, as []string) {
+			args.Resource = as[0]
+			kubernetes.RunImpact(args)
+		},
+	}
+
+	command.Flags().StringVar(&args.ChartPath, "chart-path", "", "path to yaml file")
+	utils.DoOrDie(command.MarkFlagRequired("chart-path"))
+
+	command.Flags().StringVarP(&args.Output, "output", "o", "table", "output format; one of [table, json]")
+
+	return command
+}
+
+func SetupGraphCommand() *cobra.Command {
+	args := &kubernetes.GraphArgs{}
+
+	command := &cobra.Command{
+		Use:   "graph",
+		Short: "draw the dependencies between workloads, containers, secrets, config maps and rbac",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			kubernetes.RunGraph(args)
+		},
+	}
+
+	command.Flags().StringVar(&args.ChartPath, "chart-path", "", "path to yaml file")
+	utils.DoOrDie(command.MarkFlagRequired("chart-path"))
+
+	command.Flags().StringVarP(&args.Output, "output", "o", "html", "output format; one of [dot, svg, html, png]; html is a standalone page with pan, zoom and highlighting")
+
+	return command
+}
+
+func SetupExploreCommand() *cobra.Command {
+	args := &tui.ExploreArgs{}
+
+	command := &cobra.Command{
+		Use:   "explore",
+		Short: "browse workloads, containers, secrets, config maps and images in a terminal ui",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			tui.RunExplore(args)
+		},
+	}
+
+	command.Flags().StringVar(&args.ChartPath, "chart-path", "", "path to yaml file")
+	utils.DoOrDie(command.MarkFlagRequired("chart-path"))
+
+	return command
+}
+
+func SetupServeCommand() *cobra.Command {
+	args := &webui.ServeArgs{}
+
+	command := &cobra.Command{
+		Use:   "serve",
+		Short: "serve a local web ui and json api for a chart's analysis, re-analyzing when the chart changes",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			webui.RunServe(args)
+		},
+	}
+
+	command.Flags().StringVar(&args.ChartPath, "chart-path", "", "path to yaml file")
+	utils.DoOrDie(command.MarkFlagRequired("chart-path"))
+
+	command.Flags().StringVar(&args.Address, "address", "127.0.0.1:8080", "address to listen on")
+	command.Flags().DurationVar(&args.PollInterval, "poll-interval", time.Second, "how often to check the chart for changes")
+
+	return command
+}
@@ -0,0 +1,197 @@
+package graph
+
+import (
+	"github.com/mattfenwick/collections/pkg/set"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+)
+
+// NodeIDs returns the IDs of all nodes, including those only referenced by
+// edges, sorted
+func (g *Graph) NodeIDs() []string {
+	ids := set.FromSlice[string](nil)
+	for _, node := range g.AllNodes() {
+		ids.Add(node.ID)
+	}
+	for _, edge := range g.AllEdges() {
+		ids.Add(edge.From)
+		ids.Add(edge.To)
+	}
+	return slice.Sort(ids.ToSlice())
+}
+
+// Adjacency maps each node to the sorted, distinct nodes its edges point to --
+// the nodes it depends on -- ignoring edge classes
+func (g *Graph) Adjacency() map[string][]string {
+	return adjacency(g.NodeIDs(), g.AllEdges(), func(e *Edge) (string, string) { return e.From, e.To })
+}
+
+// ReverseAdjacency maps each node to the sorted, distinct nodes pointing to it
+// -- the nodes depending on it
+func (g *Graph) ReverseAdjacency() map[string][]string {
+	return adjacency(g.NodeIDs(), g.AllEdges(), func(e *Edge) (string, string) { return e.To, e.From })
+}
+
+func adjacency(ids []string, edges []*Edge, endpoints func(*Edge) (string, string)) map[string][]string {
+	neighbors := map[string]*set.Set[string]{}
+	for _, id := range ids {
+		neighbors[id] = set.FromSlice[string](nil)
+	}
+	for _, edge := range edges {
+		from, to := endpoints(edge)
+		neighbors[from].Add(to)
+	}
+	out := map[string][]string{}
+	for id, tos := range neighbors {
+		out[id] = slice.Sort(tos.ToSlice())
+	}
+	return out
+}
+
+// TopologicalSort orders nodes so that each comes after all the nodes it
+// depends on, breaking ties by ID.  It fails if the graph has cycles.
+func (g *Graph) TopologicalSort() ([]string, error) {
+	dependencies := g.Adjacency()
+	dependents := g.ReverseAdjacency()
+	remaining := map[string]int{}
+	var ready []string
+	for id, tos := range dependencies {
+		remaining[id] = len(tos)
+		if len(tos) == 0 {
+			ready = append(ready, id)
+		}
+	}
+	var order []string
+	for len(ready) > 0 {
+		ready = slice.Sort(ready)
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, id)
+		for _, dependent := range dependents[id] {
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	if len(order) < len(dependencies) {
+		return nil, errors.Errorf("unable to sort graph: found cycles %v", g.Cycles())
+	}
+	return order, nil
+}
+
+// Cycles returns the strongly connected components with more than one node,
+// or with an edge from a node to itself, each sorted by ID
+func (g *Graph) Cycles() [][]string {
+	dependencies := g.Adjacency()
+	// Tarjan's algorithm
+	index := 0
+	indexes := map[string]int{}
+	lowLinks := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	var cycles [][]string
+
+	var connect func(string)
+	connect = func(id string) {
+		indexes[id], lowLinks[id] = index, index
+		index++
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, to := range dependencies[id] {
+			if _, visited := indexes[to]; !visited {
+				connect(to)
+				lowLinks[id] = min(lowLinks[id], lowLinks[to])
+			} else if onStack[to] {
+				lowLinks[id] = min(lowLinks[id], indexes[to])
+			}
+		}
+		if lowLinks[id] != indexes[id] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == id {
+				break
+			}
+		}
+		selfLoop := slice.Any(func(to string) bool { return to == id }, dependencies[id])
+		if len(component) > 1 || selfLoop {
+			cycles = append(cycles, slice.Sort(component))
+		}
+	}
+
+	for _, id := range slice.Sort(maps.Keys(dependencies)) {
+		if _, visited := indexes[id]; !visited {
+			connect(id)
+		}
+	}
+	return slice.SortOn(func(c []string) string { return c[0] }, cycles)
+}
+
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Reachable returns the nodes which ids transitively depend on, sorted, not
+// including ids themselves
+func (g *Graph) Reachable(ids ...string) []string {
+	return slice.Sort(maps.Keys(searchPaths(g.Adjacency(), ids)))
+}
+
+// ReverseReachable returns the nodes which transitively depend on ids, sorted,
+// not including ids themselves
+func (g *Graph) ReverseReachable(ids ...string) []string {
+	return slice.Sort(maps.Keys(searchPaths(g.ReverseAdjacency(), ids)))
+}
+
+// DependentPaths finds the nodes which transitively depend on id, each with a
+// shortest path of edges from it to id
+func (g *Graph) DependentPaths(id string) map[string][]string {
+	paths := searchPaths(g.ReverseAdjacency(), []string{id})
+	// paths are found from id, so reverse them to follow edges
+	for dependent, path := range paths {
+		reversed := make([]string, len(path))
+		for i, node := range path {
+			reversed[len(path)-1-i] = node
+		}
+		paths[dependent] = reversed
+	}
+	return paths
+}
+
+// searchPaths runs a breadth-first search from starts, returning each reached
+// node -- other than starts -- with the path to it
+func searchPaths(neighbors map[string][]string, starts []string) map[string][]string {
+	paths := map[string][]string{}
+	visited := set.FromSlice(starts)
+	var queue []string
+	for _, start := range slice.Sort(append([]string{}, starts...)) {
+		paths[start] = []string{start}
+		queue = append(queue, start)
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range neighbors[id] {
+			if visited.Contains(next) {
+				continue
+			}
+			visited.Add(next)
+			paths[next] = append(append([]string{}, paths[id]...), next)
+			queue = append(queue, next)
+		}
+	}
+	for _, start := range starts {
+		delete(paths, start)
+	}
+	return paths
+}
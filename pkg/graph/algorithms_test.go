@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newEdgeGraph(edges ...[2]string) *Graph {
+	g := NewGraph("", "")
+	for _, edge := range edges {
+		g.AddEdge(&Edge{From: edge[0], To: edge[1]})
+	}
+	return g
+}
+
+func TestTopologicalSortAndCycles(t *testing.T) {
+	for _, testCase := range []struct {
+		Name   string
+		Graph  *Graph
+		Order  []string
+		Cycles [][]string
+	}{
+		{
+			Name:   "dag",
+			Graph:  newEdgeGraph([2]string{"w", "x"}, [2]string{"w", "y"}, [2]string{"x", "z"}, [2]string{"y", "z"}, [2]string{"v", "z"}),
+			Order:  []string{"z", "v", "x", "y", "w"},
+			Cycles: [][]string{},
+		},
+		{
+			Name:   "self-loop",
+			Graph:  newEdgeGraph([2]string{"a", "a"}, [2]string{"b", "a"}),
+			Cycles: [][]string{{"a"}},
+		},
+		{
+			Name:   "2-cycle",
+			Graph:  newEdgeGraph([2]string{"a", "b"}, [2]string{"b", "a"}, [2]string{"c", "a"}, [2]string{"c", "d"}),
+			Cycles: [][]string{{"a", "b"}},
+		},
+		{
+			Name:   "separate cycles",
+			Graph:  newEdgeGraph([2]string{"d", "c"}, [2]string{"c", "d"}, [2]string{"a", "b"}, [2]string{"b", "a"}, [2]string{"e", "e"}),
+			Cycles: [][]string{{"a", "b"}, {"c", "d"}, {"e"}},
+		},
+	} {
+		order, err := testCase.Graph.TopologicalSort()
+		if testCase.Order == nil {
+			if err == nil {
+				t.Errorf("%s: expected error, found order %v", testCase.Name, order)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %+v", testCase.Name, err)
+		} else if !reflect.DeepEqual(order, testCase.Order) {
+			t.Errorf("%s: expected order %v, found %v", testCase.Name, testCase.Order, order)
+		}
+		if cycles := testCase.Graph.Cycles(); !reflect.DeepEqual(cycles, testCase.Cycles) {
+			t.Errorf("%s: expected cycles %v, found %v", testCase.Name, testCase.Cycles, cycles)
+		}
+	}
+}
+
+func TestReachableAndDependentPaths(t *testing.T) {
+	g := newEdgeGraph([2]string{"w", "x"}, [2]string{"w", "y"}, [2]string{"x", "z"}, [2]string{"y", "z"}, [2]string{"z", "x"})
+	if found, expected := g.Reachable("w"), []string{"x", "y", "z"}; !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected reachable %v, found %v", expected, found)
+	}
+	if found, expected := g.ReverseReachable("z"), []string{"w", "x", "y"}; !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected reverse reachable %v, found %v", expected, found)
+	}
+	// shortest paths, following edges to z, without going around the x <-> z cycle
+	expected := map[string][]string{
+		"x": {"x", "z"},
+		"y": {"y", "z"},
+		"w": {"w", "x", "z"},
+	}
+	if found := g.DependentPaths("z"); !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected dependent paths %v, found %v", expected, found)
+	}
+}
+
+func TestSearchPathsExcludesStarts(t *testing.T) {
+	neighbors := map[string][]string{"a": {"b", "c"}, "b": {"a", "d"}, "c": {"d"}}
+	expected := map[string][]string{"c": {"a", "c"}, "d": {"b", "d"}}
+	if found := searchPaths(neighbors, []string{"b", "a"}); !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected %v, found %v", expected, found)
+	}
+}
@@ -7,77 +7,65 @@ import (
 	"strings"
 )
 
-type Graph struct {
-	Name      string
-	Label     string
-	Nodes     map[string][]string
-	Edges     map[string]map[string]bool
-	Subgraphs map[string]*Graph
-}
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
 
-func NewGraph(name string, label string) *Graph {
-	return &Graph{
-		Name:      name,
-		Label:     label,
-		Nodes:     map[string][]string{},
-		Edges:     map[string]map[string]bool{},
-		Subgraphs: map[string]*Graph{},
-	}
-}
-
-// AddNode adds a node
-// example: `  "%s" [color="%s",penwidth=2,style="dashed"];`
-func (g *Graph) AddNode(node string, config ...string) {
-	if _, ok := g.Edges[node]; !ok {
-		g.Nodes[node] = config
-		g.Edges[node] = map[string]bool{}
-	}
+// QuoteDot makes s a DOT string literal, so that IDs and labels may contain
+// quotes, backslashes and newlines
+func QuoteDot(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
 }
 
-func (g *Graph) AddEdge(from string, to string) {
-	if _, ok := g.Nodes[from]; !ok {
-		g.AddNode(from)
+// renderDotAttributes renders attributes sorted by key: `color="red", penwidth="2"`
+func renderDotAttributes(attrs Attributes) string {
+	var pieces []string
+	for _, key := range slice.Sort(maps.Keys(attrs)) {
+		pieces = append(pieces, fmt.Sprintf("%s=%s", key, QuoteDot(attrs[key])))
 	}
-	if _, ok := g.Nodes[to]; !ok {
-		g.AddNode(to)
-	}
-	g.Edges[from][to] = true
-}
-
-func (g *Graph) AddSubgraph(sub *Graph) {
-	g.Subgraphs[sub.Name] = sub
+	return strings.Join(pieces, ", ")
 }
 
-func (g *Graph) RenderDotBody(indent string) []string {
+func (g *Graph) RenderDotBody(indent string, theme *Theme, attrs Attributes) []string {
 	lines := []string{
-		fmt.Sprintf(`%s  label="%s";`, indent, g.Label),
+		fmt.Sprintf(`%s  label=%s;`, indent, QuoteDot(g.Label)),
+	}
+	merged := attrs.Merge(g.Attributes)
+	for _, key := range slice.Sort(maps.Keys(merged)) {
+		lines = append(lines, fmt.Sprintf(`%s  %s=%s;`, indent, key, QuoteDot(merged[key])))
 	}
 
-	for _, node := range slice.Sort(maps.Keys(g.Nodes)) {
-		config := g.Nodes[node]
-		lines = append(lines, fmt.Sprintf(`%s  "%s" [%s];`, indent, node, strings.Join(config, ", ")))
+	for _, id := range slice.Sort(maps.Keys(g.Nodes)) {
+		node := g.Nodes[id]
+		label := node.Label
+		if label == "" {
+			label = node.ID
+		}
+		nodeAttrs := theme.nodeAttributes(node.Class).Merge(node.Attributes, Attributes{"label": label})
+		lines = append(lines, fmt.Sprintf(`%s  %s [%s];`, indent, QuoteDot(id), renderDotAttributes(nodeAttrs)))
 	}
 	lines = append(lines, "")
 
 	for _, from := range slice.Sort(maps.Keys(g.Edges)) {
-		tos := g.Edges[from]
-		for to := range tos {
-			lines = append(lines, fmt.Sprintf(`%s  "%s" -> "%s" [color=red,penwidth=5,style="dashed"];`, indent, from, to))
+		for _, edge := range slice.SortOn(func(e *Edge) string { return e.To + "\x00" + e.Class }, g.Edges[from]) {
+			edgeAttrs := theme.edgeAttributes(edge.Class).Merge(edge.Attributes)
+			if edge.Label != "" {
+				edgeAttrs["label"] = edge.Label
+			}
+			lines = append(lines, fmt.Sprintf(`%s  %s -> %s [%s];`, indent, QuoteDot(edge.From), QuoteDot(edge.To), renderDotAttributes(edgeAttrs)))
 		}
 	}
 	lines = append(lines, "")
 
 	for _, key := range slice.Sort(maps.Keys(g.Subgraphs)) {
 		sub := g.Subgraphs[key]
-		lines = append(lines, fmt.Sprintf(`%s  subgraph "cluster_%s" {`, indent, sub.Name))
-		lines = append(lines, sub.RenderDotBody(indent+"  ")...)
+		lines = append(lines, fmt.Sprintf(`%s  subgraph %s {`, indent, QuoteDot("cluster_"+sub.Name)))
+		lines = append(lines, sub.RenderDotBody(indent+"  ", theme, theme.subgraphAttributes())...)
 		lines = append(lines, indent+"  }")
 	}
 	return lines
 }
 
 func (g *Graph) RenderAsDot() string {
-	lines := []string{fmt.Sprintf(`digraph "%s" {`, g.Name)}
-	lines = append(lines, g.RenderDotBody("")...)
+	lines := []string{fmt.Sprintf(`digraph %s {`, QuoteDot(g.Name))}
+	lines = append(lines, g.RenderDotBody("", g.Theme, g.Theme.graphAttributes())...)
 	return strings.Join(append(lines, "}"), "\n")
 }
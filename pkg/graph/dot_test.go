@@ -0,0 +1,21 @@
+package graph
+
+import (
+	"testing"
+)
+
+func TestQuoteDot(t *testing.T) {
+	for s, expected := range map[string]string{
+		"":                  `""`,
+		"plain":             `"plain"`,
+		`say "hi"`:          `"say \"hi\""`,
+		`back\slash`:        `"back\\slash"`,
+		"two\nlines":        `"two\nlines"`,
+		"windows\r\nlines":  `"windows\nlines"`,
+		`\"already escaped`: `"\\\"already escaped"`,
+	} {
+		if found := QuoteDot(s); found != expected {
+			t.Errorf("%q: expected %s, found %s", s, expected, found)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package graph
+
+import (
+	"github.com/mattfenwick/collections/pkg/slice"
+	"golang.org/x/exp/maps"
+)
+
+// Attributes are DOT attributes, such as `color` or `penwidth`.  Values are
+// quoted and escaped when rendered.
+type Attributes map[string]string
+
+// Merge returns a copy of a, overridden by each of others in turn
+func (a Attributes) Merge(others ...Attributes) Attributes {
+	merged := Attributes{}
+	for _, attrs := range append([]Attributes{a}, others...) {
+		for key, value := range attrs {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// Node has a stable ID, separate from the Label which is displayed.  Class
+// selects a style from the theme; Attributes override it.
+type Node struct {
+	ID         string
+	Label      string
+	Class      string
+	Attributes Attributes
+}
+
+// Edge points from a node to a node it depends on.  Class selects a style from
+// the theme; Attributes override it.  Nodes may have several edges between
+// them, of different classes.
+type Edge struct {
+	From       string
+	To         string
+	Class      string
+	Label      string
+	Attributes Attributes
+}
+
+// Theme styles nodes and edges by class, so that callers say what something
+// is, rather than how to draw it
+type Theme struct {
+	Graph     Attributes
+	Subgraphs Attributes
+	Nodes     map[string]Attributes
+	Edges     map[string]Attributes
+}
+
+func (t *Theme) graphAttributes() Attributes {
+	if t == nil {
+		return nil
+	}
+	return t.Graph
+}
+
+func (t *Theme) subgraphAttributes() Attributes {
+	if t == nil {
+		return nil
+	}
+	return t.Subgraphs
+}
+
+func (t *Theme) nodeAttributes(class string) Attributes {
+	if t == nil {
+		return nil
+	}
+	return t.Nodes[class]
+}
+
+func (t *Theme) edgeAttributes(class string) Attributes {
+	if t == nil {
+		return nil
+	}
+	return t.Edges[class]
+}
+
+type Graph struct {
+	Name       string
+	Label      string
+	Attributes Attributes
+	// Theme is used when rendering; subgraphs use their root's theme
+	Theme     *Theme
+	Nodes     map[string]*Node
+	Edges     map[string][]*Edge
+	Subgraphs map[string]*Graph
+}
+
+func NewGraph(name string, label string) *Graph {
+	return &Graph{
+		Name:      name,
+		Label:     label,
+		Nodes:     map[string]*Node{},
+		Edges:     map[string][]*Edge{},
+		Subgraphs: map[string]*Graph{},
+	}
+}
+
+// AddNode adds a node, unless one with the same ID is already in this graph
+func (g *Graph) AddNode(node *Node) {
+	if _, ok := g.Nodes[node.ID]; !ok {
+		g.Nodes[node.ID] = node
+	}
+}
+
+// AddEdge adds an edge, unless one with the same endpoints and class is
+// already in this graph.  Its nodes may be in any subgraph; nodes which aren't
+// in any are rendered with default attributes.
+func (g *Graph) AddEdge(edge *Edge) {
+	for _, existing := range g.Edges[edge.From] {
+		if existing.To == edge.To && existing.Class == edge.Class {
+			return
+		}
+	}
+	g.Edges[edge.From] = append(g.Edges[edge.From], edge)
+}
+
+func (g *Graph) AddSubgraph(sub *Graph) {
+	g.Subgraphs[sub.Name] = sub
+}
+
+// FindNode looks for a node in this graph and its subgraphs
+func (g *Graph) FindNode(id string) (*Node, bool) {
+	if node, ok := g.Nodes[id]; ok {
+		return node, true
+	}
+	for _, key := range slice.Sort(maps.Keys(g.Subgraphs)) {
+		if node, ok := g.Subgraphs[key].FindNode(id); ok {
+			return node, true
+		}
+	}
+	return nil, false
+}
+
+// AllNodes returns nodes from this graph and its subgraphs, sorted by ID
+func (g *Graph) AllNodes() []*Node {
+	nodes := map[string]*Node{}
+	g.walk(func(sub *Graph) {
+		for id, node := range sub.Nodes {
+			if _, ok := nodes[id]; !ok {
+				nodes[id] = node
+			}
+		}
+	})
+	return slice.SortOn(func(n *Node) string { return n.ID }, maps.Values(nodes))
+}
+
+// AllEdges returns edges from this graph and its subgraphs, sorted by
+// endpoints and class
+func (g *Graph) AllEdges() []*Edge {
+	var edges []*Edge
+	g.walk(func(sub *Graph) {
+		for _, fromEdges := range sub.Edges {
+			edges = append(edges, fromEdges...)
+		}
+	})
+	return slice.SortOn(func(e *Edge) string { return e.From + "\x00" + e.To + "\x00" + e.Class }, edges)
+}
+
+func (g *Graph) walk(f func(*Graph)) {
+	f(g)
+	for _, key := range slice.Sort(maps.Keys(g.Subgraphs)) {
+		g.Subgraphs[key].walk(f)
+	}
+}
@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"fmt"
+	"html"
+)
+
+// htmlTemplate is a standalone page: drag to pan, scroll to zoom, click a node
+// to highlight it and its neighbors, click the background to clear
+const htmlTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+  html, body { margin: 0; height: 100%%; overflow: hidden; font-family: Helvetica, Arial, sans-serif; }
+  #help { position: fixed; top: 8px; left: 8px; padding: 4px 8px; background: rgba(255, 255, 255, 0.85); border: 1px solid #ccc; font-size: 12px; }
+  #graph { width: 100%%; height: 100%%; cursor: grab; }
+  #graph.panning { cursor: grabbing; }
+  #graph svg { width: 100%%; height: 100%%; }
+  .node { cursor: pointer; }
+  .dimmed { opacity: 0.15; }
+  .selected > rect, .selected > ellipse { stroke-width: 3; }
+</style>
+</head>
+<body>
+<div id="help">%s &mdash; drag to pan, scroll to zoom, click a node to highlight its neighbors</div>
+<div id="graph">
+%s
+</div>
+<script>
+(function () {
+  var container = document.getElementById("graph");
+  var svg = container.querySelector("svg");
+  var viewport = svg.querySelector(".viewport");
+  svg.removeAttribute("width");
+  svg.removeAttribute("height");
+  var scale = 1, x = 0, y = 0;
+  function apply() { viewport.setAttribute("transform", "translate(" + x + "," + y + ") scale(" + scale + ")"); }
+
+  svg.addEventListener("wheel", function (event) {
+    event.preventDefault();
+    var point = svg.createSVGPoint();
+    point.x = event.clientX;
+    point.y = event.clientY;
+    var p = point.matrixTransform(svg.getScreenCTM().inverse());
+    var factor = event.deltaY < 0 ? 1.1 : 1 / 1.1;
+    x = p.x - (p.x - x) * factor;
+    y = p.y - (p.y - y) * factor;
+    scale *= factor;
+    apply();
+  }, { passive: false });
+
+  var dragging = null, moved = false;
+  svg.addEventListener("mousedown", function (event) {
+    dragging = { x: event.clientX, y: event.clientY, startX: x, startY: y };
+    moved = false;
+    container.classList.add("panning");
+  });
+  window.addEventListener("mousemove", function (event) {
+    if (!dragging) { return; }
+    var ctm = svg.getScreenCTM();
+    var dx = (event.clientX - dragging.x) / ctm.a, dy = (event.clientY - dragging.y) / ctm.d;
+    if (Math.abs(dx) + Math.abs(dy) > 2) { moved = true; }
+    x = dragging.startX + dx;
+    y = dragging.startY + dy;
+    apply();
+  });
+  window.addEventListener("mouseup", function () {
+    dragging = null;
+    container.classList.remove("panning");
+  });
+
+  var nodes = Array.prototype.slice.call(svg.querySelectorAll(".node"));
+  var edges = Array.prototype.slice.call(svg.querySelectorAll(".edge"));
+  var clusters = Array.prototype.slice.call(svg.querySelectorAll(".cluster"));
+  function clear() {
+    nodes.concat(edges, clusters).forEach(function (el) { el.classList.remove("dimmed", "selected"); });
+  }
+  function highlight(id) {
+    var neighbors = {};
+    neighbors[id] = true;
+    edges.forEach(function (edge) {
+      var from = edge.getAttribute("data-from"), to = edge.getAttribute("data-to");
+      var touches = from === id || to === id;
+      if (touches) { neighbors[from] = true; neighbors[to] = true; }
+      edge.classList.toggle("dimmed", !touches);
+    });
+    nodes.forEach(function (node) {
+      var nodeId = node.getAttribute("data-id");
+      node.classList.toggle("dimmed", !neighbors[nodeId]);
+      node.classList.toggle("selected", nodeId === id);
+    });
+    clusters.forEach(function (cluster) { cluster.classList.add("dimmed"); });
+  }
+  svg.addEventListener("click", function (event) {
+    if (moved) { return; }
+    var node = event.target.closest(".node");
+    clear();
+    if (node) { highlight(node.getAttribute("data-id")); }
+  });
+})();
+</script>
+</body>
+</html>
+`
+
+// RenderAsHtml is a standalone page embedding the SVG, with pan, zoom and
+// click-to-highlight of a node's neighbors, which works offline
+func (g *Graph) RenderAsHtml(title string) string {
+	escaped := html.EscapeString(title)
+	return fmt.Sprintf(htmlTemplate, escaped, escaped, g.RenderAsSvg())
+}
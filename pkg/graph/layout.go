@@ -0,0 +1,321 @@
+package graph
+
+import (
+	"github.com/mattfenwick/collections/pkg/slice"
+	"golang.org/x/exp/maps"
+	"sort"
+)
+
+// layout sizes, in pixels
+const (
+	layoutCharWidth     = 7.0
+	layoutNodePadding   = 20.0
+	layoutNodeHeight    = 30.0
+	layoutNodeGap       = 16.0
+	layoutLayerGap      = 90.0
+	layoutClusterPad    = 12.0
+	layoutClusterHeader = 22.0
+	layoutMargin        = 20.0
+	layoutSweeps        = 4
+)
+
+type Box struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+func (b *Box) CenterY() float64 {
+	return b.Y + b.Height/2
+}
+
+type NodeLayout struct {
+	Node *Node
+	Box
+	Layer int
+}
+
+type ClusterLayout struct {
+	Graph *Graph
+	Box
+	Depth int
+}
+
+type EdgeLayout struct {
+	Edge *Edge
+	From *NodeLayout
+	To   *NodeLayout
+}
+
+// Layout is where nodes, clusters and edges are drawn, left to right
+type Layout struct {
+	Width    float64
+	Height   float64
+	Nodes    []*NodeLayout
+	Clusters []*ClusterLayout
+	Edges    []*EdgeLayout
+}
+
+// band is a graph's own nodes, stacked above its subgraphs' bands; each layer
+// of a band is a column of nodes.  Bands span all layers, so that clusters'
+// boxes never overlap other nodes.
+type band struct {
+	graph  *Graph
+	depth  int
+	layers map[int][]*NodeLayout
+	subs   []*band
+}
+
+// Layout places nodes in layers -- columns -- so that edges point rightwards,
+// from dependents to dependencies, except for edges closing cycles.  Within a
+// layer, nodes are grouped by subgraph, and ordered to reduce edge crossings.
+func (g *Graph) Layout() *Layout {
+	layers := g.assignLayers()
+	nodes := map[string]*NodeLayout{}
+	for _, id := range g.NodeIDs() {
+		node, ok := g.FindNode(id)
+		if !ok {
+			node = &Node{ID: id}
+		}
+		label := node.Label
+		if label == "" {
+			label = node.ID
+		}
+		width := float64(len([]rune(label)))*layoutCharWidth + layoutNodePadding
+		nodes[id] = &NodeLayout{Node: node, Box: Box{Width: width, Height: layoutNodeHeight}, Layer: layers[id]}
+	}
+
+	placed := map[string]bool{}
+	root := newBand(g, 0, nodes, placed)
+	// nodes only referenced by edges go with the root's nodes
+	for _, id := range slice.Sort(maps.Keys(nodes)) {
+		if !placed[id] {
+			root.layers[nodes[id].Layer] = append(root.layers[nodes[id].Layer], nodes[id])
+		}
+	}
+
+	layerCount := 0
+	for _, node := range nodes {
+		if node.Layer+1 > layerCount {
+			layerCount = node.Layer + 1
+		}
+	}
+	layerXs := make([]float64, layerCount)
+	x := layoutMargin + layoutClusterPad*float64(root.maxDepth())
+	for layer := 0; layer < layerCount; layer++ {
+		layerXs[layer] = x
+		width := 0.0
+		for _, node := range nodes {
+			if node.Layer == layer && node.Width > width {
+				width = node.Width
+			}
+		}
+		x += width + layoutLayerGap
+	}
+	for _, node := range nodes {
+		node.X = layerXs[node.Layer]
+	}
+
+	layout := &Layout{}
+	for _, edge := range g.AllEdges() {
+		layout.Edges = append(layout.Edges, &EdgeLayout{Edge: edge, From: nodes[edge.From], To: nodes[edge.To]})
+	}
+
+	// order nodes within each band and layer by the average position of their
+	// neighbors, repeating to let positions settle
+	root.place(layoutMargin)
+	neighbors := map[string][]*NodeLayout{}
+	for _, edge := range layout.Edges {
+		neighbors[edge.From.Node.ID] = append(neighbors[edge.From.Node.ID], edge.To)
+		neighbors[edge.To.Node.ID] = append(neighbors[edge.To.Node.ID], edge.From)
+	}
+	for i := 0; i < layoutSweeps; i++ {
+		root.sortLayers(func(node *NodeLayout) float64 {
+			if len(neighbors[node.Node.ID]) == 0 {
+				return node.CenterY()
+			}
+			total := 0.0
+			for _, neighbor := range neighbors[node.Node.ID] {
+				total += neighbor.CenterY()
+			}
+			return total / float64(len(neighbors[node.Node.ID]))
+		})
+		root.place(layoutMargin)
+	}
+
+	height := root.place(layoutMargin)
+	root.collectClusters(layout, layerXs, layerCount)
+	layout.Nodes = slice.SortOn(func(n *NodeLayout) string { return n.Node.ID }, maps.Values(nodes))
+	layout.Width = x - layoutLayerGap + layoutMargin + layoutClusterPad*float64(root.maxDepth())
+	layout.Height = height + layoutMargin
+	return layout
+}
+
+func newBand(g *Graph, depth int, nodes map[string]*NodeLayout, placed map[string]bool) *band {
+	b := &band{graph: g, depth: depth, layers: map[int][]*NodeLayout{}}
+	for _, id := range slice.Sort(maps.Keys(g.Nodes)) {
+		if placed[id] {
+			continue
+		}
+		placed[id] = true
+		b.layers[nodes[id].Layer] = append(b.layers[nodes[id].Layer], nodes[id])
+	}
+	for _, key := range slice.Sort(maps.Keys(g.Subgraphs)) {
+		sub := newBand(g.Subgraphs[key], depth+1, nodes, placed)
+		if !sub.isEmpty() {
+			b.subs = append(b.subs, sub)
+		}
+	}
+	return b
+}
+
+func (b *band) isEmpty() bool {
+	return len(b.layers) == 0 && len(b.subs) == 0
+}
+
+func (b *band) maxDepth() int {
+	depth := b.depth
+	for _, sub := range b.subs {
+		if d := sub.maxDepth(); d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
+
+// place sets node Ys from top, returning the band's bottom
+func (b *band) place(top float64) float64 {
+	y := top
+	if b.depth > 0 {
+		y += layoutClusterHeader
+	}
+	bottom := y
+	for _, layer := range b.layers {
+		nodeY := y
+		for _, node := range layer {
+			node.Y = nodeY
+			nodeY += node.Height + layoutNodeGap
+		}
+		if nodeY > bottom {
+			bottom = nodeY
+		}
+	}
+	for _, sub := range b.subs {
+		bottom = sub.place(bottom) + layoutNodeGap
+	}
+	if b.depth > 0 {
+		bottom += layoutClusterPad - layoutNodeGap
+	}
+	return bottom
+}
+
+func (b *band) sortLayers(position func(*NodeLayout) float64) {
+	for _, layer := range b.layers {
+		positions := map[string]float64{}
+		for _, node := range layer {
+			positions[node.Node.ID] = position(node)
+		}
+		sort.SliceStable(layer, func(i, j int) bool {
+			return positions[layer[i].Node.ID] < positions[layer[j].Node.ID]
+		})
+	}
+	for _, sub := range b.subs {
+		sub.sortLayers(position)
+	}
+}
+
+// collectClusters adds a box for each subgraph band, spanning the layers in
+// which it has nodes
+func (b *band) collectClusters(layout *Layout, layerXs []float64, layerCount int) (float64, float64, float64, float64) {
+	minX, maxX, minY, maxY := -1.0, -1.0, -1.0, -1.0
+	extend := func(x0, x1, y0, y1 float64) {
+		if minX < 0 || x0 < minX {
+			minX = x0
+		}
+		if x1 > maxX {
+			maxX = x1
+		}
+		if minY < 0 || y0 < minY {
+			minY = y0
+		}
+		if y1 > maxY {
+			maxY = y1
+		}
+	}
+	for _, layer := range b.layers {
+		for _, node := range layer {
+			extend(node.X, node.X+node.Width, node.Y, node.Y+node.Height)
+		}
+	}
+	for _, sub := range b.subs {
+		x0, x1, y0, y1 := sub.collectClusters(layout, layerXs, layerCount)
+		extend(x0, x1, y0, y1)
+	}
+	if b.depth == 0 {
+		return minX, maxX, minY, maxY
+	}
+	box := Box{
+		X:      minX - layoutClusterPad,
+		Y:      minY - layoutClusterHeader,
+		Width:  maxX - minX + 2*layoutClusterPad,
+		Height: maxY - minY + layoutClusterHeader + layoutClusterPad,
+	}
+	layout.Clusters = append(layout.Clusters, &ClusterLayout{Graph: b.graph, Box: box, Depth: b.depth})
+	return box.X, box.X + box.Width, box.Y, box.Y + box.Height
+}
+
+// assignLayers puts each node one layer past the furthest of its dependents,
+// ignoring edges which close cycles
+func (g *Graph) assignLayers() map[string]int {
+	dependencies := g.Adjacency()
+	// find back edges with a depth-first search
+	backEdges := map[[2]string]bool{}
+	state := map[string]int{} // 1: on stack, 2: done
+	var visit func(string)
+	visit = func(id string) {
+		state[id] = 1
+		for _, to := range dependencies[id] {
+			switch state[to] {
+			case 0:
+				visit(to)
+			case 1:
+				backEdges[[2]string{id, to}] = true
+			}
+		}
+		state[id] = 2
+	}
+	for _, id := range slice.Sort(maps.Keys(dependencies)) {
+		if state[id] == 0 {
+			visit(id)
+		}
+	}
+
+	dependents := map[string][]string{}
+	for from, tos := range dependencies {
+		for _, to := range tos {
+			if !backEdges[[2]string{from, to}] {
+				dependents[to] = append(dependents[to], from)
+			}
+		}
+	}
+	layers := map[string]int{}
+	var layer func(string) int
+	layer = func(id string) int {
+		if l, ok := layers[id]; ok {
+			return l
+		}
+		l := 0
+		for _, from := range dependents[id] {
+			if fromLayer := layer(from) + 1; fromLayer > l {
+				l = fromLayer
+			}
+		}
+		layers[id] = l
+		return l
+	}
+	for id := range dependencies {
+		layer(id)
+	}
+	return layers
+}
@@ -0,0 +1,87 @@
+package graph
+
+import (
+	"testing"
+)
+
+// newTestGraph has nested clusters, a cycle, a self-loop, and an edge to a node
+// which isn't in any graph
+func newTestGraph() *Graph {
+	g := NewGraph("root", "root")
+	g.AddNode(&Node{ID: "a"})
+	g.AddNode(&Node{ID: "b", Label: "a much longer label than the others"})
+	outer := NewGraph("outer", "outer")
+	outer.AddNode(&Node{ID: "c"})
+	outer.AddNode(&Node{ID: "d"})
+	inner := NewGraph("inner", "inner")
+	inner.AddNode(&Node{ID: "e"})
+	inner.AddNode(&Node{ID: "f"})
+	outer.AddSubgraph(inner)
+	g.AddSubgraph(outer)
+	for _, edge := range [][2]string{{"a", "c"}, {"a", "d"}, {"c", "e"}, {"b", "e"}, {"e", "f"}, {"f", "a"}, {"d", "d"}, {"b", "ghost"}} {
+		g.AddEdge(&Edge{From: edge[0], To: edge[1]})
+	}
+	return g
+}
+
+func boxesOverlap(a Box, b Box) bool {
+	return a.X < b.X+b.Width && b.X < a.X+a.Width && a.Y < b.Y+b.Height && b.Y < a.Y+a.Height
+}
+
+func boxContains(outer Box, inner Box) bool {
+	return outer.X <= inner.X && outer.Y <= inner.Y && inner.X+inner.Width <= outer.X+outer.Width && inner.Y+inner.Height <= outer.Y+outer.Height
+}
+
+func TestLayoutNodesDontOverlap(t *testing.T) {
+	layout := newTestGraph().Layout()
+	if len(layout.Nodes) != 7 {
+		t.Fatalf("expected 7 nodes, found %d", len(layout.Nodes))
+	}
+	bounds := Box{Width: layout.Width, Height: layout.Height}
+	for i, a := range layout.Nodes {
+		if !boxContains(bounds, a.Box) {
+			t.Errorf("node %s at %+v is outside of the layout, %+v", a.Node.ID, a.Box, bounds)
+		}
+		for _, b := range layout.Nodes[i+1:] {
+			if boxesOverlap(a.Box, b.Box) {
+				t.Errorf("nodes %s at %+v and %s at %+v overlap", a.Node.ID, a.Box, b.Node.ID, b.Box)
+			}
+		}
+	}
+}
+
+func TestLayoutClustersContainTheirNodes(t *testing.T) {
+	layout := newTestGraph().Layout()
+	if len(layout.Clusters) != 2 {
+		t.Fatalf("expected 2 clusters, found %d", len(layout.Clusters))
+	}
+	clusters := map[string]*ClusterLayout{}
+	for _, cluster := range layout.Clusters {
+		clusters[cluster.Graph.Name] = cluster
+		members := map[string]bool{}
+		for _, node := range cluster.Graph.AllNodes() {
+			members[node.ID] = true
+		}
+		for _, node := range layout.Nodes {
+			if members[node.Node.ID] && !boxContains(cluster.Box, node.Box) {
+				t.Errorf("cluster %s at %+v doesn't contain its node %s at %+v", cluster.Graph.Name, cluster.Box, node.Node.ID, node.Box)
+			} else if !members[node.Node.ID] && boxesOverlap(cluster.Box, node.Box) {
+				t.Errorf("cluster %s at %+v overlaps node %s at %+v", cluster.Graph.Name, cluster.Box, node.Node.ID, node.Box)
+			}
+		}
+	}
+	if !boxContains(clusters["outer"].Box, clusters["inner"].Box) {
+		t.Errorf("cluster outer at %+v doesn't contain cluster inner at %+v", clusters["outer"].Box, clusters["inner"].Box)
+	}
+}
+
+func TestLayoutEdgesPointRight(t *testing.T) {
+	layout := newTestGraph().Layout()
+	for _, edge := range layout.Edges {
+		// f -> a closes the cycle a -> c -> e -> f, and d -> d is a self-loop
+		isBackEdge := (edge.Edge.From == "f" && edge.Edge.To == "a") || edge.Edge.From == edge.Edge.To
+		if isBackEdge == (edge.From.Layer < edge.To.Layer) {
+			t.Errorf("edge %s -> %s: unexpected layers %d -> %d", edge.Edge.From, edge.Edge.To, edge.From.Layer, edge.To.Layer)
+		}
+	}
+}
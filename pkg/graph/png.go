@@ -0,0 +1,204 @@
+package graph
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"golang.org/x/image/colornames"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// pngCurveSegments is how many straight lines approximate each edge's curve
+const pngCurveSegments = 48
+
+// pngColor resolves colors as SVG does: theme names, `#rrggbb`, or X11 names
+func pngColor(name string, defaultColor string) color.RGBA {
+	resolved := svgColor(name, defaultColor)
+	if strings.HasPrefix(resolved, "#") && len(resolved) == 7 {
+		if rgb, err := strconv.ParseUint(resolved[1:], 16, 32); err == nil {
+			return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 255}
+		}
+	}
+	if c, ok := colornames.Map[strings.ToLower(resolved)]; ok {
+		return c
+	}
+	return colornames.Map[defaultColor]
+}
+
+// pngCanvas draws with square pens, without antialiasing
+type pngCanvas struct {
+	img *image.RGBA
+}
+
+func (c *pngCanvas) fillRect(x0 float64, y0 float64, x1 float64, y1 float64, col color.RGBA) {
+	rect := image.Rect(int(math.Round(x0)), int(math.Round(y0)), int(math.Round(x1)), int(math.Round(y1)))
+	draw.Draw(c.img, rect, image.NewUniform(col), image.Point{}, draw.Src)
+}
+
+func (c *pngCanvas) dot(p point, width float64, col color.RGBA) {
+	half := width / 2
+	c.fillRect(p.X-half, p.Y-half, p.X+half, p.Y+half, col)
+}
+
+// polyline strokes the lines through points, skipping the gaps of a dash
+// pattern, which continues from one line to the next
+func (c *pngCanvas) polyline(points []point, width float64, dashes []float64, col color.RGBA) {
+	dashLength := 0.0
+	for _, length := range dashes {
+		dashLength += length
+	}
+	distance := 0.0
+	for i := 0; i+1 < len(points); i++ {
+		a, b := points[i], points[i+1]
+		length := math.Hypot(b.X-a.X, b.Y-a.Y)
+		for step := 0.0; step <= length; step += 0.5 {
+			if dashLength == 0 || math.Mod(distance+step, dashLength) < dashes[0] {
+				t := 0.0
+				if length > 0 {
+					t = step / length
+				}
+				c.dot(point{a.X + (b.X-a.X)*t, a.Y + (b.Y-a.Y)*t}, width, col)
+			}
+		}
+		distance += length
+	}
+}
+
+// fillTriangle fills points inside all three edges
+func (c *pngCanvas) fillTriangle(a point, b point, d point, col color.RGBA) {
+	side := func(p point, q point, r point) float64 { return (q.X-p.X)*(r.Y-p.Y) - (q.Y-p.Y)*(r.X-p.X) }
+	minX, maxX := math.Floor(math.Min(a.X, math.Min(b.X, d.X))), math.Ceil(math.Max(a.X, math.Max(b.X, d.X)))
+	minY, maxY := math.Floor(math.Min(a.Y, math.Min(b.Y, d.Y))), math.Ceil(math.Max(a.Y, math.Max(b.Y, d.Y)))
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			p := point{x + 0.5, y + 0.5}
+			s1, s2, s3 := side(a, b, p), side(b, d, p), side(d, a, p)
+			if (s1 >= 0 && s2 >= 0 && s3 >= 0) || (s1 <= 0 && s2 <= 0 && s3 <= 0) {
+				c.img.SetRGBA(int(x), int(y), col)
+			}
+		}
+	}
+}
+
+func (c *pngCanvas) fillEllipse(box Box, col color.RGBA) {
+	cx, cy, rx, ry := box.X+box.Width/2, box.CenterY(), box.Width/2, box.Height/2
+	for y := math.Floor(box.Y); y < box.Y+box.Height; y++ {
+		dy := (y + 0.5 - cy) / ry
+		if dy*dy > 1 {
+			continue
+		}
+		dx := rx * math.Sqrt(1-dy*dy)
+		c.fillRect(cx-dx, y, cx+dx, y+1, col)
+	}
+}
+
+func ellipsePoints(box Box) []point {
+	cx, cy, rx, ry := box.X+box.Width/2, box.CenterY(), box.Width/2, box.Height/2
+	var points []point
+	for i := 0; i <= pngCurveSegments; i++ {
+		angle := 2 * math.Pi * float64(i) / pngCurveSegments
+		points = append(points, point{cx + rx*math.Cos(angle), cy + ry*math.Sin(angle)})
+	}
+	return points
+}
+
+func rectPoints(box Box) []point {
+	return []point{{box.X, box.Y}, {box.X + box.Width, box.Y}, {box.X + box.Width, box.Y + box.Height}, {box.X, box.Y + box.Height}, {box.X, box.Y}}
+}
+
+func curvePoints(c [4]point) []point {
+	var points []point
+	for i := 0; i <= pngCurveSegments; i++ {
+		t := float64(i) / pngCurveSegments
+		u := 1 - t
+		points = append(points, point{
+			u*u*u*c[0].X + 3*u*u*t*c[1].X + 3*u*t*t*c[2].X + t*t*t*c[3].X,
+			u*u*u*c[0].Y + 3*u*u*t*c[1].Y + 3*u*t*t*c[2].Y + t*t*t*c[3].Y,
+		})
+	}
+	return points
+}
+
+// text draws s in the fixed width font which Layout sizes nodes for, with
+// its left edge -- or its center, if centered -- at x, vertically centered on y
+func (c *pngCanvas) text(s string, x float64, y float64, centered bool, col color.RGBA) {
+	face := basicfont.Face7x13
+	drawer := &font.Drawer{Dst: c.img, Src: image.NewUniform(col), Face: face}
+	if centered {
+		x -= float64(drawer.MeasureString(s).Round()) / 2
+	}
+	baseline := y + float64(face.Ascent-face.Descent)/2
+	drawer.Dot = fixed.P(int(math.Round(x)), int(math.Round(baseline)))
+	drawer.DrawString(s)
+}
+
+// RenderAsPng draws the same layout and styles as RenderAsSvg, except that
+// rounded corners aren't rounded
+func (g *Graph) RenderAsPng() ([]byte, error) {
+	layout := g.Layout()
+	canvas := &pngCanvas{img: image.NewRGBA(image.Rect(0, 0, int(math.Ceil(layout.Width)), int(math.Ceil(layout.Height))))}
+	draw.Draw(canvas.img, canvas.img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	subgraphAttrs := g.Theme.subgraphAttributes()
+	for _, cluster := range layout.Clusters {
+		attrs := subgraphAttrs.Merge(cluster.Graph.Attributes)
+		canvas.polyline(rectPoints(cluster.Box), 1, nil, pngColor(attrs["color"], "black"))
+		canvas.text(cluster.Graph.Label, cluster.X+8, cluster.Y+11, false, colornames.Black)
+	}
+
+	for _, edge := range layout.Edges {
+		attrs := g.edgeAttributes(edge.Edge)
+		col := pngColor(attrs["color"], "black")
+		width, err := strconv.ParseFloat(attrs["penwidth"], 64)
+		if err != nil {
+			width = 1
+		}
+		points := curvePoints(edgeCurve(edge))
+		canvas.polyline(points, width, dashPattern(attrs["style"]), col)
+		if attrs["arrowhead"] != "none" {
+			tip, before := points[len(points)-1], points[len(points)-2]
+			length := math.Hypot(tip.X-before.X, tip.Y-before.Y)
+			if length > 0 {
+				dx, dy := (tip.X-before.X)/length, (tip.Y-before.Y)/length
+				base := point{tip.X - 8*dx, tip.Y - 8*dy}
+				canvas.fillTriangle(tip, point{base.X - 4*dy, base.Y + 4*dx}, point{base.X + 4*dy, base.Y - 4*dx}, col)
+			}
+		}
+	}
+
+	for _, node := range layout.Nodes {
+		attrs := g.Theme.nodeAttributes(node.Node.Class).Merge(node.Node.Attributes)
+		fill := colornames.White
+		if strings.Contains(attrs["style"], "filled") {
+			fill = pngColor(attrs["fillcolor"], "lightgray")
+		}
+		stroke := pngColor(attrs["color"], "black")
+		switch attrs["shape"] {
+		case "ellipse", "circle", "oval":
+			canvas.fillEllipse(node.Box, fill)
+			canvas.polyline(ellipsePoints(node.Box), 1, dashPattern(attrs["style"]), stroke)
+		default:
+			canvas.fillRect(node.X, node.Y, node.X+node.Width, node.Y+node.Height, fill)
+			canvas.polyline(rectPoints(node.Box), 1, dashPattern(attrs["style"]), stroke)
+		}
+		label := node.Node.Label
+		if label == "" {
+			label = node.Node.ID
+		}
+		canvas.text(label, node.X+node.Width/2, node.CenterY(), true, colornames.Black)
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := png.Encode(buffer, canvas.img); err != nil {
+		return nil, errors.Wrapf(err, "unable to encode png")
+	}
+	return buffer.Bytes(), nil
+}
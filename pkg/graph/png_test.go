@@ -0,0 +1,36 @@
+package graph
+
+import (
+	"bytes"
+	"image/png"
+	"math"
+	"testing"
+)
+
+func TestRenderAsPng(t *testing.T) {
+	g := newTestGraph()
+	g.Theme = &Theme{Nodes: map[string]Attributes{"filled": {"style": "filled", "fillcolor": "lightblue"}}}
+	g.Nodes["a"].Class = "filled"
+	out, err := g.RenderAsPng()
+	if err != nil {
+		t.Fatalf("unable to render png: %+v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("unable to decode png: %+v", err)
+	}
+	layout := g.Layout()
+	if bounds := img.Bounds(); bounds.Dx() != int(math.Ceil(layout.Width)) || bounds.Dy() != int(math.Ceil(layout.Height)) {
+		t.Errorf("expected %.0fx%.0f image, found %dx%d", layout.Width, layout.Height, bounds.Dx(), bounds.Dy())
+	}
+	for _, node := range layout.Nodes {
+		if node.Node.ID != "a" {
+			continue
+		}
+		// inside the border, left of the label
+		r, g, b, _ := img.At(int(node.X)+3, int(node.Y)+3).RGBA()
+		if r>>8 != 0xad || g>>8 != 0xd8 || b>>8 != 0xe6 {
+			t.Errorf("expected node a to be filled with lightblue, found %d %d %d", r>>8, g>>8, b>>8)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package graph
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// svgColors are the DOT color names used by themes; other colors are passed
+// through, as SVG understands most X11 names
+var svgColors = map[string]string{
+	"gray40": "#666666",
+	"gray60": "#999999",
+}
+
+func svgColor(color string, defaultColor string) string {
+	if color == "" {
+		return defaultColor
+	}
+	if c, ok := svgColors[color]; ok {
+		return c
+	}
+	return color
+}
+
+// dashPattern is the lengths of alternating dashes and gaps for a DOT style,
+// or nil for solid lines
+func dashPattern(style string) []float64 {
+	switch {
+	case strings.Contains(style, "dashed"):
+		return []float64{6, 4}
+	case strings.Contains(style, "dotted"):
+		return []float64{2, 3}
+	default:
+		return nil
+	}
+}
+
+func svgDashArray(style string) string {
+	return strings.Join(slice.Map(func(length float64) string { return strconv.FormatFloat(length, 'f', -1, 64) }, dashPattern(style)), ",")
+}
+
+func svgAttr(name string, value string) string {
+	return fmt.Sprintf(` %s="%s"`, name, html.EscapeString(value))
+}
+
+// RenderAsSvg lays out and draws the graph -- including subgraphs as clusters
+// -- styled by its theme
+func (g *Graph) RenderAsSvg() string {
+	layout := g.Layout()
+	lines := []string{
+		fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" font-family="Helvetica, Arial, sans-serif" font-size="12">`,
+			layout.Width, layout.Height, layout.Width, layout.Height),
+	}
+
+	markers := map[string]bool{}
+	var defs []string
+	for _, edge := range layout.Edges {
+		color := svgColor(g.edgeAttributes(edge.Edge)["color"], "black")
+		if !markers[color] {
+			markers[color] = true
+			defs = append(defs, fmt.Sprintf(`    <marker id="%s" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="7" markerHeight="7" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z"%s/></marker>`,
+				svgMarkerID(color), svgAttr("fill", color)))
+		}
+	}
+	lines = append(lines, "  <defs>")
+	lines = append(lines, defs...)
+	lines = append(lines, "  </defs>")
+	lines = append(lines, `  <g class="viewport">`)
+
+	subgraphAttrs := g.Theme.subgraphAttributes()
+	for _, cluster := range layout.Clusters {
+		attrs := subgraphAttrs.Merge(cluster.Graph.Attributes)
+		rounded := ""
+		if strings.Contains(attrs["style"], "rounded") {
+			rounded = ` rx="8" ry="8"`
+		}
+		lines = append(lines,
+			fmt.Sprintf(`    <g class="cluster"%s>`, svgAttr("data-name", cluster.Graph.Name)),
+			fmt.Sprintf(`      <rect x="%.1f" y="%.1f" width="%.1f" height="%.1f"%s fill="none"%s/>`,
+				cluster.X, cluster.Y, cluster.Width, cluster.Height, rounded, svgAttr("stroke", svgColor(attrs["color"], "black"))),
+			fmt.Sprintf(`      <text x="%.1f" y="%.1f" font-weight="bold">%s</text>`, cluster.X+8, cluster.Y+15, html.EscapeString(cluster.Graph.Label)),
+			"    </g>")
+	}
+
+	for _, edge := range layout.Edges {
+		attrs := g.edgeAttributes(edge.Edge)
+		color := svgColor(attrs["color"], "black")
+		width := attrs["penwidth"]
+		if width == "" {
+			width = "1"
+		}
+		marker := fmt.Sprintf(` marker-end="url(#%s)"`, svgMarkerID(color))
+		if attrs["arrowhead"] == "none" {
+			marker = ""
+		}
+		dash := ""
+		if d := svgDashArray(attrs["style"]); d != "" {
+			dash = svgAttr("stroke-dasharray", d)
+		}
+		title := fmt.Sprintf("%s -> %s", edge.Edge.From, edge.Edge.To)
+		if edge.Edge.Class != "" {
+			title += fmt.Sprintf(" (%s)", edge.Edge.Class)
+		}
+		lines = append(lines,
+			fmt.Sprintf(`    <path class="edge"%s%s d="%s" fill="none"%s%s%s%s><title>%s</title></path>`,
+				svgAttr("data-from", edge.Edge.From), svgAttr("data-to", edge.Edge.To), svgEdgePath(edge),
+				svgAttr("stroke", color), svgAttr("stroke-width", width), dash, marker, html.EscapeString(title)))
+	}
+
+	for _, node := range layout.Nodes {
+		attrs := g.Theme.nodeAttributes(node.Node.Class).Merge(node.Node.Attributes)
+		fill := "white"
+		if strings.Contains(attrs["style"], "filled") {
+			fill = svgColor(attrs["fillcolor"], "lightgray")
+		}
+		stroke := svgColor(attrs["color"], "black")
+		dash := ""
+		if d := svgDashArray(attrs["style"]); d != "" {
+			dash = svgAttr("stroke-dasharray", d)
+		}
+		var shape string
+		switch attrs["shape"] {
+		case "ellipse", "circle", "oval":
+			shape = fmt.Sprintf(`<ellipse cx="%.1f" cy="%.1f" rx="%.1f" ry="%.1f"%s%s%s/>`,
+				node.X+node.Width/2, node.CenterY(), node.Width/2, node.Height/2, svgAttr("fill", fill), svgAttr("stroke", stroke), dash)
+		default:
+			rounded := ""
+			if strings.Contains(attrs["style"], "rounded") || attrs["shape"] == "hexagon" {
+				rounded = ` rx="6" ry="6"`
+			}
+			shape = fmt.Sprintf(`<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f"%s%s%s%s/>`,
+				node.X, node.Y, node.Width, node.Height, rounded, svgAttr("fill", fill), svgAttr("stroke", stroke), dash)
+		}
+		label := node.Node.Label
+		if label == "" {
+			label = node.Node.ID
+		}
+		lines = append(lines,
+			fmt.Sprintf(`    <g class="node"%s%s>`, svgAttr("data-id", node.Node.ID), svgAttr("data-class", node.Node.Class)),
+			fmt.Sprintf(`      <title>%s</title>`, html.EscapeString(node.Node.ID)),
+			"      "+shape,
+			fmt.Sprintf(`      <text x="%.1f" y="%.1f" text-anchor="middle" dominant-baseline="central">%s</text>`,
+				node.X+node.Width/2, node.CenterY(), html.EscapeString(label)),
+			"    </g>")
+	}
+
+	lines = append(lines, "  </g>", "</svg>")
+	return strings.Join(lines, "\n")
+}
+
+func (g *Graph) edgeAttributes(edge *Edge) Attributes {
+	return g.Theme.edgeAttributes(edge.Class).Merge(edge.Attributes)
+}
+
+func svgMarkerID(color string) string {
+	return "arrow-" + strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '-'
+	}, color)
+}
+
+type point struct {
+	X float64
+	Y float64
+}
+
+// edgeCurve is the control points of a cubic bezier curve from the right side
+// of From to the left side of To; edges to the same or an earlier layer loop
+// around the right side
+func edgeCurve(edge *EdgeLayout) [4]point {
+	from, to := edge.From, edge.To
+	if to.Layer > from.Layer {
+		x0, y0 := from.X+from.Width, from.CenterY()
+		x1, y1 := to.X, to.CenterY()
+		bend := (x1 - x0) / 2
+		return [4]point{{x0, y0}, {x0 + bend, y0}, {x1 - bend, y1}, {x1, y1}}
+	}
+	x0, y0 := from.X+from.Width, from.CenterY()
+	x1, y1 := to.X+to.Width, to.CenterY()
+	if from == to {
+		return [4]point{{x0, y0 - 5}, {x0 + 40, y0 - 30}, {x0 + 40, y0 + 30}, {x1, y1 + 5}}
+	}
+	reach := maxFloat(x0, x1) + layoutLayerGap/2
+	return [4]point{{x0, y0}, {reach, y0}, {reach, y1}, {x1, y1}}
+}
+
+func svgEdgePath(edge *EdgeLayout) string {
+	c := edgeCurve(edge)
+	return fmt.Sprintf("M %.1f %.1f C %.1f %.1f, %.1f %.1f, %.1f %.1f", c[0].X, c[0].Y, c[1].X, c[1].Y, c[2].X, c[2].Y, c[3].X, c[3].Y)
+}
+
+func maxFloat(a float64, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,70 @@
+package graph
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSvgEscaping(t *testing.T) {
+	id := `Secret/a"b<c>&'d`
+	label := "<script>alert('x')</script> & more"
+	g := NewGraph("root", "root")
+	sub := NewGraph("sub", `secrets & "config" <maps>`)
+	sub.AddNode(&Node{ID: id, Label: label, Class: `class"with<quotes>`})
+	g.AddSubgraph(sub)
+	g.AddNode(&Node{ID: "plain"})
+	g.AddEdge(&Edge{From: "plain", To: id, Class: "a&b"})
+	g.Theme = &Theme{Edges: map[string]Attributes{"a&b": {"color": `red" onload="alert(1)`}}}
+
+	// the svg must parse, and its text and attributes must decode back to the
+	// original strings
+	var texts []string
+	attributes := map[string][]string{}
+	decoder := xml.NewDecoder(strings.NewReader(g.RenderAsSvg()))
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("unable to parse svg: %+v", err)
+		}
+		switch tok := token.(type) {
+		case xml.StartElement:
+			inText = tok.Name.Local == "text" || tok.Name.Local == "title"
+			for _, attr := range tok.Attr {
+				attributes[attr.Name.Local] = append(attributes[attr.Name.Local], attr.Value)
+			}
+			if tok.Name.Local == "path" && len(attributes["onload"]) > 0 {
+				t.Errorf("attribute value escaped into a new attribute")
+			}
+		case xml.EndElement:
+			inText = false
+		case xml.CharData:
+			if inText {
+				texts = append(texts, string(tok))
+			}
+		}
+	}
+	for _, expected := range []string{id, label, `secrets & "config" <maps>`, `plain -> ` + id + ` (a&b)`} {
+		if !containsString(texts, expected) {
+			t.Errorf("expected text %q, found %q", expected, texts)
+		}
+	}
+	for name, expected := range map[string]string{"data-id": id, "data-class": `class"with<quotes>`, "data-to": id, "stroke": `red" onload="alert(1)`} {
+		if !containsString(attributes[name], expected) {
+			t.Errorf("expected attribute %s=%q, found %q", name, expected, attributes[name])
+		}
+	}
+}
+
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package helm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/file"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ValueDoc documents one key of a chart's values.yaml
+type ValueDoc struct {
+	Key         string `json:"key"`
+	Type        string `json:"type"`
+	Default     string `json:"default"`
+	Description string `json:"description"`
+}
+
+// ReadValuesDocs documents every key in a values.yaml, in file order
+func ReadValuesDocs(path string) ([]*ValueDoc, error) {
+	contents, err := file.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseValuesDocs(contents)
+}
+
+// ParseValuesDocs documents every key -- including those of nested maps, and of
+// maps within arrays -- using the comments above or beside each key as its
+// description.  Arrays of scalars are documented as a single value.
+func ParseValuesDocs(contents []byte) ([]*ValueDoc, error) {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(contents, root); err != nil {
+		return nil, errors.Wrapf(err, "unable to unmarshal yaml")
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	document := root.Content[0]
+	if document.Kind != yaml.MappingNode {
+		return nil, errors.Errorf("expected values to be a map, found %s", nodeType(document))
+	}
+	var docs []*ValueDoc
+	if err := valuesDocsHelper("", document, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func valuesDocsHelper(path string, node *yaml.Node, docs *[]*ValueDoc) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if err := addValueDoc(keyPath(path, key.Value), value, commentText(key.HeadComment, key.LineComment, value.LineComment), docs); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			if err := addValueDoc(fmt.Sprintf("%s[%d]", path, i), item, commentText(item.HeadComment, item.LineComment), docs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func addValueDoc(path string, value *yaml.Node, description string, docs *[]*ValueDoc) error {
+	defaultValue, err := nodeDefault(value)
+	if err != nil {
+		return err
+	}
+	*docs = append(*docs, &ValueDoc{Key: path, Type: nodeType(value), Default: defaultValue, Description: description})
+	if value.Kind == yaml.MappingNode || (value.Kind == yaml.SequenceNode && hasCollections(value)) {
+		return valuesDocsHelper(path, value, docs)
+	}
+	return nil
+}
+
+func hasCollections(node *yaml.Node) bool {
+	for _, item := range node.Content {
+		if item.Kind == yaml.MappingNode || item.Kind == yaml.SequenceNode {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeType(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "list"
+	case yaml.AliasNode:
+		return nodeType(node.Alias)
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!str":
+			return "string"
+		case "!!int":
+			return "int"
+		case "!!float":
+			return "float"
+		case "!!bool":
+			return "bool"
+		case "!!null":
+			return "null"
+		}
+		return node.ShortTag()
+	default:
+		return "unknown"
+	}
+}
+
+// nodeDefault renders a value as compact JSON.  Non-empty maps are left blank,
+// as their keys are documented separately.
+func nodeDefault(node *yaml.Node) (string, error) {
+	if node.Kind == yaml.MappingNode && len(node.Content) > 0 {
+		return "", nil
+	}
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return "", errors.Wrapf(err, "unable to decode yaml node at line %d", node.Line)
+	}
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", errors.Wrapf(err, "unable to marshal json for yaml node at line %d", node.Line)
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+// commentText strips the `#`s from comments, joining them with newlines
+func commentText(comments ...string) string {
+	var lines []string
+	for _, comment := range comments {
+		for _, line := range strings.Split(comment, "\n") {
+			line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#"))
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var plainKeyRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// keyPath appends a key to a dotted path, quoting keys -- such as annotation
+// names -- which would be ambiguous: `podAnnotations["prometheus.io/scrape"]`
+func keyPath(path string, key string) string {
+	if !plainKeyRegex.MatchString(key) {
+		return fmt.Sprintf("%s[%s]", path, strconv.Quote(key))
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func ValuesDocsMarkdown(docs []*ValueDoc) string {
+	lines := []string{
+		"| Key | Type | Default | Description |",
+		"|-----|------|---------|-------------|",
+	}
+	for _, doc := range docs {
+		defaultValue := ""
+		if doc.Default != "" {
+			defaultValue = fmt.Sprintf("`%s`", markdownCell(doc.Default))
+		}
+		lines = append(lines, fmt.Sprintf("| `%s` | %s | %s | %s |", markdownCell(doc.Key), doc.Type, defaultValue, markdownCell(doc.Description)))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+const (
+	ValuesDocStartMarker = "<!-- values-doc:start -->"
+	ValuesDocEndMarker   = "<!-- values-doc:end -->"
+)
+
+// ReplaceReadmeSection replaces the text between ValuesDocStartMarker and
+// ValuesDocEndMarker in a README with section
+func ReplaceReadmeSection(readme string, section string) (string, error) {
+	start := strings.Index(readme, ValuesDocStartMarker)
+	end := strings.Index(readme, ValuesDocEndMarker)
+	if start < 0 || end < 0 || end < start {
+		return "", errors.Errorf("README must contain '%s' followed by '%s'", ValuesDocStartMarker, ValuesDocEndMarker)
+	}
+	return readme[:start+len(ValuesDocStartMarker)] + "\n" + section + readme[end:], nil
+}
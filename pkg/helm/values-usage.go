@@ -0,0 +1,453 @@
+package helm
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/file"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"text/template/parse"
+)
+
+// ValuesReference is a `.Values.x.y` reference in a template
+type ValuesReference struct {
+	Path   []string
+	File   string
+	Line   int
+	Column int
+	Source string
+	// Defaulted is set for references passed to `default`, which don't need a
+	// value in values.yaml
+	Defaulted bool
+	// Scope is set for `with .Values.x`, which only uses the parts of x
+	// referenced within it
+	Scope bool
+	// Inferred is set for references in `define`d templates whose dot isn't
+	// known.  These use their keys, but aren't reported as undefined.
+	Inferred bool
+}
+
+func (r *ValuesReference) Key() string {
+	return strings.Join(r.Path, ".")
+}
+
+func (r *ValuesReference) Location() string {
+	return fmt.Sprintf("%s:%d:%d", r.File, r.Line, r.Column)
+}
+
+// ValueKey is a key in values.yaml
+type ValueKey struct {
+	Path []string
+	File string
+	Line int
+}
+
+func (k *ValueKey) Key() string {
+	return strings.Join(k.Path, ".")
+}
+
+func (k *ValueKey) Location() string {
+	return fmt.Sprintf("%s:%d", k.File, k.Line)
+}
+
+type ValuesUsage struct {
+	// Unused lists values.yaml keys which no template references, either
+	// directly or through a parent or child key.  Only the topmost unused key of
+	// a subtree is listed.
+	Unused []*ValueKey
+	// Undefined lists template references to keys which aren't in values.yaml
+	// and aren't passed to `default`
+	Undefined []*ValuesReference
+}
+
+// AnalyzeChartValues compares a chart's values.yaml with the `.Values`
+// references in its templates directory
+func AnalyzeChartValues(chartPath string) (*ValuesUsage, error) {
+	valuesPath := filepath.Join(chartPath, "values.yaml")
+	keys, err := ReadValueKeys(valuesPath)
+	if err != nil {
+		return nil, err
+	}
+	references, err := FindTemplateValuesReferences(filepath.Join(chartPath, "templates"))
+	if err != nil {
+		return nil, err
+	}
+	return CompareValuesUsage(keys, references), nil
+}
+
+// ReadValueKeys lists every map key in values.yaml, in file order.  Arrays are
+// treated as single values.
+func ReadValueKeys(path string) ([]*ValueKey, error) {
+	contents, err := file.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(contents, root); err != nil {
+		return nil, errors.Wrapf(err, "unable to unmarshal yaml from %s", path)
+	}
+	var keys []*ValueKey
+	var helper func(prefix []string, node *yaml.Node)
+	helper = func(prefix []string, node *yaml.Node) {
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := append(append([]string{}, prefix...), node.Content[i].Value)
+			keys = append(keys, &ValueKey{Path: key, File: path, Line: node.Content[i].Line})
+			helper(key, node.Content[i+1])
+		}
+	}
+	if len(root.Content) > 0 {
+		helper(nil, root.Content[0])
+	}
+	return keys, nil
+}
+
+// FindTemplateValuesReferences parses every template under dir, finding
+// references to `.Values` -- including `$.Values`, `index .Values "a" "b"`,
+// relative references within `with .Values.x`, and references in `define`d
+// templates, such as those in _helpers.tpl, which are `include`d from other
+// files
+func FindTemplateValuesReferences(dir string) ([]*ValuesReference, error) {
+	var sources []*templateSource
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		contents, err := file.ReadString(path)
+		if err != nil {
+			return err
+		}
+		fileSources, err := parseTemplateSources(path, contents)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, fileSources...)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read templates from %s", dir)
+	}
+	return findValuesReferences(sources), nil
+}
+
+func ParseTemplateValuesReferences(path string, contents string) ([]*ValuesReference, error) {
+	sources, err := parseTemplateSources(path, contents)
+	if err != nil {
+		return nil, err
+	}
+	return findValuesReferences(sources), nil
+}
+
+// templateSource is a file's top level template, or a template it `define`s
+type templateSource struct {
+	Name     string
+	File     string
+	Contents string
+	Tree     *parse.Tree
+	IsDefine bool
+}
+
+func parseTemplateSources(path string, contents string) ([]*templateSource, error) {
+	trees := map[string]*parse.Tree{}
+	tree := parse.New(path)
+	tree.Mode = parse.SkipFuncCheck
+	if _, err := tree.Parse(contents, "{{", "}}", trees); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse template %s", path)
+	}
+	var sources []*templateSource
+	for _, name := range slice.Sort(maps.Keys(trees)) {
+		if trees[name].Root == nil {
+			continue
+		}
+		sources = append(sources, &templateSource{Name: name, File: path, Contents: contents, Tree: trees[name], IsDefine: name != path})
+	}
+	return sources, nil
+}
+
+// findValuesReferences walks each top level template with the chart as dot.
+// `define`d templates are walked with what they're `include`d with -- such as
+// `.`, `$` or `.Values.x` -- wherever that's known.  Those only included with
+// something unknown, such as a `dict`, or not included at all, are walked
+// looking for anything named `Values`; these references are inferred.
+func findValuesReferences(sources []*templateSource) []*ValuesReference {
+	defines := map[string]*templateSource{}
+	for _, source := range sources {
+		if source.IsDefine {
+			// as in helm, later definitions win
+			defines[source.Name] = source
+		}
+	}
+
+	var references []*ValuesReference
+	seen := map[string]bool{}
+	var queue []*templateCall
+	walk := func(source *templateSource, dot []string, inferred bool) {
+		f := &referenceFinder{file: source.File, contents: source.Contents, root: dot, inferred: inferred}
+		f.walk(source.Tree.Root, dot)
+		for _, reference := range f.references {
+			key := fmt.Sprintf("%s:%s:%t", reference.Location(), reference.Key(), reference.Scope)
+			if !seen[key] {
+				seen[key] = true
+				references = append(references, reference)
+			}
+		}
+		queue = append(queue, f.calls...)
+	}
+
+	for _, source := range sources {
+		if !source.IsDefine {
+			walk(source, []string{}, false)
+		}
+	}
+	walked := map[string]bool{}
+	for len(queue) > 0 {
+		call := queue[0]
+		queue = queue[1:]
+		source, ok := defines[call.Name]
+		if !ok || call.Dot == nil {
+			continue
+		}
+		key := call.Name + "\x00" + strings.Join(call.Dot, ".")
+		if walked[key] {
+			continue
+		}
+		walked[key] = true
+		walked[call.Name] = true
+		walk(source, call.Dot, false)
+	}
+	for _, name := range slice.Sort(maps.Keys(defines)) {
+		if !walked[name] {
+			walk(defines[name], nil, true)
+		}
+	}
+
+	return slice.SortOn(func(r *ValuesReference) string {
+		return fmt.Sprintf("%s:%09d:%09d", r.File, r.Line, r.Column)
+	}, references)
+}
+
+// templateCall is an `include` or `template` of a `define`d template, with the
+// path of what's passed as dot, or nil when that's not known
+type templateCall struct {
+	Name string
+	Dot  []string
+}
+
+type referenceFinder struct {
+	file     string
+	contents string
+	// root is the path of `$`: what the template was invoked with, or nil when
+	// that's not known
+	root []string
+	// inferred is set while walking templates whose dot isn't known
+	inferred   bool
+	references []*ValuesReference
+	calls      []*templateCall
+}
+
+// walk finds references below node.  dot is the path of `.`: nil when it's not
+// known, empty for the chart, `[Values x]` within `with .Values.x`.
+func (f *referenceFinder) walk(node parse.Node, dot []string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			f.walk(child, dot)
+		}
+	case *parse.ActionNode:
+		f.walkPipe(n.Pipe, dot)
+	case *parse.IfNode:
+		f.walkPipe(n.Pipe, dot)
+		f.walk(n.List, dot)
+		f.walk(n.ElseList, dot)
+	case *parse.RangeNode:
+		f.walkPipe(n.Pipe, dot)
+		f.walk(n.List, nil)
+		f.walk(n.ElseList, dot)
+	case *parse.WithNode:
+		if path := f.pipePath(n.Pipe, dot); len(path) > 0 && path[0] == "Values" {
+			f.addReference(n.Pipe, path[1:], false).Scope = true
+		} else {
+			f.walkPipe(n.Pipe, dot)
+		}
+		f.walk(n.List, f.pipePath(n.Pipe, dot))
+		f.walk(n.ElseList, dot)
+	case *parse.TemplateNode:
+		f.walkPipe(n.Pipe, dot)
+		f.addCall(n.Name, f.pipePath(n.Pipe, dot), n.Pipe != nil)
+	}
+}
+
+func (f *referenceFinder) addCall(name string, dot []string, hasArg bool) {
+	// `template "x"` is invoked with nil, which has no values
+	if !hasArg {
+		return
+	}
+	f.calls = append(f.calls, &templateCall{Name: name, Dot: dot})
+}
+
+func (f *referenceFinder) walkPipe(pipe *parse.PipeNode, dot []string) {
+	if pipe == nil {
+		return
+	}
+	for i, command := range pipe.Cmds {
+		// `.Values.x | default "y"`
+		defaulted := i+1 < len(pipe.Cmds) && isCommand(pipe.Cmds[i+1], "default")
+		f.walkCommand(command, dot, defaulted)
+	}
+}
+
+func isCommand(command *parse.CommandNode, name string) bool {
+	if len(command.Args) == 0 {
+		return false
+	}
+	identifier, ok := command.Args[0].(*parse.IdentifierNode)
+	return ok && identifier.Ident == name
+}
+
+func (f *referenceFinder) walkCommand(command *parse.CommandNode, dot []string, defaulted bool) {
+	// `include "x" .`
+	if isCommand(command, "include") && len(command.Args) == 3 {
+		if name, ok := command.Args[1].(*parse.StringNode); ok {
+			f.addCall(name.Text, f.nodePath(command.Args[2], dot), true)
+		}
+	}
+	// `default "y" .Values.x`
+	defaulted = defaulted || isCommand(command, "default")
+	for i := 0; i < len(command.Args); i++ {
+		arg := command.Args[i]
+		if pipe, ok := arg.(*parse.PipeNode); ok {
+			f.walkPipe(pipe, dot)
+			continue
+		}
+		path := f.nodePath(arg, dot)
+		if len(path) == 0 || path[0] != "Values" {
+			continue
+		}
+		source := arg
+		// `index .Values "x" "y"`
+		if i == 1 && isCommand(command, "index") {
+			source = command
+			for _, key := range command.Args[2:] {
+				str, ok := key.(*parse.StringNode)
+				if !ok {
+					break
+				}
+				path = append(path, str.Text)
+				i++
+			}
+		}
+		f.addReference(source, path[1:], defaulted)
+	}
+}
+
+// pipePath is the path a pipeline evaluates to, if it's a plain reference
+func (f *referenceFinder) pipePath(pipe *parse.PipeNode, dot []string) []string {
+	if pipe == nil || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return nil
+	}
+	return f.nodePath(pipe.Cmds[0].Args[0], dot)
+}
+
+func (f *referenceFinder) nodePath(node parse.Node, dot []string) []string {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		return f.resolvePath(dot, n.Ident)
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" {
+			return f.resolvePath(f.root, n.Ident[1:])
+		}
+	case *parse.DotNode:
+		return dot
+	}
+	return nil
+}
+
+// resolvePath appends fields to base.  When base isn't known, and references
+// are being inferred, fields from `Values` on are used: `.ctx.Values.x`.
+func (f *referenceFinder) resolvePath(base []string, fields []string) []string {
+	if base != nil {
+		return append(append([]string{}, base...), fields...)
+	}
+	if !f.inferred {
+		return nil
+	}
+	for i, field := range fields {
+		if field == "Values" {
+			return append([]string{}, fields[i:]...)
+		}
+	}
+	return nil
+}
+
+func (f *referenceFinder) addReference(node parse.Node, path []string, defaulted bool) *ValuesReference {
+	position := int(node.Position())
+	line := strings.Count(f.contents[:position], "\n") + 1
+	column := position - strings.LastIndex(f.contents[:position], "\n")
+	reference := &ValuesReference{
+		Path:      path,
+		File:      f.file,
+		Line:      line,
+		Column:    column,
+		Source:    node.String(),
+		Defaulted: defaulted,
+		Inferred:  f.inferred,
+	}
+	f.references = append(f.references, reference)
+	return reference
+}
+
+// CompareValuesUsage finds unused keys and undefined references.  A reference
+// uses its key, along with the key's parents and children: `toYaml
+// .Values.resources` uses everything under `resources`.
+func CompareValuesUsage(keys []*ValueKey, references []*ValuesReference) *ValuesUsage {
+	defined := map[string]bool{}
+	for _, key := range keys {
+		defined[key.Key()] = true
+	}
+	referenced, referencedPrefixes := map[string]bool{}, map[string]bool{}
+	for _, reference := range references {
+		if !reference.Scope {
+			referenced[reference.Key()] = true
+		}
+		for i := 0; i <= len(reference.Path); i++ {
+			referencedPrefixes[strings.Join(reference.Path[:i], ".")] = true
+		}
+	}
+	isUsed := func(path []string) bool {
+		if referencedPrefixes[strings.Join(path, ".")] {
+			return true
+		}
+		for i := 0; i <= len(path); i++ {
+			if referenced[strings.Join(path[:i], ".")] {
+				return true
+			}
+		}
+		return false
+	}
+
+	usage := &ValuesUsage{}
+	for _, key := range keys {
+		parentUnused := len(key.Path) > 1 && !isUsed(key.Path[:len(key.Path)-1])
+		if !isUsed(key.Path) && !parentUnused {
+			usage.Unused = append(usage.Unused, key)
+		}
+	}
+	for _, reference := range references {
+		if len(reference.Path) > 0 && !defined[reference.Key()] && !reference.Defaulted && !reference.Inferred {
+			usage.Undefined = append(usage.Undefined, reference)
+		}
+	}
+	return usage
+}
@@ -0,0 +1,138 @@
+package helm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// a trimmed down `helm create` chart
+var createdChart = map[string]string{
+	"values.yaml": `replicaCount: 1
+image:
+  repository: nginx
+  tag: ""
+nameOverride: ""
+fullnameOverride: ""
+serviceAccount:
+  create: true
+  annotations: {}
+  name: ""
+podAnnotations: {}
+unusedKey: 3
+`,
+	"templates/_helpers.tpl": `{{- define "chart.name" -}}
+{{- default .Chart.Name .Values.nameOverride | trunc 63 | trimSuffix "-" }}
+{{- end }}
+
+{{- define "chart.fullname" -}}
+{{- if .Values.fullnameOverride }}
+{{- .Values.fullnameOverride | trunc 63 | trimSuffix "-" }}
+{{- else }}
+{{- $name := default .Chart.Name .Values.nameOverride }}
+{{- printf "%s-%s" .Release.Name $name | trunc 63 | trimSuffix "-" }}
+{{- end }}
+{{- end }}
+
+{{- define "chart.serviceAccountName" -}}
+{{- if .Values.serviceAccount.create }}
+{{- default (include "chart.fullname" .) .Values.serviceAccount.name }}
+{{- else }}
+{{- default "default" .Values.serviceAccount.name }}
+{{- end }}
+{{- end }}
+
+{{- define "chart.image" -}}
+{{ .repository }}:{{ .tag | default $.Chart.AppVersion }}
+{{- end }}
+
+{{- define "chart.annotations" -}}
+{{- toYaml .ctx.Values.podAnnotations }}
+{{- end }}
+`,
+	"templates/serviceaccount.yaml": `{{- if .Values.serviceAccount.create -}}
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: {{ include "chart.serviceAccountName" . }}
+  {{- with .Values.serviceAccount.annotations }}
+  annotations:
+    {{- toYaml . | nindent 4 }}
+  {{- end }}
+{{- end }}
+`,
+	"templates/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ include "chart.fullname" $ }}
+spec:
+  replicas: {{ .Values.replicaCount }}
+  template:
+    metadata:
+      annotations: {{ include "chart.annotations" (dict "ctx" .) }}
+    spec:
+      serviceAccountName: {{ include "chart.serviceAccountName" . }}
+      containers:
+      - image: {{ include "chart.image" .Values.image }}
+`,
+}
+
+func writeChart(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for path, contents := range files {
+		fullPath := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("unable to create directory: %+v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(contents), 0600); err != nil {
+			t.Fatalf("unable to write %s: %+v", path, err)
+		}
+	}
+	return dir
+}
+
+func TestAnalyzeChartValuesFollowsIncludes(t *testing.T) {
+	usage, err := AnalyzeChartValues(writeChart(t, createdChart))
+	if err != nil {
+		t.Fatalf("unable to analyze chart: %+v", err)
+	}
+	if len(usage.Unused) != 1 || usage.Unused[0].Key() != "unusedKey" {
+		for _, key := range usage.Unused {
+			t.Logf("unused: %s", key.Key())
+		}
+		t.Fatalf("expected only unusedKey to be unused")
+	}
+	if len(usage.Undefined) != 0 {
+		for _, reference := range usage.Undefined {
+			t.Logf("undefined: %s at %s", reference.Key(), reference.Location())
+		}
+		t.Fatalf("expected no undefined values")
+	}
+}
+
+func TestAnalyzeChartValuesReportsUndefinedInIncludes(t *testing.T) {
+	files := map[string]string{
+		"values.yaml": "name: x\n",
+		"templates/_helpers.tpl": `{{- define "chart.name" -}}
+{{ .Values.name }}-{{ .Values.suffix }}
+{{- end }}
+{{- define "chart.unknown" -}}
+{{ .ctx.Values.other }}
+{{- end }}
+`,
+		"templates/configmap.yaml": `name: {{ include "chart.name" . }}
+other: {{ include "chart.unknown" (dict "ctx" .) }}
+`,
+	}
+	usage, err := AnalyzeChartValues(writeChart(t, files))
+	if err != nil {
+		t.Fatalf("unable to analyze chart: %+v", err)
+	}
+	if len(usage.Unused) != 0 {
+		t.Fatalf("expected no unused values, found %d", len(usage.Unused))
+	}
+	// inferred references, such as other, aren't reported
+	if len(usage.Undefined) != 1 || usage.Undefined[0].Key() != "suffix" {
+		t.Fatalf("expected only suffix to be undefined, found %+v", usage.Undefined)
+	}
+}
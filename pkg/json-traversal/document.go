@@ -0,0 +1,64 @@
+package json_traversal
+
+import (
+	"bytes"
+	"github.com/mattfenwick/collections/pkg/file"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"io"
+)
+
+// Document is one document from a (possibly multi-document) yaml or json file.
+// Node keeps the source structure -- key order and positions -- which is lost
+// when decoding into Value.
+type Document struct {
+	Index int
+	Node  *yaml.Node
+	Value interface{}
+}
+
+// ReadDocuments reads every document from a yaml file; since json is a subset of
+// yaml, this works for json files too.  Empty documents are skipped.
+func ReadDocuments(path string) ([]*Document, error) {
+	data, err := file.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDocuments(data)
+}
+
+func ParseDocuments(data []byte) ([]*Document, error) {
+	nodes, err := DecodeYamlNodes(data)
+	if err != nil {
+		return nil, err
+	}
+	var docs []*Document
+	for index, node := range nodes {
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, errors.Wrapf(err, "unable to decode document %d", index)
+		}
+		if value == nil {
+			continue
+		}
+		docs = append(docs, &Document{Index: index, Node: node, Value: value})
+	}
+	return docs, nil
+}
+
+// DecodeYamlNodes decodes every document, including empty ones
+func DecodeYamlNodes(data []byte) ([]*yaml.Node, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	var nodes []*yaml.Node
+	for index := 0; ; index++ {
+		node := &yaml.Node{}
+		err := decoder.Decode(node)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "unable to decode document %d", index)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,152 @@
+package json_traversal
+
+import (
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// SetByPath stores value at path within obj, creating intermediate maps (for
+// MapValue components) and arrays (for ArrayIndex components) as needed; arrays
+// are padded with nulls to reach the index.  A final MapKey component renames
+// the key: value must be the new key, a string.
+// Maps are modified in place, but arrays may need to grow, so callers must use
+// the returned object as the new root.
+func SetByPath(obj interface{}, path []*PathComponent, value interface{}) (interface{}, error) {
+	return setByPathHelper(obj, path, 0, value)
+}
+
+func setByPathHelper(obj interface{}, path []*PathComponent, i int, value interface{}) (interface{}, error) {
+	if i == len(path) {
+		return value, nil
+	}
+	component := path[i]
+	switch {
+	case component.ArrayIndex != nil:
+		if obj == nil {
+			obj = []interface{}{}
+		}
+		array, ok := obj.([]interface{})
+		if !ok {
+			return nil, errors.Errorf("expected array at %s, found %T", pathPrefixString(path, i), obj)
+		}
+		index := *component.ArrayIndex
+		if index < 0 {
+			return nil, errors.Errorf("negative array index at %s", pathPrefixString(path, i+1))
+		}
+		for len(array) <= index {
+			array = append(array, nil)
+		}
+		child, err := setByPathHelper(array[index], path, i+1, value)
+		if err != nil {
+			return nil, err
+		}
+		array[index] = child
+		return array, nil
+	case component.MapValue != nil:
+		if obj == nil {
+			obj = map[string]interface{}{}
+		}
+		dict, ok := obj.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("expected map at %s, found %T", pathPrefixString(path, i), obj)
+		}
+		child, err := setByPathHelper(dict[*component.MapValue], path, i+1, value)
+		if err != nil {
+			return nil, err
+		}
+		dict[*component.MapValue] = child
+		return dict, nil
+	case component.MapKey != nil:
+		if i != len(path)-1 {
+			return nil, errors.Errorf("MapKey must be the final path component: %s", pathPrefixString(path, i+1))
+		}
+		dict, ok := obj.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("expected map at %s, found %T", pathPrefixString(path, i), obj)
+		}
+		oldKey := *component.MapKey
+		v, ok := dict[oldKey]
+		if !ok {
+			return nil, errors.Errorf("key not found at %s", pathPrefixString(path, i+1))
+		}
+		newKey, ok := value.(string)
+		if !ok {
+			return nil, errors.Errorf("can only rename key at %s to a string, found %T", pathPrefixString(path, i+1), value)
+		}
+		if newKey == oldKey {
+			return dict, nil
+		}
+		if _, ok := dict[newKey]; ok {
+			return nil, errors.Errorf("unable to rename key at %s: key '%s' already exists", pathPrefixString(path, i+1), newKey)
+		}
+		delete(dict, oldKey)
+		dict[newKey] = v
+		return dict, nil
+	default:
+		return nil, errors.Errorf("invalid PathComponent at index %d: %+v", i, component)
+	}
+}
+
+// DeleteByPath removes whatever path points to: an array element (later elements
+// shift down by one), or a map entry for both MapValue and MapKey components.
+// Deleting a path that doesn't exist is a no-op.  As with SetByPath, callers
+// must use the returned object as the new root.
+func DeleteByPath(obj interface{}, path []*PathComponent) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	return deleteByPathHelper(obj, path, 0)
+}
+
+func deleteByPathHelper(obj interface{}, path []*PathComponent, i int) (interface{}, error) {
+	component := path[i]
+	isLast := i == len(path)-1
+	switch o := obj.(type) {
+	case []interface{}:
+		if component.ArrayIndex == nil {
+			return o, nil
+		}
+		index := *component.ArrayIndex
+		if index < 0 || index >= len(o) {
+			return o, nil
+		}
+		if isLast {
+			return append(o[:index], o[index+1:]...), nil
+		}
+		child, err := deleteByPathHelper(o[index], path, i+1)
+		if err != nil {
+			return nil, err
+		}
+		o[index] = child
+		return o, nil
+	case map[string]interface{}:
+		if component.MapKey != nil {
+			if !isLast {
+				return nil, errors.Errorf("MapKey must be the final path component: %s", pathPrefixString(path, i+1))
+			}
+			delete(o, *component.MapKey)
+			return o, nil
+		} else if component.MapValue != nil {
+			v, ok := o[*component.MapValue]
+			if !ok {
+				return o, nil
+			}
+			if isLast {
+				delete(o, *component.MapValue)
+				return o, nil
+			}
+			child, err := deleteByPathHelper(v, path, i+1)
+			if err != nil {
+				return nil, err
+			}
+			o[*component.MapValue] = child
+		}
+		return o, nil
+	default:
+		return obj, nil
+	}
+}
+
+func pathPrefixString(path []*PathComponent, length int) string {
+	return strings.Join(PathString(path[:length]), "")
+}
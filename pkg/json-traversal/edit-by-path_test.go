@@ -0,0 +1,156 @@
+package json_traversal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newEditTestObject() interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "app"},
+		"items":    []interface{}{"a", "b"},
+	}
+}
+
+func TestSetByPath(t *testing.T) {
+	for _, testCase := range []struct {
+		Name     string
+		Path     []*PathComponent
+		Value    interface{}
+		Expected interface{}
+		IsError  bool
+	}{
+		{
+			Name:  "replace map value",
+			Path:  []*PathComponent{NewMapValuePathComponent("metadata"), NewMapValuePathComponent("name")},
+			Value: "other",
+			Expected: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "other"},
+				"items":    []interface{}{"a", "b"},
+			},
+		},
+		{
+			Name:  "final MapKey renames the key",
+			Path:  []*PathComponent{NewMapValuePathComponent("metadata"), NewMapKeyPathComponent("name")},
+			Value: "generateName",
+			Expected: map[string]interface{}{
+				"metadata": map[string]interface{}{"generateName": "app"},
+				"items":    []interface{}{"a", "b"},
+			},
+		},
+		{
+			Name:    "rename to an existing key",
+			Path:    []*PathComponent{NewMapKeyPathComponent("items")},
+			Value:   "metadata",
+			IsError: true,
+		},
+		{
+			Name:    "MapKey which isn't final",
+			Path:    []*PathComponent{NewMapKeyPathComponent("metadata"), NewMapValuePathComponent("name")},
+			Value:   "x",
+			IsError: true,
+		},
+		{
+			Name:  "out of range array index pads with nulls",
+			Path:  []*PathComponent{NewMapValuePathComponent("items"), NewArrayPathComponent(3)},
+			Value: "d",
+			Expected: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "app"},
+				"items":    []interface{}{"a", "b", nil, "d"},
+			},
+		},
+		{
+			Name:    "negative array index",
+			Path:    []*PathComponent{NewMapValuePathComponent("items"), NewArrayPathComponent(-1)},
+			Value:   "d",
+			IsError: true,
+		},
+		{
+			Name:  "missing intermediate keys are created",
+			Path:  []*PathComponent{NewMapValuePathComponent("spec"), NewMapValuePathComponent("ports"), NewArrayPathComponent(0)},
+			Value: 80,
+			Expected: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "app"},
+				"items":    []interface{}{"a", "b"},
+				"spec":     map[string]interface{}{"ports": []interface{}{80}},
+			},
+		},
+		{
+			Name:    "array index into a map",
+			Path:    []*PathComponent{NewMapValuePathComponent("metadata"), NewArrayPathComponent(0)},
+			Value:   "x",
+			IsError: true,
+		},
+		{
+			Name:     "empty path replaces the root",
+			Path:     []*PathComponent{},
+			Value:    "root",
+			Expected: "root",
+		},
+	} {
+		found, err := SetByPath(newEditTestObject(), testCase.Path, testCase.Value)
+		if testCase.IsError {
+			if err == nil {
+				t.Errorf("%s: expected error, found %+v", testCase.Name, found)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %+v", testCase.Name, err)
+		} else if !reflect.DeepEqual(found, testCase.Expected) {
+			t.Errorf("%s: expected %+v, found %+v", testCase.Name, testCase.Expected, found)
+		}
+	}
+}
+
+func TestDeleteByPath(t *testing.T) {
+	for _, testCase := range []struct {
+		Name     string
+		Path     []*PathComponent
+		Expected interface{}
+		IsError  bool
+	}{
+		{
+			Name:     "empty path",
+			Path:     []*PathComponent{},
+			Expected: nil,
+		},
+		{
+			Name: "array element",
+			Path: []*PathComponent{NewMapValuePathComponent("items"), NewArrayPathComponent(0)},
+			Expected: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "app"},
+				"items":    []interface{}{"b"},
+			},
+		},
+		{
+			Name:     "out of range array index",
+			Path:     []*PathComponent{NewMapValuePathComponent("items"), NewArrayPathComponent(2)},
+			Expected: newEditTestObject(),
+		},
+		{
+			Name:     "MapKey deletes the entry",
+			Path:     []*PathComponent{NewMapKeyPathComponent("metadata")},
+			Expected: map[string]interface{}{"items": []interface{}{"a", "b"}},
+		},
+		{
+			Name:     "missing intermediate key",
+			Path:     []*PathComponent{NewMapValuePathComponent("spec"), NewMapValuePathComponent("replicas")},
+			Expected: newEditTestObject(),
+		},
+		{
+			Name:    "MapKey which isn't final",
+			Path:    []*PathComponent{NewMapKeyPathComponent("metadata"), NewMapValuePathComponent("name")},
+			IsError: true,
+		},
+	} {
+		found, err := DeleteByPath(newEditTestObject(), testCase.Path)
+		if testCase.IsError {
+			if err == nil {
+				t.Errorf("%s: expected error, found %+v", testCase.Name, found)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %+v", testCase.Name, err)
+		} else if !reflect.DeepEqual(found, testCase.Expected) {
+			t.Errorf("%s: expected %+v, found %+v", testCase.Name, testCase.Expected, found)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package json_traversal
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/file"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	goyaml "gopkg.in/yaml.v3"
+	"regexp"
+)
+
+type EditAction string
+
+const (
+	EditActionReplace EditAction = "replace"
+	EditActionRegex   EditAction = "regex"
+	EditActionDelete  EditAction = "delete"
+)
+
+type EditArgs struct {
+	File        string
+	Output      string
+	Selector    string
+	Action      string
+	Value       string
+	Regex       string
+	Replacement string
+}
+
+func RunEdit(args *EditArgs) error {
+	selector, err := ParseSelector(args.Selector)
+	if err != nil {
+		return err
+	}
+	edit, err := args.EditFunc()
+	if err != nil {
+		return err
+	}
+
+	data, err := file.Read(args.File)
+	if err != nil {
+		return err
+	}
+	docs, err := DecodeYamlNodes(data)
+	if err != nil {
+		return err
+	}
+
+	count, err := EditDocuments(docs, selector, edit)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("edited %d values across %d documents", count, len(docs))
+
+	out, err := MarshalDocuments(docs)
+	if err != nil {
+		return err
+	}
+	if args.Output == "" {
+		fmt.Print(string(out))
+		return nil
+	}
+	return file.Write(args.Output, out, 0644)
+}
+
+// EditFunc computes a value's replacement; returning false deletes the value.
+type EditFunc func(result *Result) (interface{}, bool, error)
+
+func (a *EditArgs) EditFunc() (EditFunc, error) {
+	switch EditAction(a.Action) {
+	case EditActionReplace:
+		// parse the value as yaml, so that `--value 3` is a number and `--value '"3"'` is a string
+		var value interface{}
+		if err := goyaml.Unmarshal([]byte(a.Value), &value); err != nil {
+			return nil, errors.Wrapf(err, "unable to parse value '%s' as yaml", a.Value)
+		}
+		return func(result *Result) (interface{}, bool, error) {
+			return value, true, nil
+		}, nil
+	case EditActionRegex:
+		re, err := regexp.Compile(a.Regex)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to compile regex '%s'", a.Regex)
+		}
+		return func(result *Result) (interface{}, bool, error) {
+			s, ok := result.Value.(string)
+			if !ok {
+				logrus.Warnf("skipping regex substitution for non-string value at %s: %T", pathPrefixString(result.Path, len(result.Path)), result.Value)
+				return result.Value, true, nil
+			}
+			return re.ReplaceAllString(s, a.Replacement), true, nil
+		}, nil
+	case EditActionDelete:
+		return func(result *Result) (interface{}, bool, error) {
+			return nil, false, nil
+		}, nil
+	default:
+		return nil, errors.Errorf("invalid edit action '%s'; expected one of [%s, %s, %s]", a.Action, EditActionReplace, EditActionRegex, EditActionDelete)
+	}
+}
+
+// EditDocuments applies edit to every match of selector in every document,
+// returning the number of matches.  Documents are modified in place, editing
+// their yaml nodes so that key order, comments and styles are kept.
+func EditDocuments(docs []*goyaml.Node, selector []*Selector, edit EditFunc) (int, error) {
+	count := 0
+	for i, doc := range docs {
+		// go backwards, so that deleting an array element doesn't shift the
+		// indexes of matches which haven't been processed yet
+		results := slice.Reverse(YamlNodeFindBySelector(doc, selector, []*PathComponent{}))
+		for _, result := range results {
+			value, keep, err := edit(result)
+			if err != nil {
+				return 0, err
+			}
+			if keep {
+				err = setYamlNodeByPath(doc, result.Path, value)
+			} else {
+				err = deleteYamlNodeByPath(doc, result.Path)
+			}
+			if err != nil {
+				return 0, errors.Wrapf(err, "unable to edit document %d", i)
+			}
+		}
+		count += len(results)
+	}
+	return count, nil
+}
+
+// setYamlNodeByPath is SetByPath for yaml nodes, except that path must already
+// exist.  The replaced node's comments are kept; a string replacing a string
+// only changes the value, keeping its quoting style.
+func setYamlNodeByPath(doc *goyaml.Node, path []*PathComponent, value interface{}) error {
+	if len(path) == 0 {
+		if doc.Kind != goyaml.DocumentNode || len(doc.Content) == 0 {
+			return errors.Errorf("expected document node, found kind %d", doc.Kind)
+		}
+		return replaceYamlNode(doc.Content, 0, value)
+	}
+	parent := YamlNodeFindByPath(doc, path[:len(path)-1])
+	if parent == nil {
+		return errors.Errorf("path not found: %s", pathPrefixString(path, len(path)-1))
+	}
+	component := path[len(path)-1]
+	switch parent.Kind {
+	case goyaml.SequenceNode:
+		if component.ArrayIndex == nil || *component.ArrayIndex < 0 || *component.ArrayIndex >= len(parent.Content) {
+			return errors.Errorf("path not found: %s", pathPrefixString(path, len(path)))
+		}
+		return replaceYamlNode(parent.Content, *component.ArrayIndex, value)
+	case goyaml.MappingNode:
+		for i := 0; i+1 < len(parent.Content); i += 2 {
+			key := parent.Content[i]
+			if component.MapKey != nil && key.Value == *component.MapKey {
+				return renameYamlKey(parent, i, path, value)
+			} else if component.MapValue != nil && key.Value == *component.MapValue {
+				return replaceYamlNode(parent.Content, i+1, value)
+			}
+		}
+	}
+	return errors.Errorf("path not found: %s", pathPrefixString(path, len(path)))
+}
+
+func replaceYamlNode(nodes []*goyaml.Node, index int, value interface{}) error {
+	old := nodes[index]
+	if s, ok := value.(string); ok && old.Kind == goyaml.ScalarNode && old.ShortTag() == "!!str" {
+		old.Value = s
+		return nil
+	}
+	node := &goyaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return errors.Wrapf(err, "unable to encode value %+v", value)
+	}
+	node.HeadComment, node.LineComment, node.FootComment = old.HeadComment, old.LineComment, old.FootComment
+	nodes[index] = node
+	return nil
+}
+
+func renameYamlKey(mapping *goyaml.Node, keyIndex int, path []*PathComponent, value interface{}) error {
+	key := mapping.Content[keyIndex]
+	newKey, ok := value.(string)
+	if !ok {
+		return errors.Errorf("can only rename key at %s to a string, found %T", pathPrefixString(path, len(path)), value)
+	}
+	if newKey == key.Value {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == newKey {
+			return errors.Errorf("unable to rename key at %s: key '%s' already exists", pathPrefixString(path, len(path)), newKey)
+		}
+	}
+	key.Value = newKey
+	return nil
+}
+
+// deleteYamlNodeByPath is DeleteByPath for yaml nodes: deleting a path that
+// doesn't exist is a no-op, and deleting the root leaves a null document.
+func deleteYamlNodeByPath(doc *goyaml.Node, path []*PathComponent) error {
+	if len(path) == 0 {
+		if doc.Kind == goyaml.DocumentNode {
+			doc.Content = []*goyaml.Node{{Kind: goyaml.ScalarNode, Tag: "!!null", Value: "null"}}
+		}
+		return nil
+	}
+	parent := YamlNodeFindByPath(doc, path[:len(path)-1])
+	if parent == nil {
+		return nil
+	}
+	component := path[len(path)-1]
+	switch parent.Kind {
+	case goyaml.SequenceNode:
+		if component.ArrayIndex != nil && *component.ArrayIndex >= 0 && *component.ArrayIndex < len(parent.Content) {
+			index := *component.ArrayIndex
+			parent.Content = append(parent.Content[:index], parent.Content[index+1:]...)
+		}
+	case goyaml.MappingNode:
+		for i := 0; i+1 < len(parent.Content); i += 2 {
+			key := parent.Content[i].Value
+			if (component.MapKey != nil && key == *component.MapKey) || (component.MapValue != nil && key == *component.MapValue) {
+				parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func MarshalDocuments(docs []*goyaml.Node) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	encoder := goyaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+	for _, doc := range docs {
+		if err := encoder.Encode(doc); err != nil {
+			return nil, errors.Wrapf(err, "unable to marshal yaml")
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, errors.Wrapf(err, "unable to marshal yaml")
+	}
+	return buffer.Bytes(), nil
+}
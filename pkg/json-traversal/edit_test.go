@@ -0,0 +1,136 @@
+package json_traversal
+
+import (
+	"testing"
+)
+
+const editTestYaml = `# deployment
+kind: Deployment
+metadata:
+  name: app # the app
+  labels:
+    version: v1
+spec:
+  replicas: 1
+  ports: [80, 443, 8080]
+---
+---
+kind: Service
+metadata:
+  name: app
+`
+
+func runTestEdit(t *testing.T, args *EditArgs) string {
+	selector, err := ParseSelector(args.Selector)
+	if err != nil {
+		t.Fatalf("unable to parse selector: %+v", err)
+	}
+	edit, err := args.EditFunc()
+	if err != nil {
+		t.Fatalf("unable to build edit: %+v", err)
+	}
+	docs, err := DecodeYamlNodes([]byte(editTestYaml))
+	if err != nil {
+		t.Fatalf("unable to decode documents: %+v", err)
+	}
+	if _, err := EditDocuments(docs, selector, edit); err != nil {
+		t.Fatalf("unable to edit documents: %+v", err)
+	}
+	out, err := MarshalDocuments(docs)
+	if err != nil {
+		t.Fatalf("unable to marshal documents: %+v", err)
+	}
+	return string(out)
+}
+
+func TestEditDocuments(t *testing.T) {
+	for _, testCase := range []struct {
+		Name     string
+		Args     *EditArgs
+		Expected string
+	}{
+		{
+			Name: "replace",
+			Args: &EditArgs{Selector: `["spec"]["replicas"]`, Action: "replace", Value: "3"},
+			Expected: `# deployment
+kind: Deployment
+metadata:
+  name: app # the app
+  labels:
+    version: v1
+spec:
+  replicas: 3
+  ports: [80, 443, 8080]
+---
+
+---
+kind: Service
+metadata:
+  name: app
+`,
+		},
+		{
+			Name: "regex keeps comments, and quotes strings which look like numbers",
+			Args: &EditArgs{Selector: `["metadata"][*]`, Action: "regex", Regex: "^(app|v)", Replacement: "1"},
+			Expected: `# deployment
+kind: Deployment
+metadata:
+  name: "1" # the app
+  labels:
+    version: v1
+spec:
+  replicas: 1
+  ports: [80, 443, 8080]
+---
+
+---
+kind: Service
+metadata:
+  name: "1"
+`,
+		},
+		{
+			Name: "delete array elements and map entries",
+			Args: &EditArgs{Selector: `["spec"]["ports"][*]`, Action: "delete"},
+			Expected: `# deployment
+kind: Deployment
+metadata:
+  name: app # the app
+  labels:
+    version: v1
+spec:
+  replicas: 1
+  ports: []
+---
+
+---
+kind: Service
+metadata:
+  name: app
+`,
+		},
+		{
+			Name: "delete map entries",
+			Args: &EditArgs{Selector: `["metadata"]["labels"]`, Action: "delete"},
+			Expected: `# deployment
+kind: Deployment
+metadata:
+  name: app # the app
+spec:
+  replicas: 1
+  ports: [80, 443, 8080]
+---
+
+---
+kind: Service
+metadata:
+  name: app
+`,
+		},
+	} {
+		found := runTestEdit(t, testCase.Args)
+		if found != testCase.Expected {
+			t.Errorf("%s: expected\n%s\nfound\n%s", testCase.Name, testCase.Expected, found)
+		}
+	}
+}
@@ -9,32 +9,51 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"os"
 	"regexp"
 	"strings"
 )
 
 func Executable() {
-	mode := "find-by-path-nested-items"
-
-	switch mode {
-	case "find-by-path-nested-items":
-		RunFindByPathNestedItems()
-	case "find-by-path":
-		logrus.SetLevel(logrus.DebugLevel)
-		RunFindByPath()
-	case "find-by-regex":
-		RunFindByRegex()
-	default:
-		panic("invalid mode")
-	}
+	command := SetupRootCommand()
+	utils.DoOrDie(errors.Wrapf(command.Execute(), "run root command"))
 }
 
-func RunFindByPathNestedItems() {
-	path := os.Args[1]
+func SetupRootCommand() *cobra.Command {
+	var verbosity string
+	command := &cobra.Command{
+		Use:   "query-json",
+		Short: "search and edit json and yaml documents",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.SetUpLogger(verbosity)
+		},
+	}
+
+	command.PersistentFlags().StringVarP(&verbosity, "verbosity", "v", "info", "log level; one of [info, debug, trace, warn, error, fatal, panic]")
+
+	command.AddCommand(&cobra.Command{
+		Use:   "find-by-path-nested-items <path>",
+		Short: "find nested items in a swagger spec",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, as []string) {
+			RunFindByPathNestedItems(as[0])
+		},
+	})
+	command.AddCommand(&cobra.Command{
+		Use:   "find-by-path <path>",
+		Short: "find group/version/kinds in a swagger spec",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, as []string) {
+			RunFindByPath(as[0])
+		},
+	})
+	command.AddCommand(setupFindBySelectorCommand())
+	command.AddCommand(setupFindByRegexCommand())
+	command.AddCommand(setupEditCommand())
 
-	logrus.SetLevel(logrus.DebugLevel)
+	return command
+}
 
+func RunFindByPathNestedItems(path string) {
 	nestedItemsSelector := []*Selector{
 		{Key: utils.Pointer("definitions")},
 		{IsGlob: true},
@@ -43,23 +62,18 @@ func RunFindByPathNestedItems() {
 		{Key: utils.Pointer("items")},
 	}
 
-	obj, err := json.ParseFile[map[string]interface{}](path)
-	utils.DoOrDie(errors.Wrapf(err, "unable to read json from %s", path))
-
-	results := JsonFindBySelector(*obj, nestedItemsSelector, []*PathComponent{})
+	results := findInFileBySelector(path, nestedItemsSelector)
 
 	if len(results) == 0 {
 		fmt.Println("found 0 results")
 	}
 
 	for _, result := range results {
-		fmt.Printf("result: - %s\n - %+v\n", PathString(result.Path), result.Value)
+		fmt.Printf("result: %s\n - %s\n - %+v\n", SourceLocation(path, result.Position()), PathString(result.Path), result.Value)
 	}
 }
 
-func RunFindByPath() {
-	path := os.Args[1]
-
+func RunFindByPath(path string) {
 	selector := []*Selector{
 		{Key: utils.Pointer("definitions")},
 		{IsGlob: true},
@@ -69,32 +83,83 @@ func RunFindByPath() {
 	}
 	// ["definitions"]["io.k8s.api.extensions.v1beta1.Ingress"]["x-kubernetes-group-version-kind"][0]["kind"]
 
-	obj, err := json.ParseFile[map[string]interface{}](path)
-	utils.DoOrDie(errors.Wrapf(err, "unable to read json from %s", path))
-
 	results := slice.SortOn(func(a *Result) string {
 		return a.Value.(string)
-	}, JsonFindBySelector(*obj, selector, []*PathComponent{}))
+	}, findInFileBySelector(path, selector))
 
 	tableString := &strings.Builder{}
 	table := tablewriter.NewWriter(tableString)
 	table.SetAutoWrapText(false)
 	table.SetRowLine(true)
 	table.SetAutoMergeCells(true)
-	table.SetHeader([]string{"group", "type"})
+	table.SetHeader([]string{"group", "type", "location"})
 	for _, result := range results {
-		//fmt.Printf("value %+v at %s\n", result.Value, PathString(result.Path))
-		//fmt.Printf("%s/%s\n", result.Path[1].RawString(), result.Value)
-		table.Append([]string{result.Path[1].RawString(), result.Value.(string)})
+		table.Append([]string{result.Path[1].RawString(), result.Value.(string), SourceLocation(path, result.Position())})
 	}
 	table.Render()
 	fmt.Printf("%s\n", tableString)
 }
 
+type FindBySelectorArgs struct {
+	File       string
+	Selector   string
+	PathFormat string
+}
+
+func setupFindBySelectorCommand() *cobra.Command {
+	args := &FindBySelectorArgs{}
+
+	command := &cobra.Command{
+		Use:   "find-by-selector",
+		Short: "find every match of a selector in json or multi-document yaml",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			RunFindBySelector(args)
+		},
+	}
+
+	command.Flags().StringVar(&args.File, "file", "", "json or yaml file in which to search")
+	utils.DoOrDie(command.MarkFlagRequired("file"))
+
+	command.Flags().StringVar(&args.Selector, "selector", "", `selector to match, example: ["spec"]["template"]["spec"]["containers"][*]["image"]`)
+	utils.DoOrDie(command.MarkFlagRequired("selector"))
+
+	command.Flags().StringVar(&args.PathFormat, "path-format", string(PathFormatNative), fmt.Sprintf("how to print paths; one of %+v", AllPathFormats))
+
+	return command
+}
+
+func RunFindBySelector(args *FindBySelectorArgs) {
+	selector, err := ParseSelector(args.Selector)
+	utils.DoOrDie(err)
+	pathFormat, err := ParsePathFormat(args.PathFormat)
+	utils.DoOrDie(err)
+
+	for _, result := range findInFileBySelector(args.File, selector) {
+		value, err := json.MarshalWithOptions(result.Value, &json.MarshalOptions{EscapeHTML: false, Indent: false, Sort: false})
+		utils.DoOrDie(err)
+		fmt.Printf("%s: %s: %s", SourceLocation(args.File, result.Position()), FormatPathOrNative(result.Path, pathFormat), value)
+	}
+}
+
+func findInFileBySelector(path string, selector []*Selector) []*Result {
+	docs, err := ReadDocuments(path)
+	utils.DoOrDie(errors.Wrapf(err, "unable to read documents from %s", path))
+
+	var results []*Result
+	for _, doc := range docs {
+		results = append(results, YamlNodeFindBySelector(doc.Node, selector, []*PathComponent{})...)
+	}
+	return results
+}
+
 type FindByRegexArgs struct {
-	File      string
-	Regex     string
-	StartPath []string
+	File       string
+	Regex      string
+	StartPath  []string
+	Order      string
+	Unique     bool
+	PathFormat string
 }
 
 func (a *FindByRegexArgs) Json() string {
@@ -123,63 +188,138 @@ func (a *FindByRegexArgs) StartPathSelector() []*Selector {
 
 func setupFindByRegexCommand() *cobra.Command {
 	var configPath string
+	flagArgs := &FindByRegexArgs{}
 
 	command := &cobra.Command{
 		Use:   "find-json",
-		Short: "find strings in a JSON blob",
+		Short: "find strings in json or multi-document yaml",
 		Args:  cobra.ExactArgs(0),
 		Run: func(cmd *cobra.Command, as []string) {
-			args := &FindByRegexArgs{}
-			args, err := json.ParseFile[FindByRegexArgs](configPath)
-			utils.DoOrDie(err)
+			args := flagArgs
+			if configPath != "" {
+				var err error
+				args, err = json.ParseFile[FindByRegexArgs](configPath)
+				utils.DoOrDie(err)
+				// flags on the command line take precedence over the config file
+				if cmd.Flags().Changed("file") {
+					args.File = flagArgs.File
+				}
+				if cmd.Flags().Changed("regex") {
+					args.Regex = flagArgs.Regex
+				}
+				if cmd.Flags().Changed("start-path") {
+					args.StartPath = flagArgs.StartPath
+				}
+				if cmd.Flags().Changed("order") || args.Order == "" {
+					args.Order = flagArgs.Order
+				}
+				if cmd.Flags().Changed("unique") {
+					args.Unique = flagArgs.Unique
+				}
+				if cmd.Flags().Changed("path-format") || args.PathFormat == "" {
+					args.PathFormat = flagArgs.PathFormat
+				}
+			}
 			RunFindInJsonByRegex(args)
 		},
 	}
 
-	command.Flags().StringVar(&configPath, "config-path", "", "path to json config file")
-	utils.DoOrDie(command.MarkFlagRequired("config-path"))
+	command.Flags().StringVar(&configPath, "config-path", "", "path to json config file; flags override its values")
 
-	//command.Flags().StringVar(&args.File, "file", "", "json file in which to search")
-	//
-	//command.Flags().StringVar(&args.Regex, "regex", "", "regex to search for")
-	//utils.DoOrDie(command.MarkFlagRequired("regex"))
-	//
-	//command.Flags().StringSliceVar(&args.StartPath, "start-path", []string{}, "path to search under")
+	command.Flags().StringVar(&flagArgs.File, "file", "", "json or yaml file in which to search")
+	command.Flags().StringVar(&flagArgs.Regex, "regex", "", "regex to search for")
+	command.Flags().StringArrayVar(&flagArgs.StartPath, "start-path", []string{}, `path component to search under, repeatable: '"key"', array index, or '*'`)
+	command.Flags().StringVar(&flagArgs.Order, "order", string(OutputOrderPath), fmt.Sprintf("order of results; one of %+v", AllOutputOrders))
+	command.Flags().BoolVar(&flagArgs.Unique, "unique", false, "if true, prints each distinct matching value once, with a count")
+	command.Flags().StringVar(&flagArgs.PathFormat, "path-format", string(PathFormatNative), fmt.Sprintf("how to print paths; one of %+v", AllPathFormats))
 
 	return command
 }
 
-func RunFindByRegex() {
-	logrus.SetLevel(logrus.DebugLevel)
-
-	command := setupFindByRegexCommand()
-	utils.DoOrDie(errors.Wrapf(command.Execute(), "run root command"))
-}
-
 func RunFindInJsonByRegex(args *FindByRegexArgs) {
 	logrus.Infof("configuration: %s", args.Json())
 
-	path := args.File
-	regexString := args.Regex
+	order, err := ParseOutputOrder(args.Order)
+	utils.DoOrDie(err)
+	pathFormat, err := ParsePathFormat(args.PathFormat)
+	utils.DoOrDie(err)
 
-	obj, err := json.ParseFile[map[string]interface{}](path)
+	docs, err := ReadDocuments(args.File)
 	utils.DoOrDie(err)
 
-	re := regexp.MustCompile(regexString)
-	var matches []*KeyMatch
+	re, err := regexp.Compile(args.Regex)
+	utils.DoOrDie(errors.Wrapf(err, "unable to compile regex '%s'", args.Regex))
+
+	var matches []*DocumentMatch
+	for _, doc := range docs {
+		var docMatches []*KeyMatch
+		if len(args.StartPath) > 0 {
+			pathSelectorResults := YamlNodeFindBySelector(doc.Node, args.StartPathSelector(), []*PathComponent{})
+
+			for _, result := range pathSelectorResults {
+				logrus.Infof("searching under: %s", PathString(result.Path))
+				docMatches = append(docMatches, YamlNodeFindByRegex(YamlNodeFindByPath(doc.Node, result.Path), result.Path, re)...)
+			}
+		} else {
+			docMatches = YamlNodeFindByRegex(doc.Node, []*PathComponent{}, re)
+		}
+		for _, match := range docMatches {
+			matches = append(matches, &DocumentMatch{Document: doc, Match: match})
+		}
+	}
 
-	if len(args.StartPath) > 0 {
-		pathSelectorResults := JsonFindBySelector(*obj, args.StartPathSelector(), []*PathComponent{})
+	matches = SortMatches(matches, order)
 
-		for _, result := range pathSelectorResults {
-			logrus.Infof("searching under: %s", PathString(result.Path))
-			matches = append(matches, JsonFindByRegex(JsonFindByPath(*obj, result.Path), result.Path, re)...)
+	if args.Unique {
+		tableString := &strings.Builder{}
+		table := tablewriter.NewWriter(tableString)
+		table.SetAutoWrapText(false)
+		table.SetRowLine(true)
+		table.SetHeader([]string{"Value", "Count"})
+		for _, count := range UniqueValues(matches) {
+			table.Append([]string{count.Value, fmt.Sprintf("%d", count.Count)})
 		}
-	} else {
-		matches = append(matches, JsonFindByRegex(*obj, []*PathComponent{}, re)...)
+		table.Render()
+		fmt.Printf("%s\n", tableString)
+		return
 	}
 
 	for _, match := range matches {
-		fmt.Printf("%s: %s\n", strings.Join(match.PathString(), ""), match.Value)
+		fmt.Printf("%s: %s: %s\n", SourceLocation(args.File, match.Match.Position()), FormatPathOrNative(match.Match.Path, pathFormat), match.Match.Value)
 	}
 }
+
+// SourceLocation renders a position as `file:line:col`
+func SourceLocation(file string, position *SourcePosition) string {
+	if position == nil {
+		return file
+	}
+	return fmt.Sprintf("%s:%s", file, position.String())
+}
+
+func setupEditCommand() *cobra.Command {
+	args := &EditArgs{}
+
+	command := &cobra.Command{
+		Use:   "edit",
+		Short: "replace, regex-substitute or delete every match of a selector in multi-document yaml",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			utils.DoOrDie(RunEdit(args))
+		},
+	}
+
+	command.Flags().StringVar(&args.File, "file", "", "yaml file to edit; may contain multiple documents")
+	utils.DoOrDie(command.MarkFlagRequired("file"))
+
+	command.Flags().StringVar(&args.Selector, "selector", "", `selector to match in each document, example: ["spec"]["template"]["spec"]["containers"][*]["image"]`)
+	utils.DoOrDie(command.MarkFlagRequired("selector"))
+
+	command.Flags().StringVar(&args.Action, "action", string(EditActionReplace), fmt.Sprintf("what to do with each match; one of [%s, %s, %s]", EditActionReplace, EditActionRegex, EditActionDelete))
+	command.Flags().StringVar(&args.Value, "value", "", "for replace: new value, parsed as yaml")
+	command.Flags().StringVar(&args.Regex, "regex", "", "for regex: regex to match in string values")
+	command.Flags().StringVar(&args.Replacement, "replacement", "", "for regex: replacement string; may refer to capture groups, e.g. ${1}")
+	command.Flags().StringVar(&args.Output, "output", "", "file to write edited yaml to; if empty, prints to stdout")
+
+	return command
+}
@@ -1,33 +1,44 @@
 package json_traversal
 
+// JsonFindByPath walks obj along path and returns the value found there, or nil
+// if the path doesn't exist.  PathComponent semantics:
+//   - ArrayIndex: the element at that index of an array
+//   - MapValue: the value stored under that key of a map
+//   - MapKey: the key itself (a string), if the map contains it.  Since a key
+//     has no children, MapKey is only meaningful as the final component.
 func JsonFindByPath(obj interface{}, path []*PathComponent) interface{} {
-	for _, component := range path {
+	value, _ := JsonLookupByPath(obj, path)
+	return value
+}
+
+// JsonLookupByPath is like JsonFindByPath, but also reports whether the path
+// exists -- which distinguishes a missing value from an explicit null.
+func JsonLookupByPath(obj interface{}, path []*PathComponent) (interface{}, bool) {
+	for i, component := range path {
 		switch o := obj.(type) {
 		case []interface{}:
-			if component.ArrayIndex != nil {
-				if len(o) > *component.ArrayIndex {
-					obj = o[*component.ArrayIndex]
-				} else {
-					return nil
-				}
-			} else {
-				return nil
+			if component.ArrayIndex == nil || *component.ArrayIndex < 0 || *component.ArrayIndex >= len(o) {
+				return nil, false
 			}
+			obj = o[*component.ArrayIndex]
 		case map[string]interface{}:
 			if component.MapKey != nil {
-				if _, ok := o[*component.MapKey]; ok {
-					obj = *component.MapKey
-				} else {
-					return nil
+				if _, ok := o[*component.MapKey]; !ok || i != len(path)-1 {
+					return nil, false
 				}
+				obj = *component.MapKey
 			} else if component.MapValue != nil {
-				obj = o[*component.MapValue]
+				v, ok := o[*component.MapValue]
+				if !ok {
+					return nil, false
+				}
+				obj = v
 			} else {
-				return nil
+				return nil, false
 			}
 		default:
-			return nil
+			return nil, false
 		}
 	}
-	return obj
+	return obj, true
 }
@@ -1,12 +1,15 @@
 package json_traversal
 
 import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/slice"
 	"github.com/mattfenwick/kube-utils/pkg/utils"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/exp/maps"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 type Selector struct {
@@ -15,11 +18,66 @@ type Selector struct {
 	Key     *string
 }
 
+func (s *Selector) String() string {
+	if s.IsGlob {
+		return "[*]"
+	} else if s.IsArray {
+		return fmt.Sprintf("[%s]", *s.Key)
+	}
+	return fmt.Sprintf("[%s]", quoteKey(*s.Key))
+}
+
+// ParseSelector parses the same notation as ParsePath, minus MapKeys and plus a
+// glob: `["spec"]["containers"][*]["image"]`.
+func ParseSelector(s string) ([]*Selector, error) {
+	var selectors []*Selector
+	rest := strings.TrimSpace(s)
+	for len(rest) > 0 {
+		if rest[0] != '[' {
+			return nil, errors.Errorf("unable to parse selector '%s': expected '[' at '%s'", s, rest)
+		}
+		rest = rest[1:]
+		var selector *Selector
+		if strings.HasPrefix(rest, `"`) {
+			key, remaining, err := readJsonString(rest)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to parse key in selector '%s'", s)
+			}
+			rest = remaining
+			selector = &Selector{Key: &key}
+		} else {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, errors.Errorf("unable to parse selector '%s': missing ']'", s)
+			}
+			content := rest[:end]
+			rest = rest[end:]
+			if content == "*" {
+				selector = &Selector{IsGlob: true}
+			} else if index, err := strconv.Atoi(content); err == nil && index >= 0 {
+				selector = &Selector{IsArray: true, Key: &content}
+			} else {
+				return nil, errors.Errorf("unable to parse selector '%s': invalid component '%s'", s, content)
+			}
+		}
+		if len(rest) == 0 || rest[0] != ']' {
+			return nil, errors.Errorf("unable to parse selector '%s': missing ']'", s)
+		}
+		rest = rest[1:]
+		selectors = append(selectors, selector)
+	}
+	return selectors, nil
+}
+
 type Result struct {
 	Path  []*PathComponent
 	Value interface{}
 }
 
+func (r *Result) Position() *SourcePosition {
+	return PathPosition(r.Path)
+}
+
 func JsonFindBySelector(obj interface{}, selector []*Selector, context []*PathComponent) []*Result {
 	logrus.Debugf("JsonFindBySelector: %s", PathString(context))
 	if len(selector) == 0 {
@@ -37,16 +95,16 @@ func JsonFindBySelector(obj interface{}, selector []*Selector, context []*PathCo
 			for i, e := range o {
 				index := i
 				results = append(results,
-					JsonFindBySelector(e, selector[1:], append(context, NewArrayPathComponent(index)))...)
+					JsonFindBySelector(e, selector[1:], appendPath(context, NewArrayPathComponent(index)))...)
 			}
 		case map[string]interface{}:
 			logrus.Debugf("searching under glob map")
-			for k, v := range o {
+			for _, k := range slice.Sort(maps.Keys(o)) {
 				results = append(results,
-					JsonFindBySelector(v, selector[1:], append(context, NewMapValuePathComponent(k)))...)
+					JsonFindBySelector(o[k], selector[1:], appendPath(context, NewMapValuePathComponent(k)))...)
 			}
 		default:
-			panic(errors.Errorf("can only glob slice or map, found %T", o))
+			logrus.Debugf("can only glob slice or map, skipping type %T", o)
 		}
 	} else {
 		logrus.Debugf("searching under key")
@@ -59,7 +117,7 @@ func JsonFindBySelector(obj interface{}, selector []*Selector, context []*PathCo
 				utils.DoOrDie(errors.Wrapf(err, "unable to ParseInt from %s", *next.Key))
 				if index < len(o) {
 					results = append(results,
-						JsonFindBySelector(o[index], selector[1:], append(context, NewArrayPathComponent(index)))...)
+						JsonFindBySelector(o[index], selector[1:], appendPath(context, NewArrayPathComponent(index)))...)
 				}
 			}
 		case map[string]interface{}:
@@ -69,13 +127,9 @@ func JsonFindBySelector(obj interface{}, selector []*Selector, context []*PathCo
 				logrus.Debugf("key '%s' in map? %t", *next.Key, ok)
 				if ok {
 					results = append(results,
-						JsonFindBySelector(v, selector[1:], append(context, NewMapValuePathComponent(*next.Key)))...)
+						JsonFindBySelector(v, selector[1:], appendPath(context, NewMapValuePathComponent(*next.Key)))...)
 				} else {
-					logrus.Infof("did not find key %s; keys: %+v", *next.Key, maps.Keys(o))
-				}
-
-				for k := range o {
-					logrus.Debugf("key in map: %s", k)
+					logrus.Debugf("did not find key %s; keys: %+v", *next.Key, slice.Sort(maps.Keys(o)))
 				}
 			}
 		default:
@@ -94,6 +148,10 @@ func (k *KeyMatch) PathString() []string {
 	return PathString(k.Path)
 }
 
+func (k *KeyMatch) Position() *SourcePosition {
+	return PathPosition(k.Path)
+}
+
 func JsonFindByRegex(obj interface{}, path []*PathComponent, re *regexp.Regexp) []*KeyMatch {
 	switch o := obj.(type) {
 	case string:
@@ -108,20 +166,21 @@ func JsonFindByRegex(obj interface{}, path []*PathComponent, re *regexp.Regexp)
 		var matches []*KeyMatch
 		for i, e := range o {
 			index := i
-			matches = append(matches, JsonFindByRegex(e, append(path, &PathComponent{ArrayIndex: &index}), re)...)
+			matches = append(matches, JsonFindByRegex(e, appendPath(path, &PathComponent{ArrayIndex: &index}), re)...)
 		}
 		return matches
 	case map[string]interface{}:
 		var matches []*KeyMatch
-		for k, v := range o {
+		for _, k := range slice.Sort(maps.Keys(o)) {
 			key := k
+			v := o[k]
 			if re.FindString(k) != "" {
 				matches = append(matches, &KeyMatch{
-					Path:  append(path, &PathComponent{MapKey: &key}),
+					Path:  appendPath(path, &PathComponent{MapKey: &key}),
 					Value: key,
 				})
 			}
-			matches = append(matches, JsonFindByRegex(v, append(path, &PathComponent{MapValue: &key}), re)...)
+			matches = append(matches, JsonFindByRegex(v, appendPath(path, &PathComponent{MapValue: &key}), re)...)
 		}
 		return matches
 	default:
@@ -0,0 +1,171 @@
+package json_traversal
+
+import (
+	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"regexp"
+	"strconv"
+)
+
+// These are the yaml node counterparts of JsonFindBySelector and JsonFindByRegex:
+// same semantics, but they visit map entries in source order and record each
+// PathComponent's source position.
+
+func YamlNodeFindBySelector(node *yaml.Node, selector []*Selector, context []*PathComponent) []*Result {
+	node = resolveYamlNode(node)
+	if node == nil {
+		return nil
+	}
+	if len(selector) == 0 {
+		var value interface{}
+		utils.DoOrDie(errors.Wrapf(node.Decode(&value), "unable to decode yaml node at %s", pathPrefixString(context, len(context))))
+		return []*Result{{Path: context, Value: value}}
+	}
+
+	next := selector[0]
+	var results []*Result
+
+	switch node.Kind {
+	case yaml.SequenceNode:
+		if next.IsGlob {
+			for i, e := range node.Content {
+				results = append(results,
+					YamlNodeFindBySelector(e, selector[1:], appendPath(context, newPositionedArrayPathComponent(i, e)))...)
+			}
+		} else if next.IsArray {
+			index, err := strconv.Atoi(*next.Key)
+			utils.DoOrDie(errors.Wrapf(err, "unable to ParseInt from %s", *next.Key))
+			if index < len(node.Content) {
+				e := node.Content[index]
+				results = append(results,
+					YamlNodeFindBySelector(e, selector[1:], appendPath(context, newPositionedArrayPathComponent(index, e)))...)
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if next.IsGlob || (!next.IsArray && key.Value == *next.Key) {
+				results = append(results,
+					YamlNodeFindBySelector(value, selector[1:], appendPath(context, newPositionedMapValuePathComponent(key.Value, value)))...)
+			}
+		}
+	default:
+		logrus.Debugf("skipping yaml node kind %d", node.Kind)
+	}
+	return results
+}
+
+func YamlNodeFindByRegex(node *yaml.Node, path []*PathComponent, re *regexp.Regexp) []*KeyMatch {
+	node = resolveYamlNode(node)
+	if node == nil {
+		return nil
+	}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!str" && re.FindString(node.Value) != "" {
+			return []*KeyMatch{{
+				Path:  path,
+				Value: node.Value,
+			}}
+		}
+		return nil
+	case yaml.SequenceNode:
+		var matches []*KeyMatch
+		for i, e := range node.Content {
+			matches = append(matches, YamlNodeFindByRegex(e, appendPath(path, newPositionedArrayPathComponent(i, e)), re)...)
+		}
+		return matches
+	case yaml.MappingNode:
+		var matches []*KeyMatch
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if re.FindString(key.Value) != "" {
+				matches = append(matches, &KeyMatch{
+					Path:  appendPath(path, newPositionedMapKeyPathComponent(key.Value, key)),
+					Value: key.Value,
+				})
+			}
+			matches = append(matches, YamlNodeFindByRegex(value, appendPath(path, newPositionedMapValuePathComponent(key.Value, value)), re)...)
+		}
+		return matches
+	default:
+		logrus.Tracef("nothing to find: yaml node kind %d (path: %+v)", node.Kind, PathString(path))
+		return nil
+	}
+}
+
+// YamlNodeFindByPath is JsonFindByPath for yaml nodes, following the same
+// PathComponent semantics: for a MapKey it returns the key's node.  Returns nil
+// if the path doesn't exist.
+func YamlNodeFindByPath(node *yaml.Node, path []*PathComponent) *yaml.Node {
+	node = resolveYamlNode(node)
+	if node == nil {
+		return nil
+	}
+	if len(path) == 0 {
+		return node
+	}
+	component := path[0]
+	switch node.Kind {
+	case yaml.SequenceNode:
+		if component.ArrayIndex == nil || *component.ArrayIndex < 0 || *component.ArrayIndex >= len(node.Content) {
+			return nil
+		}
+		return YamlNodeFindByPath(node.Content[*component.ArrayIndex], path[1:])
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if component.MapKey != nil && key.Value == *component.MapKey {
+				if len(path) != 1 {
+					return nil
+				}
+				return key
+			} else if component.MapValue != nil && key.Value == *component.MapValue {
+				return YamlNodeFindByPath(value, path[1:])
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+// resolveYamlNode skips through document and alias nodes to the node holding
+// the actual content
+func resolveYamlNode(node *yaml.Node) *yaml.Node {
+	for node != nil && (node.Kind == yaml.DocumentNode || node.Kind == yaml.AliasNode) {
+		if node.Kind == yaml.DocumentNode {
+			if len(node.Content) == 0 {
+				return nil
+			}
+			node = node.Content[0]
+		} else {
+			node = node.Alias
+		}
+	}
+	return node
+}
+
+func nodePosition(node *yaml.Node) *SourcePosition {
+	return &SourcePosition{Line: node.Line, Column: node.Column}
+}
+
+func newPositionedArrayPathComponent(index int, node *yaml.Node) *PathComponent {
+	component := NewArrayPathComponent(index)
+	component.Position = nodePosition(node)
+	return component
+}
+
+func newPositionedMapKeyPathComponent(key string, node *yaml.Node) *PathComponent {
+	component := NewMapKeyPathComponent(key)
+	component.Position = nodePosition(node)
+	return component
+}
+
+func newPositionedMapValuePathComponent(key string, node *yaml.Node) *PathComponent {
+	component := NewMapValuePathComponent(key)
+	component.Position = nodePosition(node)
+	return component
+}
@@ -0,0 +1,136 @@
+package json_traversal
+
+import (
+	"github.com/pkg/errors"
+	"sort"
+	"strings"
+)
+
+type OutputOrder string
+
+const (
+	// OutputOrderDocument orders matches as they appear in the source file
+	OutputOrderDocument OutputOrder = "document"
+	// OutputOrderPath orders matches by document, then by path, with map keys sorted
+	OutputOrderPath OutputOrder = "path"
+	// OutputOrderValue orders matches by the matched value
+	OutputOrderValue OutputOrder = "value"
+)
+
+var AllOutputOrders = []OutputOrder{OutputOrderDocument, OutputOrderPath, OutputOrderValue}
+
+func ParseOutputOrder(s string) (OutputOrder, error) {
+	for _, order := range AllOutputOrders {
+		if string(order) == s {
+			return order, nil
+		}
+	}
+	return "", errors.Errorf("invalid output order '%s'; expected one of %+v", s, AllOutputOrders)
+}
+
+type DocumentMatch struct {
+	Document *Document
+	Match    *KeyMatch
+}
+
+// SortMatches orders matches; ties keep their original order.
+func SortMatches(matches []*DocumentMatch, order OutputOrder) []*DocumentMatch {
+	sorted := append([]*DocumentMatch{}, matches...)
+	switch order {
+	case OutputOrderDocument:
+		type position struct{ line, column int }
+		positions := map[*DocumentMatch]position{}
+		for _, match := range sorted {
+			if pos := match.Match.Position(); pos != nil {
+				positions[match] = position{line: pos.Line, column: pos.Column}
+			} else if node := YamlNodeFindByPath(match.Document.Node, match.Match.Path); node != nil {
+				positions[match] = position{line: node.Line, column: node.Column}
+			}
+		}
+		sort.SliceStable(sorted, func(i, j int) bool {
+			a, b := sorted[i], sorted[j]
+			if a.Document.Index != b.Document.Index {
+				return a.Document.Index < b.Document.Index
+			}
+			pa, pb := positions[a], positions[b]
+			if pa.line != pb.line {
+				return pa.line < pb.line
+			}
+			return pa.column < pb.column
+		})
+	case OutputOrderPath:
+		sort.SliceStable(sorted, func(i, j int) bool {
+			a, b := sorted[i], sorted[j]
+			if a.Document.Index != b.Document.Index {
+				return a.Document.Index < b.Document.Index
+			}
+			return ComparePaths(a.Match.Path, b.Match.Path) < 0
+		})
+	case OutputOrderValue:
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].Match.Value < sorted[j].Match.Value
+		})
+	default:
+		panic(errors.Errorf("invalid OutputOrder %s", order))
+	}
+	return sorted
+}
+
+// ComparePaths orders paths component by component: array indexes numerically,
+// map keys lexically, and a map's key before its value.  A path sorts before any
+// longer path it's a prefix of.
+func ComparePaths(a []*PathComponent, b []*PathComponent) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := comparePathComponents(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
+func comparePathComponents(a *PathComponent, b *PathComponent) int {
+	if a.ArrayIndex != nil && b.ArrayIndex != nil {
+		return *a.ArrayIndex - *b.ArrayIndex
+	}
+	aKey, aIsValue := pathComponentKey(a)
+	bKey, bIsValue := pathComponentKey(b)
+	if aKey != bKey {
+		return strings.Compare(aKey, bKey)
+	}
+	if aIsValue == bIsValue {
+		return 0
+	} else if aIsValue {
+		return 1
+	}
+	return -1
+}
+
+func pathComponentKey(p *PathComponent) (string, bool) {
+	if p.MapKey != nil {
+		return *p.MapKey, false
+	} else if p.MapValue != nil {
+		return *p.MapValue, true
+	}
+	return p.RawString(), true
+}
+
+type ValueCount struct {
+	Value string
+	Count int
+}
+
+// UniqueValues deduplicates matched values, keeping the order in which each value
+// first appears.
+func UniqueValues(matches []*DocumentMatch) []*ValueCount {
+	var counts []*ValueCount
+	indexes := map[string]int{}
+	for _, match := range matches {
+		if index, ok := indexes[match.Match.Value]; ok {
+			counts[index].Count++
+		} else {
+			indexes[match.Match.Value] = len(counts)
+			counts = append(counts, &ValueCount{Value: match.Match.Value, Count: 1})
+		}
+	}
+	return counts
+}
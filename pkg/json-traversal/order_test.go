@@ -0,0 +1,92 @@
+package json_traversal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const orderTestYaml = `zeta: b
+alpha: [c, a]
+---
+items:
+  - b
+`
+
+func findOrderTestMatches(t *testing.T) []*DocumentMatch {
+	docs, err := ParseDocuments([]byte(orderTestYaml))
+	if err != nil {
+		t.Fatalf("unable to parse documents: %+v", err)
+	}
+	var matches []*DocumentMatch
+	for _, doc := range docs {
+		for _, match := range YamlNodeFindByRegex(doc.Node, []*PathComponent{}, regexp.MustCompile(".")) {
+			matches = append(matches, &DocumentMatch{Document: doc, Match: match})
+		}
+	}
+	return matches
+}
+
+func describeMatches(matches []*DocumentMatch) []string {
+	var out []string
+	for _, match := range matches {
+		out = append(out, fmt.Sprintf("%d %s=%s", match.Document.Index, strings.Join(match.Match.PathString(), ""), match.Match.Value))
+	}
+	return out
+}
+
+func TestSortMatches(t *testing.T) {
+	for _, testCase := range []struct {
+		Order    OutputOrder
+		Expected []string
+	}{
+		{
+			Order:    OutputOrderDocument,
+			Expected: []string{`0 {"zeta"}=zeta`, `0 ["zeta"]=b`, `0 {"alpha"}=alpha`, `0 ["alpha"][0]=c`, `0 ["alpha"][1]=a`, `1 {"items"}=items`, `1 ["items"][0]=b`},
+		},
+		{
+			Order:    OutputOrderPath,
+			Expected: []string{`0 {"alpha"}=alpha`, `0 ["alpha"][0]=c`, `0 ["alpha"][1]=a`, `0 {"zeta"}=zeta`, `0 ["zeta"]=b`, `1 {"items"}=items`, `1 ["items"][0]=b`},
+		},
+		{
+			Order:    OutputOrderValue,
+			Expected: []string{`0 ["alpha"][1]=a`, `0 {"alpha"}=alpha`, `0 ["zeta"]=b`, `1 ["items"][0]=b`, `0 ["alpha"][0]=c`, `1 {"items"}=items`, `0 {"zeta"}=zeta`},
+		},
+	} {
+		found := describeMatches(SortMatches(findOrderTestMatches(t), testCase.Order))
+		if strings.Join(found, "\n") != strings.Join(testCase.Expected, "\n") {
+			t.Errorf("order %s: expected\n%s\nfound\n%s", testCase.Order, strings.Join(testCase.Expected, "\n"), strings.Join(found, "\n"))
+		}
+	}
+}
+
+func TestComparePaths(t *testing.T) {
+	for _, testCase := range []struct {
+		A        []*PathComponent
+		B        []*PathComponent
+		Expected int
+	}{
+		{A: []*PathComponent{NewArrayPathComponent(2)}, B: []*PathComponent{NewArrayPathComponent(10)}, Expected: -1},
+		{A: []*PathComponent{NewMapValuePathComponent("b")}, B: []*PathComponent{NewMapValuePathComponent("a")}, Expected: 1},
+		{A: []*PathComponent{NewMapKeyPathComponent("a")}, B: []*PathComponent{NewMapValuePathComponent("a")}, Expected: -1},
+		{A: []*PathComponent{NewMapValuePathComponent("a")}, B: []*PathComponent{NewMapValuePathComponent("a"), NewArrayPathComponent(0)}, Expected: -1},
+		{A: []*PathComponent{NewMapValuePathComponent("a"), NewArrayPathComponent(0)}, B: []*PathComponent{NewMapValuePathComponent("a"), NewArrayPathComponent(0)}, Expected: 0},
+	} {
+		found := ComparePaths(testCase.A, testCase.B)
+		if (found < 0 && testCase.Expected >= 0) || (found > 0 && testCase.Expected <= 0) || (found == 0 && testCase.Expected != 0) {
+			t.Errorf("comparing %v to %v: expected sign of %d, found %d", PathString(testCase.A), PathString(testCase.B), testCase.Expected, found)
+		}
+	}
+}
+
+func TestUniqueValues(t *testing.T) {
+	var found []string
+	for _, count := range UniqueValues(SortMatches(findOrderTestMatches(t), OutputOrderDocument)) {
+		found = append(found, fmt.Sprintf("%s:%d", count.Value, count.Count))
+	}
+	expected := []string{"zeta:1", "b:2", "alpha:1", "c:1", "a:1", "items:1"}
+	if strings.Join(found, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, found %v", expected, found)
+	}
+}
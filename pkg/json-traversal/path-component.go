@@ -10,6 +10,18 @@ type PathComponent struct {
 	ArrayIndex *int
 	MapKey     *string
 	MapValue   *string
+	// Position is where this component's value -- or key, for a MapKey -- starts
+	// in the source.  Only set by yaml node traversals.
+	Position *SourcePosition
+}
+
+type SourcePosition struct {
+	Line   int
+	Column int
+}
+
+func (s *SourcePosition) String() string {
+	return fmt.Sprintf("%d:%d", s.Line, s.Column)
 }
 
 func NewArrayPathComponent(index int) *PathComponent {
@@ -37,11 +49,14 @@ func (p *PathComponent) RawString() string {
 	panic(errors.Errorf("this shouldn't happen"))
 }
 
+// PathString renders a MapKey as `{"key"}`, a MapValue as `["key"]` and an
+// ArrayIndex as `[0]`; keys are quoted and escaped as JSON strings, so that
+// ParsePath can read them back.
 func (p *PathComponent) PathString() string {
 	if p.MapKey != nil {
-		return fmt.Sprintf(`{"%s"}`, *p.MapKey)
+		return fmt.Sprintf(`{%s}`, quoteKey(*p.MapKey))
 	} else if p.MapValue != nil {
-		return fmt.Sprintf(`["%s"]`, *p.MapValue)
+		return fmt.Sprintf(`[%s]`, quoteKey(*p.MapValue))
 	} else if p.ArrayIndex != nil {
 		return fmt.Sprintf(`[%d]`, *p.ArrayIndex)
 	} else {
@@ -50,6 +65,15 @@ func (p *PathComponent) PathString() string {
 	panic(errors.Errorf("this shouldn't happen"))
 }
 
+// PathPosition is the source position of whatever path points to, or nil if
+// the path doesn't carry positions
+func PathPosition(components []*PathComponent) *SourcePosition {
+	if len(components) == 0 {
+		return nil
+	}
+	return components[len(components)-1].Position
+}
+
 func PathString(components []*PathComponent) []string {
 	path := make([]string, len(components))
 	for i, component := range components {
@@ -57,3 +81,9 @@ func PathString(components []*PathComponent) []string {
 	}
 	return path
 }
+
+// appendPath returns a new path, leaving path untouched -- a plain append could
+// share a backing array between sibling paths.
+func appendPath(path []*PathComponent, component *PathComponent) []*PathComponent {
+	return append(utils.CopySlice(path), component)
+}
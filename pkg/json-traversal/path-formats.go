@@ -0,0 +1,372 @@
+package json_traversal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type PathFormat string
+
+const (
+	// PathFormatNative is the notation of PathString: `["spec"][0]{"key"}`
+	PathFormatNative PathFormat = "native"
+	// PathFormatJsonPointer is RFC 6901: `/spec/0`
+	PathFormatJsonPointer PathFormat = "json-pointer"
+	// PathFormatJsonPath is JSONPath: `$.spec[0]`
+	PathFormatJsonPath PathFormat = "jsonpath"
+	// PathFormatKubectlJsonPath is a template for `kubectl -o jsonpath=...`: `{.spec[0]}`
+	PathFormatKubectlJsonPath PathFormat = "kubectl-jsonpath"
+	// PathFormatJq is a jq path expression: `.spec[0]`
+	PathFormatJq PathFormat = "jq"
+)
+
+var AllPathFormats = []PathFormat{PathFormatNative, PathFormatJsonPointer, PathFormatJsonPath, PathFormatKubectlJsonPath, PathFormatJq}
+
+func ParsePathFormat(s string) (PathFormat, error) {
+	for _, format := range AllPathFormats {
+		if string(format) == s {
+			return format, nil
+		}
+	}
+	return "", errors.Errorf("invalid path format '%s'; expected one of %+v", s, AllPathFormats)
+}
+
+// FormatPath renders path in the given format.  Only the native format can
+// express MapKey components.
+func FormatPath(path []*PathComponent, format PathFormat) (string, error) {
+	switch format {
+	case PathFormatNative:
+		return strings.Join(PathString(path), ""), nil
+	case PathFormatJsonPointer:
+		return ToJsonPointer(path)
+	case PathFormatJsonPath:
+		return ToJsonPath(path)
+	case PathFormatKubectlJsonPath:
+		return ToKubectlJsonPath(path)
+	case PathFormatJq:
+		return ToJqPath(path)
+	default:
+		return "", errors.Errorf("invalid PathFormat %s", format)
+	}
+}
+
+// FormatPathOrNative is FormatPath, falling back to the native format for paths
+// which the requested format can't express
+func FormatPathOrNative(path []*PathComponent, format PathFormat) string {
+	formatted, err := FormatPath(path, format)
+	if err != nil {
+		logrus.Debugf("falling back to native path format: %+v", err)
+		return strings.Join(PathString(path), "")
+	}
+	return formatted
+}
+
+func quoteKey(key string) string {
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	utils.DoOrDie(errors.Wrapf(encoder.Encode(key), "unable to marshal key %s", key))
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
+
+// readJsonString reads a JSON string literal from the start of s, returning the
+// unquoted string and the rest of s
+func readJsonString(s string) (string, string, error) {
+	decoder := json.NewDecoder(strings.NewReader(s))
+	var str string
+	if err := decoder.Decode(&str); err != nil {
+		return "", "", errors.Wrapf(err, "unable to read json string")
+	}
+	return str, s[decoder.InputOffset():], nil
+}
+
+// ParsePath is the inverse of PathString: it reads a concatenation of
+// `{"key"}`, `["key"]` and `[0]` components.
+func ParsePath(s string) ([]*PathComponent, error) {
+	path := []*PathComponent{}
+	rest := strings.TrimSpace(s)
+	for len(rest) > 0 {
+		open := rest[0]
+		var close byte
+		switch open {
+		case '{':
+			close = '}'
+		case '[':
+			close = ']'
+		default:
+			return nil, errors.Errorf("unable to parse path '%s': expected '[' or '{' at '%s'", s, rest)
+		}
+		rest = rest[1:]
+		var component *PathComponent
+		if strings.HasPrefix(rest, `"`) {
+			key, remaining, err := readJsonString(rest)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to parse key in path '%s'", s)
+			}
+			rest = remaining
+			if open == '{' {
+				component = NewMapKeyPathComponent(key)
+			} else {
+				component = NewMapValuePathComponent(key)
+			}
+		} else if open == '[' {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, errors.Errorf("unable to parse path '%s': missing ']'", s)
+			}
+			index, err := strconv.Atoi(rest[:end])
+			if err != nil || index < 0 {
+				return nil, errors.Errorf("unable to parse path '%s': invalid array index '%s'", s, rest[:end])
+			}
+			rest = rest[end:]
+			component = NewArrayPathComponent(index)
+		} else {
+			return nil, errors.Errorf("unable to parse path '%s': expected a quoted key at '%s'", s, rest)
+		}
+		if len(rest) == 0 || rest[0] != close {
+			return nil, errors.Errorf("unable to parse path '%s': missing '%c'", s, close)
+		}
+		rest = rest[1:]
+		path = append(path, component)
+	}
+	return path, nil
+}
+
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+var jsonPointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// ToJsonPointer renders path as an RFC 6901 JSON Pointer
+func ToJsonPointer(path []*PathComponent) (string, error) {
+	builder := &strings.Builder{}
+	for _, component := range path {
+		if component.MapKey != nil {
+			return "", errors.Errorf("unable to express map key %s as a json pointer", component.PathString())
+		}
+		builder.WriteString("/")
+		builder.WriteString(jsonPointerEscaper.Replace(component.RawString()))
+	}
+	return builder.String(), nil
+}
+
+var jsonPointerArrayIndex = regexp.MustCompile(`^(0|[1-9][0-9]*)$`)
+
+// ParseJsonPointer reads an RFC 6901 JSON Pointer.  A pointer doesn't say whether
+// a token such as `0` is an array index or a map key; if obj is non-nil, each
+// token is resolved against it, otherwise tokens which look like array indexes
+// are taken to be array indexes.
+func ParseJsonPointer(pointer string, obj interface{}) ([]*PathComponent, error) {
+	path := []*PathComponent{}
+	if pointer == "" {
+		return path, nil
+	}
+	if pointer[0] != '/' {
+		return nil, errors.Errorf("invalid json pointer '%s': must be empty or start with '/'", pointer)
+	}
+	for _, token := range strings.Split(pointer[1:], "/") {
+		key := jsonPointerUnescaper.Replace(token)
+		isIndex := jsonPointerArrayIndex.MatchString(key)
+		if obj != nil {
+			_, isArray := obj.([]interface{})
+			isIndex = isIndex && isArray
+		}
+		var component *PathComponent
+		if isIndex {
+			index, err := strconv.Atoi(key)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid array index '%s' in json pointer '%s'", key, pointer)
+			}
+			component = NewArrayPathComponent(index)
+		} else {
+			component = NewMapValuePathComponent(key)
+		}
+		path = append(path, component)
+		if obj != nil {
+			obj = JsonFindByPath(obj, []*PathComponent{component})
+		}
+	}
+	return path, nil
+}
+
+var identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ToJsonPath renders path as a JSONPath expression rooted at `$`; for kubectl,
+// use ToKubectlJsonPath
+func ToJsonPath(path []*PathComponent) (string, error) {
+	builder := &strings.Builder{}
+	builder.WriteString("$")
+	for _, component := range path {
+		if component.MapKey != nil {
+			return "", errors.Errorf("unable to express map key %s in jsonpath", component.PathString())
+		} else if component.MapValue != nil {
+			key := *component.MapValue
+			if identifierRegex.MatchString(key) {
+				builder.WriteString("." + key)
+			} else {
+				builder.WriteString("['" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(key) + "']")
+			}
+		} else {
+			builder.WriteString(fmt.Sprintf("[%d]", *component.ArrayIndex))
+		}
+	}
+	return builder.String(), nil
+}
+
+// kubectlJsonPathEscaper escapes the characters which end a `.name` member in
+// kubectl's jsonpath
+var kubectlJsonPathEscaper = strings.NewReplacer(
+	".", `\.`, ",", `\,`, "[", `\[`, "]", `\]`, "$", `\$`, "@", `\@`, "{", `\{`, "}", `\}`, " ", `\ `, "\t", "\\\t")
+
+// ToKubectlJsonPath renders path as a template for `kubectl -o jsonpath=...`.
+// kubectl reads `['key']` as `.key`, so keys are always written as members,
+// with kubectl's backslash escapes: `.app\.kubernetes\.io/name`.  kubectl
+// drops every backslash, so keys containing one -- or a line break, or empty
+// keys -- can't be expressed.
+func ToKubectlJsonPath(path []*PathComponent) (string, error) {
+	builder := &strings.Builder{}
+	builder.WriteString("{")
+	for _, component := range path {
+		if component.MapKey != nil {
+			return "", errors.Errorf("unable to express map key %s in kubectl jsonpath", component.PathString())
+		} else if component.MapValue != nil {
+			key := *component.MapValue
+			if key == "" || strings.ContainsAny(key, "\\\r\n") {
+				return "", errors.Errorf("unable to express key %s in kubectl jsonpath", component.PathString())
+			}
+			builder.WriteString("." + kubectlJsonPathEscaper.Replace(key))
+		} else {
+			builder.WriteString(fmt.Sprintf("[%d]", *component.ArrayIndex))
+		}
+	}
+	builder.WriteString("}")
+	return builder.String(), nil
+}
+
+// ParseJsonPath reads a JSONPath expression made up of child selectors:
+// `.name`, `['name']`, `["name"]` and `[0]`.  The leading `$` is optional, and a
+// kubectl-style `{...}` wrapper is allowed, as are kubectl's escapes in names:
+// `.app\.kubernetes\.io/name`.  Wildcards, slices, filters and
+// recursive descent aren't supported, since they don't denote a single path.
+func ParseJsonPath(s string) ([]*PathComponent, error) {
+	rest := strings.TrimSpace(s)
+	if strings.HasPrefix(rest, "{") && strings.HasSuffix(rest, "}") {
+		rest = rest[1 : len(rest)-1]
+	}
+	rest = strings.TrimPrefix(rest, "$")
+	path := []*PathComponent{}
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			name, remaining, err := readJsonPathMember(rest[1:])
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to parse jsonpath '%s'", s)
+			}
+			if name == "" || name == "*" {
+				return nil, errors.Errorf("unable to parse jsonpath '%s': unsupported member '%s'", s, name)
+			}
+			path = append(path, NewMapValuePathComponent(name))
+			rest = remaining
+		case '[':
+			rest = rest[1:]
+			if len(rest) > 0 && (rest[0] == '\'' || rest[0] == '"') {
+				key, remaining, err := readQuotedJsonPathString(rest)
+				if err != nil {
+					return nil, errors.Wrapf(err, "unable to parse jsonpath '%s'", s)
+				}
+				path = append(path, NewMapValuePathComponent(key))
+				rest = remaining
+			} else {
+				end := strings.IndexByte(rest, ']')
+				if end < 0 {
+					return nil, errors.Errorf("unable to parse jsonpath '%s': missing ']'", s)
+				}
+				index, err := strconv.Atoi(rest[:end])
+				if err != nil || index < 0 {
+					return nil, errors.Errorf("unable to parse jsonpath '%s': unsupported selector '[%s]'", s, rest[:end])
+				}
+				path = append(path, NewArrayPathComponent(index))
+				rest = rest[end:]
+			}
+			if len(rest) == 0 || rest[0] != ']' {
+				return nil, errors.Errorf("unable to parse jsonpath '%s': missing ']'", s)
+			}
+			rest = rest[1:]
+		default:
+			return nil, errors.Errorf("unable to parse jsonpath '%s': unexpected '%s'", s, rest)
+		}
+	}
+	return path, nil
+}
+
+// readJsonPathMember reads a `.name` member up to the next unescaped `.` or
+// `[`, unescaping kubectl-style backslash escapes:
+// `.metadata.labels.app\.kubernetes\.io/name`
+func readJsonPathMember(s string) (string, string, error) {
+	builder := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '.', '[':
+			return builder.String(), s[i:], nil
+		case '\\':
+			if i+1 >= len(s) {
+				return "", "", errors.Errorf("unterminated escape in %s", s)
+			}
+			i++
+			builder.WriteByte(s[i])
+		default:
+			builder.WriteByte(s[i])
+		}
+	}
+	return builder.String(), "", nil
+}
+
+// readQuotedJsonPathString reads a single- or double-quoted string with
+// backslash escapes, returning the unquoted string and the rest of s
+func readQuotedJsonPathString(s string) (string, string, error) {
+	quote := s[0]
+	builder := &strings.Builder{}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case quote:
+			return builder.String(), s[i+1:], nil
+		case '\\':
+			if i+1 >= len(s) {
+				return "", "", errors.Errorf("unterminated escape in %s", s)
+			}
+			i++
+			builder.WriteByte(s[i])
+		default:
+			builder.WriteByte(s[i])
+		}
+	}
+	return "", "", errors.Errorf("unterminated string %s", s)
+}
+
+// ToJqPath renders path as a jq path expression
+func ToJqPath(path []*PathComponent) (string, error) {
+	if len(path) == 0 {
+		return ".", nil
+	}
+	builder := &strings.Builder{}
+	for _, component := range path {
+		if component.MapKey != nil {
+			return "", errors.Errorf("unable to express map key %s in jq", component.PathString())
+		} else if component.MapValue != nil {
+			key := *component.MapValue
+			if identifierRegex.MatchString(key) {
+				builder.WriteString("." + key)
+			} else {
+				builder.WriteString("." + quoteKey(key))
+			}
+		} else {
+			builder.WriteString(fmt.Sprintf("[%d]", *component.ArrayIndex))
+		}
+	}
+	return builder.String(), nil
+}
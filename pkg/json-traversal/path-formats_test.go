@@ -0,0 +1,120 @@
+package json_traversal
+
+import (
+	"bytes"
+	"k8s.io/client-go/util/jsonpath"
+	"testing"
+)
+
+var roundTripKeys = []string{"plain", "app.kubernetes.io/name", "a/b", "til~de", "it's", `back\slash`, "0", "12", "with space", ""}
+
+func pathsEqual(a []*PathComponent, b []*PathComponent) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].PathString() != b[i].PathString() {
+			return false
+		}
+	}
+	return true
+}
+
+func roundTripPath(key string) []*PathComponent {
+	return []*PathComponent{NewMapValuePathComponent("metadata"), NewMapValuePathComponent(key), NewArrayPathComponent(3)}
+}
+
+func TestPathRoundTrip(t *testing.T) {
+	for _, key := range roundTripKeys {
+		path := append(roundTripPath(key), NewMapKeyPathComponent(key))
+		parsed, err := ParsePath(PathString(path)[0] + PathString(path)[1] + PathString(path)[2] + PathString(path)[3])
+		if err != nil {
+			t.Errorf("unable to parse path for key %q: %+v", key, err)
+		} else if !pathsEqual(path, parsed) {
+			t.Errorf("expected %v, found %v", PathString(path), PathString(parsed))
+		}
+	}
+}
+
+func TestJsonPointerRoundTrip(t *testing.T) {
+	for _, key := range roundTripKeys {
+		path := roundTripPath(key)
+		pointer, err := ToJsonPointer(path)
+		if err != nil {
+			t.Fatalf("unable to render json pointer for key %q: %+v", key, err)
+		}
+		// numeric keys are only map keys when resolved against an object
+		obj := map[string]interface{}{"metadata": map[string]interface{}{key: []interface{}{0, 1, 2, 3}}}
+		parsed, err := ParseJsonPointer(pointer, obj)
+		if err != nil {
+			t.Errorf("unable to parse json pointer %s: %+v", pointer, err)
+		} else if !pathsEqual(path, parsed) {
+			t.Errorf("expected %v, found %v from %s", PathString(path), PathString(parsed), pointer)
+		}
+	}
+	parsed, err := ParseJsonPointer("/metadata/0", nil)
+	if err != nil || parsed[1].ArrayIndex == nil {
+		t.Errorf("expected /metadata/0 to end with an array index, found %v, %+v", PathString(parsed), err)
+	}
+}
+
+func TestJsonPathRoundTrip(t *testing.T) {
+	for _, key := range roundTripKeys {
+		path := roundTripPath(key)
+		for _, render := range []func([]*PathComponent) (string, error){ToJsonPath, ToKubectlJsonPath} {
+			s, err := render(path)
+			if err != nil {
+				// kubectl can't express empty keys, or keys with backslashes
+				continue
+			}
+			parsed, err := ParseJsonPath(s)
+			if err != nil {
+				t.Errorf("unable to parse jsonpath %s: %+v", s, err)
+			} else if !pathsEqual(path, parsed) {
+				t.Errorf("expected %v, found %v from %s", PathString(path), PathString(parsed), s)
+			}
+		}
+	}
+}
+
+func TestParseKubectlEscapedJsonPath(t *testing.T) {
+	parsed, err := ParseJsonPath(`{.metadata.labels.app\.kubernetes\.io/name}`)
+	if err != nil {
+		t.Fatalf("unable to parse jsonpath: %+v", err)
+	}
+	expected := []*PathComponent{NewMapValuePathComponent("metadata"), NewMapValuePathComponent("labels"), NewMapValuePathComponent("app.kubernetes.io/name")}
+	if !pathsEqual(expected, parsed) {
+		t.Fatalf("expected %v, found %v", PathString(expected), PathString(parsed))
+	}
+	if _, err := ParseJsonPath(`.metadata\`); err == nil {
+		t.Fatalf("expected error for unterminated escape")
+	}
+}
+
+func TestKubectlJsonPathFindsValueWithKubectl(t *testing.T) {
+	for _, key := range roundTripKeys {
+		s, err := ToKubectlJsonPath(roundTripPath(key))
+		if key == "" || key == `back\slash` {
+			if err == nil {
+				t.Errorf("expected error for key %q, found %s", key, s)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unable to render kubectl jsonpath for key %q: %+v", key, err)
+			continue
+		}
+		parser := jsonpath.New(key)
+		if err := parser.Parse(s); err != nil {
+			t.Errorf("kubectl unable to parse %s: %+v", s, err)
+			continue
+		}
+		obj := map[string]interface{}{"metadata": map[string]interface{}{key: []interface{}{"a", "b", "c", "found"}, "other": "x"}}
+		out := &bytes.Buffer{}
+		if err := parser.Execute(out, obj); err != nil {
+			t.Errorf("kubectl unable to execute %s: %+v", s, err)
+		} else if out.String() != "found" {
+			t.Errorf("expected %s to find 'found', found '%s'", s, out.String())
+		}
+	}
+}
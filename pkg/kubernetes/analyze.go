@@ -6,6 +6,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 )
 
 func getResourceName(o map[string]interface{}) string {
@@ -14,17 +15,26 @@ func getResourceName(o map[string]interface{}) string {
 
 func analyzeVolumeMounts(isInitContainer bool, configMaps map[string]string, secrets map[string]string, containerSpec v1.Container) *Container {
 	container := &Container{
-		IsInit:     isInitContainer,
-		Name:       containerSpec.Name,
-		Image:      containerSpec.Image,
-		ConfigMaps: set.FromSlice[string](nil),
-		Secrets:    set.FromSlice[string](nil),
+		IsInit:            isInitContainer,
+		Name:              containerSpec.Name,
+		Image:             containerSpec.Image,
+		ConfigMaps:        set.FromSlice[string](nil),
+		Secrets:           set.FromSlice[string](nil),
+		MountedConfigMaps: set.FromSlice[string](nil),
+		MountedSecrets:    set.FromSlice[string](nil),
+		Requests:          containerSpec.Resources.Requests,
+		Limits:            containerSpec.Resources.Limits,
+		Env:               analyzeEnv(containerSpec),
+		Command:           containerSpec.Command,
+		Args:              containerSpec.Args,
 	}
 	for _, mount := range containerSpec.VolumeMounts {
 		if configMapName, ok := configMaps[mount.Name]; ok {
 			container.ConfigMaps.Add(configMapName)
+			container.MountedConfigMaps.Add(configMapName)
 		} else if secretName, ok := secrets[mount.Name]; ok {
 			container.Secrets.Add(secretName)
+			container.MountedSecrets.Add(secretName)
 		}
 	}
 
@@ -63,9 +73,6 @@ func AnalyzePodSpec(spec v1.PodSpec) *PodSpec {
 	}
 	for _, contSpec := range spec.Containers {
 		containers = append(containers, analyzeVolumeMounts(false, configs, secrets, contSpec))
-		// TODO:
-		//contSpec.Env
-		//contSpec.EnvFrom
 	}
 	for _, contSpec := range spec.InitContainers {
 		containers = append(containers, analyzeVolumeMounts(true, configs, secrets, contSpec))
@@ -75,24 +82,68 @@ func AnalyzePodSpec(spec v1.PodSpec) *PodSpec {
 		ips[i] = ref.Name
 	}
 	return &PodSpec{
+		Replicas:         1,
 		Containers:       containers,
 		ServiceAccount:   spec.ServiceAccountName,
 		ImagePullSecrets: ips,
 	}
 }
 
+// podCount is the number of pods run at once; kubernetes defaults unset
+// replicas and parallelism to 1
+func podCount(count *int32) int32 {
+	if count == nil {
+		return 1
+	}
+	return *count
+}
+
 func AnalyzeJob(job *batchv1.Job) *PodSpec {
-	return AnalyzePodSpec(job.Spec.Template.Spec)
+	spec := AnalyzePodSpec(job.Spec.Template.Spec)
+	spec.Namespace = job.Namespace
+	spec.Replicas = podCount(job.Spec.Parallelism)
+	return spec
 }
 
 func AnalyzeCronJob(job *batchv1.CronJob) *PodSpec {
-	return AnalyzePodSpec(job.Spec.JobTemplate.Spec.Template.Spec)
+	spec := AnalyzePodSpec(job.Spec.JobTemplate.Spec.Template.Spec)
+	spec.Namespace = job.Namespace
+	spec.Replicas = podCount(job.Spec.JobTemplate.Spec.Parallelism)
+	return spec
 }
 
 func AnalyzeStatefulSet(sset *appsv1.StatefulSet) *PodSpec {
-	return AnalyzePodSpec(sset.Spec.Template.Spec)
+	spec := AnalyzePodSpec(sset.Spec.Template.Spec)
+	spec.Namespace = sset.Namespace
+	spec.Replicas = podCount(sset.Spec.Replicas)
+	return spec
 }
 
 func AnalyzeDeployment(dep *appsv1.Deployment) *PodSpec {
-	return AnalyzePodSpec(dep.Spec.Template.Spec)
+	spec := AnalyzePodSpec(dep.Spec.Template.Spec)
+	spec.Namespace = dep.Namespace
+	spec.Replicas = podCount(dep.Spec.Replicas)
+	return spec
+}
+
+// RoleBinding is a RoleBinding or ClusterRoleBinding, granting the permissions
+// of a Role or ClusterRole to service accounts
+type RoleBinding struct {
+	Kind            string
+	Name            string
+	RoleKind        string
+	RoleName        string
+	ServiceAccounts []string
+}
+
+// AnalyzeRoleBinding finds the service accounts bound to a role; users and
+// groups are ignored, as workloads can't run as them
+func AnalyzeRoleBinding(kind string, name string, roleRef rbacv1.RoleRef, subjects []rbacv1.Subject) *RoleBinding {
+	binding := &RoleBinding{Kind: kind, Name: name, RoleKind: roleRef.Kind, RoleName: roleRef.Name}
+	for _, subject := range subjects {
+		if subject.Kind == rbacv1.ServiceAccountKind {
+			binding.ServiceAccounts = append(binding.ServiceAccounts, subject.Name)
+		}
+	}
+	return binding
 }
@@ -0,0 +1,160 @@
+package kubernetes
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ClusterArgs select a cluster and namespace the way kubectl does
+type ClusterArgs struct {
+	Kubeconfig string
+	Context    string
+	Namespace  string
+}
+
+// restConfig reads kubeconfig -- from Kubeconfig, or else $KUBECONFIG or
+// ~/.kube/config -- and returns the namespace to read: Namespace, or else the
+// context's namespace
+func (a *ClusterArgs) restConfig() (*rest.Config, string, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = a.Kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: a.Context}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "unable to load kubeconfig")
+	}
+
+	namespace := a.Namespace
+	if namespace == "" {
+		namespace, _, err = clientConfig.Namespace()
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "unable to get namespace from kubeconfig")
+		}
+	}
+	return restConfig, namespace, nil
+}
+
+// NewClientset returns a client along with the namespace to read
+func (a *ClusterArgs) NewClientset() (clientset.Interface, string, error) {
+	restConfig, namespace, err := a.restConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	client, err := clientset.NewForConfig(restConfig)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "unable to create kubernetes client")
+	}
+	return client, namespace, nil
+}
+
+// ReadClusterObjects lists the kinds NewModelFromYaml supports in namespace as
+// unstructured objects.  Jobs created by CronJobs are left out, as they're
+// analyzed as part of their CronJob, and secret values are removed, as only
+// their keys are used.  Only one namespace is read, since the model identifies
+// objects by name.
+func ReadClusterObjects(ctx context.Context, client clientset.Interface, namespace string) ([]map[string]interface{}, error) {
+	if namespace == metav1.NamespaceAll {
+		return nil, errors.Errorf("unable to read cluster objects: a namespace is required")
+	}
+	options := metav1.ListOptions{}
+	var objs []map[string]interface{}
+	add := func(gvk schema.GroupVersionKind, obj runtime.Object) error {
+		unstructured, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return errors.Wrapf(err, "unable to convert %s to unstructured", gvk.Kind)
+		}
+		// list items don't include apiVersion and kind
+		apiVersion, kind := gvk.ToAPIVersionAndKind()
+		unstructured["apiVersion"], unstructured["kind"] = apiVersion, kind
+		objs = append(objs, unstructured)
+		return nil
+	}
+
+	deployments, err := client.AppsV1().Deployments(namespace).List(ctx, options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list deployments")
+	}
+	for i := range deployments.Items {
+		if err := add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, &deployments.Items[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	statefulSets, err := client.AppsV1().StatefulSets(namespace).List(ctx, options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list statefulsets")
+	}
+	for i := range statefulSets.Items {
+		if err := add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}, &statefulSets.Items[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	jobs, err := client.BatchV1().Jobs(namespace).List(ctx, options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list jobs")
+	}
+	for i := range jobs.Items {
+		if isOwnedBy(jobs.Items[i].OwnerReferences, "CronJob") {
+			logrus.Debugf("skipping job %s/%s created by a cronjob", jobs.Items[i].Namespace, jobs.Items[i].Name)
+			continue
+		}
+		if err := add(schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}, &jobs.Items[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	cronJobs, err := client.BatchV1().CronJobs(namespace).List(ctx, options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list cronjobs")
+	}
+	for i := range cronJobs.Items {
+		if err := add(schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"}, &cronJobs.Items[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	secrets, err := client.CoreV1().Secrets(namespace).List(ctx, options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list secrets")
+	}
+	for i := range secrets.Items {
+		secret := secrets.Items[i].DeepCopy()
+		for key := range secret.Data {
+			secret.Data[key] = nil
+		}
+		if err := add(schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, secret); err != nil {
+			return nil, err
+		}
+	}
+
+	configMaps, err := client.CoreV1().ConfigMaps(namespace).List(ctx, options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list configmaps")
+	}
+	for i := range configMaps.Items {
+		if err := add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, &configMaps.Items[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return objs, nil
+}
+
+func isOwnedBy(owners []metav1.OwnerReference, kind string) bool {
+	for _, owner := range owners {
+		if owner.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package kubernetes
+
+import (
+	"context"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"sort"
+	"testing"
+)
+
+func objectMeta(namespace string, name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Namespace: namespace, Name: name}
+}
+
+func podTemplate(secret string) v1.PodTemplateSpec {
+	return v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{
+		Name:    "app",
+		Image:   "nginx:1.25",
+		EnvFrom: []v1.EnvFromSource{{SecretRef: &v1.SecretEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: secret}}}},
+	}}}}
+}
+
+func newFakeClientset() *fake.Clientset {
+	cronJobJob := &batchv1.Job{ObjectMeta: objectMeta("a", "nightly-123"), Spec: batchv1.JobSpec{Template: podTemplate("db")}}
+	cronJobJob.OwnerReferences = []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "CronJob", Name: "nightly"}}
+	return fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: objectMeta("a", "web"), Spec: appsv1.DeploymentSpec{Template: podTemplate("db")}},
+		&appsv1.Deployment{ObjectMeta: objectMeta("b", "web"), Spec: appsv1.DeploymentSpec{Template: podTemplate("db")}},
+		&appsv1.StatefulSet{ObjectMeta: objectMeta("a", "cache"), Spec: appsv1.StatefulSetSpec{Template: podTemplate("db")}},
+		&batchv1.Job{ObjectMeta: objectMeta("a", "migrate"), Spec: batchv1.JobSpec{Template: podTemplate("db")}},
+		cronJobJob,
+		&batchv1.CronJob{ObjectMeta: objectMeta("a", "nightly"), Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: podTemplate("db")}}}},
+		&v1.Secret{ObjectMeta: objectMeta("a", "db"), Data: map[string][]byte{"password": []byte("hunter2")}},
+		&v1.ConfigMap{ObjectMeta: objectMeta("a", "settings"), Data: map[string]string{"mode": "fast"}},
+		&v1.Service{ObjectMeta: objectMeta("a", "web")},
+		&v1.Pod{ObjectMeta: objectMeta("a", "web-1")},
+	)
+}
+
+func TestReadClusterObjectsSelectsKinds(t *testing.T) {
+	objs, err := ReadClusterObjects(context.TODO(), newFakeClientset(), "a")
+	if err != nil {
+		t.Fatalf("unable to read objects: %+v", err)
+	}
+	var found []string
+	for _, obj := range objs {
+		found = append(found, obj["apiVersion"].(string)+" "+obj["kind"].(string)+" "+objectName(obj))
+	}
+	sort.Strings(found)
+	expected := []string{
+		"apps/v1 Deployment web",
+		"apps/v1 StatefulSet cache",
+		"batch/v1 CronJob nightly",
+		"batch/v1 Job migrate",
+		"v1 ConfigMap settings",
+		"v1 Secret db",
+	}
+	if len(found) != len(expected) {
+		t.Fatalf("expected %v, found %v", expected, found)
+	}
+	for i := range expected {
+		if found[i] != expected[i] {
+			t.Fatalf("expected %v, found %v", expected, found)
+		}
+	}
+
+	model, err := ParseModel(objs)
+	if err != nil {
+		t.Fatalf("unable to parse model: %+v", err)
+	}
+	if len(model.Pods["Deployment"]) != 1 || len(model.Pods["Job"]) != 1 {
+		t.Fatalf("expected 1 deployment and 1 job, found %+v", model.Pods)
+	}
+	if keys := model.SecretKeys["db"]; len(keys) != 1 || keys[0] != "password" {
+		t.Fatalf("expected secret db to have key password, found %v", keys)
+	}
+}
+
+func TestReadClusterObjectsStripsSecretData(t *testing.T) {
+	objs, err := ReadClusterObjects(context.TODO(), newFakeClientset(), "a")
+	if err != nil {
+		t.Fatalf("unable to read objects: %+v", err)
+	}
+	for _, obj := range objs {
+		if obj["kind"] != "Secret" {
+			continue
+		}
+		data, ok := obj["data"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("expected secret data keys to be kept, found %+v", obj["data"])
+		}
+		for key, value := range data {
+			if value != nil {
+				t.Errorf("expected value of %s to be removed, found %v", key, value)
+			}
+		}
+	}
+}
+
+func TestReadClusterObjectsRequiresNamespace(t *testing.T) {
+	if _, err := ReadClusterObjects(context.TODO(), newFakeClientset(), metav1.NamespaceAll); err == nil {
+		t.Fatalf("expected error reading all namespaces")
+	}
+}
@@ -0,0 +1,364 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/mattfenwick/collections/pkg/yaml"
+	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+	"os"
+	k8syaml "sigs.k8s.io/yaml"
+	"strings"
+)
+
+// DriftIgnoreRule ignores differences at or below Path -- a JSON pointer, in
+// which `*` matches any one key or index: `/spec/template/spec/containers/*/image`
+// -- for objects of Kind and Name, if set
+type DriftIgnoreRule struct {
+	Kind string `json:"kind,omitempty"`
+	Name string `json:"name,omitempty"`
+	Path string `json:"path"`
+}
+
+func (r *DriftIgnoreRule) Matches(kind string, name string, path []string) bool {
+	if (r.Kind != "" && r.Kind != kind) || (r.Name != "" && r.Name != name) {
+		return false
+	}
+	pattern := splitJsonPointer(r.Path)
+	if len(pattern) > len(path) {
+		return false
+	}
+	for i, token := range pattern {
+		if token != "*" && token != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type DriftConfig struct {
+	IgnorePaths []*DriftIgnoreRule `json:"ignorePaths"`
+}
+
+// ReadDriftConfig reads a yaml or json config, failing on unknown keys so that
+// misspelled rules aren't silently dropped
+func ReadDriftConfig(path string) (*DriftConfig, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read drift config from %s", path)
+	}
+	var config DriftConfig
+	if err := k8syaml.UnmarshalStrict(bytes, &config); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse drift config from %s", path)
+	}
+	for i, rule := range config.IgnorePaths {
+		if rule == nil || rule.Path == "" {
+			return nil, errors.Errorf("invalid drift config %s: ignorePaths[%d] has no path", path, i)
+		}
+	}
+	return &config, nil
+}
+
+var (
+	jsonPointerTokenEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	jsonPointerTokenUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+func splitJsonPointer(pointer string) []string {
+	if pointer == "" || pointer == "/" {
+		return nil
+	}
+	return slice.Map(jsonPointerTokenUnescaper.Replace, strings.Split(strings.TrimPrefix(pointer, "/"), "/"))
+}
+
+func joinJsonPointer(path []string) string {
+	return "/" + strings.Join(slice.Map(jsonPointerTokenEscaper.Replace, path), "/")
+}
+
+// serverPopulatedPaths are removed from live objects before comparing
+var serverPopulatedPaths = [][]string{
+	{"status"},
+	{"metadata", "managedFields"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "uid"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "selfLink"},
+	{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"},
+	{"metadata", "annotations", "deployment.kubernetes.io/revision"},
+}
+
+func stripServerPopulatedFields(obj map[string]interface{}) {
+	for _, path := range serverPopulatedPaths {
+		unstructured.RemoveNestedField(obj, path...)
+	}
+	if annotations, ok, _ := unstructured.NestedMap(obj, "metadata", "annotations"); ok && len(annotations) == 0 {
+		unstructured.RemoveNestedField(obj, "metadata", "annotations")
+	}
+}
+
+type DriftStatus string
+
+const (
+	DriftStatusInSync  DriftStatus = "in sync"
+	DriftStatusDrifted DriftStatus = "drifted"
+	DriftStatusMissing DriftStatus = "missing"
+	// DriftStatusUnknownKind is for objects whose kind the cluster doesn't serve
+	DriftStatusUnknownKind DriftStatus = "unknown kind"
+)
+
+// DriftDiff is a difference between a rendered object and its live version.
+// Added fields are only in the live object; removed fields are only in the
+// rendered object.
+type DriftDiff struct {
+	Path     string         `json:"path"`
+	Type     utils.DiffType `json:"type"`
+	Rendered interface{}    `json:"rendered,omitempty"`
+	Live     interface{}    `json:"live,omitempty"`
+}
+
+type ObjectDrift struct {
+	ApiVersion string       `json:"apiVersion"`
+	Kind       string       `json:"kind"`
+	Namespace  string       `json:"namespace,omitempty"`
+	Name       string       `json:"name"`
+	Status     DriftStatus  `json:"status"`
+	Diffs      []*DriftDiff `json:"diffs,omitempty"`
+}
+
+// DriftOptions control which differences are reported
+type DriftOptions struct {
+	IgnorePaths []*DriftIgnoreRule
+	// IncludeAdditions reports fields only in the live object.  These are
+	// usually defaults filled in by the api server, so are ignored by default.
+	IncludeAdditions bool
+}
+
+// CompareWithLive compares a rendered object to its live version, after
+// removing server-populated fields from the live object
+func CompareWithLive(rendered map[string]interface{}, live map[string]interface{}, options *DriftOptions) ([]*DriftDiff, error) {
+	// round trip both through json so that numbers have the same type
+	renderedJson, err := remarshalJson(rendered)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to remarshal rendered object")
+	}
+	liveCopy := (&unstructured.Unstructured{Object: live}).DeepCopy().Object
+	stripServerPopulatedFields(liveCopy)
+	liveJson, err := remarshalJson(liveCopy)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to remarshal live object")
+	}
+
+	kind, name := fmt.Sprintf("%v", rendered["kind"]), getResourceName(rendered)
+	var diffs []*DriftDiff
+	for _, diff := range utils.DiffJsonValues(renderedJson, liveJson).Elements {
+		if diff.Type == utils.DiffTypeAdd && !options.IncludeAdditions {
+			continue
+		}
+		// such as a quantity rendered as the number 1, and served as "1"
+		if diff.Type == utils.DiffTypeChange && fmt.Sprintf("%v", diff.Old) == fmt.Sprintf("%v", diff.New) {
+			continue
+		}
+		if diff.Type == utils.DiffTypeChange && isQuantityPath(diff.Path) && quantitiesEqual(diff.Old, diff.New) {
+			continue
+		}
+		if slice.Any(func(rule *DriftIgnoreRule) bool { return rule.Matches(kind, name, diff.Path) }, options.IgnorePaths) {
+			continue
+		}
+		diffs = append(diffs, &DriftDiff{Path: joinJsonPointer(diff.Path), Type: diff.Type, Rendered: diff.Old, Live: diff.New})
+	}
+	return diffs, nil
+}
+
+// isQuantityPath is true for resource requests and limits, and resource quotas,
+// which the api server normalizes: `0.5` is served as `500m`
+func isQuantityPath(path []string) bool {
+	return slice.Any(func(token string) bool { return token == "resources" || token == "limits" || token == "hard" }, path)
+}
+
+func quantitiesEqual(a interface{}, b interface{}) bool {
+	aQuantity, err := resource.ParseQuantity(fmt.Sprintf("%v", a))
+	if err != nil {
+		return false
+	}
+	bQuantity, err := resource.ParseQuantity(fmt.Sprintf("%v", b))
+	if err != nil {
+		return false
+	}
+	return aQuantity.Cmp(bQuantity) == 0
+}
+
+func remarshalJson(obj interface{}) (interface{}, error) {
+	out, err := json.Remarshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	return *(out.(*interface{})), nil
+}
+
+// DriftClient fetches live objects; Mapper finds the resource for each kind
+type DriftClient struct {
+	Dynamic dynamic.Interface
+	Mapper  meta.RESTMapper
+	// Namespace is used for namespaced objects which don't set one
+	Namespace string
+}
+
+// NewDriftClient connects to the cluster, using discovery to map kinds to
+// resources
+func (a *ClusterArgs) NewDriftClient() (*DriftClient, error) {
+	restConfig, namespace, err := a.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create dynamic client")
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create discovery client")
+	}
+	return &DriftClient{
+		Dynamic:   dynamicClient,
+		Mapper:    restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient)),
+		Namespace: namespace,
+	}, nil
+}
+
+// Drift fetches the live version of each rendered object and compares them
+func (c *DriftClient) Drift(ctx context.Context, objs []map[string]interface{}, options *DriftOptions) ([]*ObjectDrift, error) {
+	var drifts []*ObjectDrift
+	for _, obj := range objs {
+		if obj == nil {
+			continue
+		}
+		rendered := &unstructured.Unstructured{Object: obj}
+		gvk := rendered.GroupVersionKind()
+		drift := &ObjectDrift{ApiVersion: rendered.GetAPIVersion(), Kind: rendered.GetKind(), Name: rendered.GetName()}
+		mapping, err := c.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if meta.IsNoMatchError(err) {
+			logrus.Warnf("unable to find resource for %s: %s", gvk, err.Error())
+			drift.Namespace = rendered.GetNamespace()
+			drift.Status = DriftStatusUnknownKind
+			drifts = append(drifts, drift)
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "unable to find resource for %s", gvk)
+		}
+
+		var resource dynamic.ResourceInterface = c.Dynamic.Resource(mapping.Resource)
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			drift.Namespace = rendered.GetNamespace()
+			if drift.Namespace == "" {
+				drift.Namespace = c.Namespace
+			}
+			resource = c.Dynamic.Resource(mapping.Resource).Namespace(drift.Namespace)
+		}
+
+		live, err := resource.Get(ctx, drift.Name, metav1.GetOptions{})
+		switch {
+		case kerrors.IsNotFound(err):
+			drift.Status = DriftStatusMissing
+		case err != nil:
+			return nil, errors.Wrapf(err, "unable to get %s %s/%s", gvk.Kind, drift.Namespace, drift.Name)
+		default:
+			drift.Diffs, err = CompareWithLive(obj, live.Object, options)
+			if err != nil {
+				return nil, err
+			}
+			drift.Status = DriftStatusInSync
+			if len(drift.Diffs) > 0 {
+				drift.Status = DriftStatusDrifted
+			}
+		}
+		drifts = append(drifts, drift)
+	}
+	return drifts, nil
+}
+
+func formatDriftValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	out, err := json.MarshalWithOptions(value, &json.MarshalOptions{EscapeHTML: false, Indent: false, Sort: true})
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(out)
+}
+
+func DriftTable(drifts []*ObjectDrift) string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetAutoMergeCellsByColumnIndex([]int{0, 1, 2, 3})
+	table.SetHeader([]string{"Kind", "Namespace", "Name", "Status", "Path", "Diff", "Rendered", "Live"})
+	for _, drift := range drifts {
+		if len(drift.Diffs) == 0 {
+			table.Append([]string{drift.Kind, drift.Namespace, drift.Name, string(drift.Status), "", "", "", ""})
+		}
+		for _, diff := range drift.Diffs {
+			table.Append([]string{drift.Kind, drift.Namespace, drift.Name, string(drift.Status), diff.Path, diff.Type.Short(), formatDriftValue(diff.Rendered), formatDriftValue(diff.Live)})
+		}
+	}
+	table.Render()
+	return tableString.String()
+}
+
+type DriftArgs struct {
+	ChartPath        string
+	Cluster          *ClusterArgs
+	ConfigPath       string
+	IncludeAdditions bool
+	Output           string
+	Check            bool
+}
+
+func RunDrift(args *DriftArgs) {
+	objs, err := yaml.ParseManyFromFile[map[string]interface{}](args.ChartPath)
+	utils.DoOrDie(err)
+
+	options := &DriftOptions{IncludeAdditions: args.IncludeAdditions}
+	if args.ConfigPath != "" {
+		config, err := ReadDriftConfig(args.ConfigPath)
+		utils.DoOrDie(err)
+		options.IgnorePaths = config.IgnorePaths
+	}
+
+	client, err := args.Cluster.NewDriftClient()
+	utils.DoOrDie(err)
+	drifts, err := client.Drift(context.TODO(), objs, options)
+	utils.DoOrDie(err)
+
+	switch args.Output {
+	case "table":
+		fmt.Printf("%s\n", DriftTable(drifts))
+	case "json":
+		json.PrintOptions(drifts, &json.MarshalOptions{EscapeHTML: false, Indent: true, Sort: false})
+	default:
+		utils.DoOrDie(errors.Errorf("invalid output format '%s'", args.Output))
+	}
+
+	outOfSync := slice.Filter(func(d *ObjectDrift) bool { return d.Status != DriftStatusInSync }, drifts)
+	if args.Check && len(outOfSync) > 0 {
+		utils.DoOrDie(errors.Errorf("found %d of %d objects drifted, missing or of unknown kind", len(outOfSync), len(drifts)))
+	}
+}
@@ -0,0 +1,158 @@
+package kubernetes
+
+import (
+	"context"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var deploymentGvk = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+
+func newDeployment(replicas int64, image string) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "web", "image": image},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newFakeDriftClient(live ...map[string]interface{}) *DriftClient {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(deploymentGvk, meta.RESTScopeNamespace)
+	var objs []runtime.Object
+	for _, obj := range live {
+		objs = append(objs, &unstructured.Unstructured{Object: obj})
+	}
+	return &DriftClient{
+		Dynamic:   dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objs...),
+		Mapper:    mapper,
+		Namespace: "default",
+	}
+}
+
+func TestDriftConfigSuppressesMatchingDiffs(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{"drift.yaml": `
+ignorePaths:
+- path: /spec/replicas
+- kind: Deployment
+  name: other
+  path: /spec/template/spec/containers/*/image
+`})
+	config, err := ReadDriftConfig(filepath.Join(dir, "drift.yaml"))
+	if err != nil {
+		t.Fatalf("unable to read config: %+v", err)
+	}
+	if len(config.IgnorePaths) != 2 {
+		t.Fatalf("expected 2 rules, found %d", len(config.IgnorePaths))
+	}
+
+	client := newFakeDriftClient(newDeployment(5, "nginx:1.25"))
+	drifts, err := client.Drift(context.TODO(), []map[string]interface{}{newDeployment(3, "nginx:1.24")}, &DriftOptions{IgnorePaths: config.IgnorePaths})
+	if err != nil {
+		t.Fatalf("unable to compute drift: %+v", err)
+	}
+	if len(drifts) != 1 || drifts[0].Status != DriftStatusDrifted {
+		t.Fatalf("expected 1 drifted object, found %+v", drifts)
+	}
+	// replicas are ignored; the image rule is for a different name
+	if len(drifts[0].Diffs) != 1 || drifts[0].Diffs[0].Path != "/spec/template/spec/containers/0/image" {
+		t.Fatalf("expected only the image diff, found %+v", drifts[0].Diffs)
+	}
+}
+
+func TestDriftInSyncAndMissing(t *testing.T) {
+	client := newFakeDriftClient(newDeployment(3, "nginx:1.24"))
+	other := newDeployment(3, "nginx:1.24")
+	other["metadata"] = map[string]interface{}{"name": "other"}
+	drifts, err := client.Drift(context.TODO(), []map[string]interface{}{newDeployment(3, "nginx:1.24"), other}, &DriftOptions{})
+	if err != nil {
+		t.Fatalf("unable to compute drift: %+v", err)
+	}
+	if len(drifts) != 2 || drifts[0].Status != DriftStatusInSync || drifts[1].Status != DriftStatusMissing {
+		t.Fatalf("expected in sync and missing, found %+v", drifts)
+	}
+	if drifts[1].Namespace != "default" {
+		t.Fatalf("expected the client's namespace, found %s", drifts[1].Namespace)
+	}
+}
+
+func TestDriftUnknownKind(t *testing.T) {
+	client := newFakeDriftClient(newDeployment(3, "nginx:1.24"))
+	widget := map[string]interface{}{"apiVersion": "example.com/v1", "kind": "Widget", "metadata": map[string]interface{}{"name": "w"}}
+	drifts, err := client.Drift(context.TODO(), []map[string]interface{}{widget, newDeployment(3, "nginx:1.24")}, &DriftOptions{})
+	if err != nil {
+		t.Fatalf("unable to compute drift: %+v", err)
+	}
+	if len(drifts) != 2 || drifts[0].Status != DriftStatusUnknownKind || drifts[1].Status != DriftStatusInSync {
+		t.Fatalf("expected unknown kind and in sync, found %+v", drifts)
+	}
+}
+
+func TestCompareWithLiveQuantities(t *testing.T) {
+	withResources := func(cpu interface{}, memory interface{}, replicas interface{}) map[string]interface{} {
+		deployment := newDeployment(1, "nginx:1.24")
+		container := deployment["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})[0].(map[string]interface{})
+		container["resources"] = map[string]interface{}{"limits": map[string]interface{}{"cpu": cpu, "memory": memory}}
+		container["env"] = []interface{}{map[string]interface{}{"name": "REPLICAS", "value": replicas}}
+		return deployment
+	}
+	for _, testCase := range []struct {
+		Rendered map[string]interface{}
+		Live     map[string]interface{}
+		Expected []string
+	}{
+		{Rendered: withResources("0.5", "1Gi", "1"), Live: withResources("500m", "1024Mi", "1"), Expected: []string{}},
+		{Rendered: withResources(1, "1G", "1"), Live: withResources("1", "1000M", "1"), Expected: []string{}},
+		{
+			Rendered: withResources("0.5", "1Gi", "1.0"),
+			Live:     withResources("600m", "1G", "1"),
+			Expected: []string{
+				"/spec/template/spec/containers/0/env/0/value",
+				"/spec/template/spec/containers/0/resources/limits/cpu",
+				"/spec/template/spec/containers/0/resources/limits/memory",
+			},
+		},
+	} {
+		diffs, err := CompareWithLive(testCase.Rendered, testCase.Live, &DriftOptions{})
+		if err != nil {
+			t.Fatalf("unable to compare: %+v", err)
+		}
+		var found []string
+		for _, diff := range diffs {
+			found = append(found, diff.Path)
+		}
+		if !reflect.DeepEqual(slice.Sort(found), testCase.Expected) {
+			t.Errorf("expected diffs at %v, found %v", testCase.Expected, found)
+		}
+	}
+}
+
+func TestDriftConfigRejectsUnknownKeys(t *testing.T) {
+	for _, contents := range []string{
+		"ignorePath:\n- path: /spec/replicas\n",
+		"ignorePaths:\n- pth: /spec/replicas\n",
+		"ignorePaths:\n- kind: Deployment\n",
+	} {
+		dir := writeTestFiles(t, map[string]string{"drift.yaml": contents})
+		if _, err := ReadDriftConfig(filepath.Join(dir, "drift.yaml")); err == nil {
+			t.Errorf("expected error for config %q", contents)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/olekukonko/tablewriter"
+	"golang.org/x/exp/maps"
+	v1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+type EnvVarSource string
+
+const (
+	EnvVarSourceLiteral          EnvVarSource = "literal"
+	EnvVarSourceSecretKeyRef     EnvVarSource = "secretKeyRef"
+	EnvVarSourceConfigMapKeyRef  EnvVarSource = "configMapKeyRef"
+	EnvVarSourceFieldRef         EnvVarSource = "fieldRef"
+	EnvVarSourceResourceFieldRef EnvVarSource = "resourceFieldRef"
+	EnvVarSourceEnvFromSecret    EnvVarSource = "envFrom secretRef"
+	EnvVarSourceEnvFromConfigMap EnvVarSource = "envFrom configMapRef"
+)
+
+// EnvVar is an `env` entry, or an `envFrom` source -- which has a Prefix
+// instead of a Name
+type EnvVar struct {
+	Name   string
+	Source EnvVarSource
+	// Value is a literal's value, before interpolation
+	Value string
+	// Reference is the secret or config map name, the field path, or the
+	// resource
+	Reference string
+	// Key is the secret or config map key
+	Key    string
+	Prefix string
+}
+
+func (e *EnvVar) IsEnvFrom() bool {
+	return e.Source == EnvVarSourceEnvFromSecret || e.Source == EnvVarSourceEnvFromConfigMap
+}
+
+// Description is where the value comes from: `secretKeyRef my-secret/password`
+func (e *EnvVar) Description() string {
+	switch {
+	case e.Source == EnvVarSourceLiteral:
+		return string(e.Source)
+	case e.Key != "":
+		return fmt.Sprintf("%s %s/%s", e.Source, e.Reference, e.Key)
+	case e.Prefix != "":
+		return fmt.Sprintf("%s %s (prefix %s)", e.Source, e.Reference, e.Prefix)
+	default:
+		return fmt.Sprintf("%s %s", e.Source, e.Reference)
+	}
+}
+
+// analyzeEnv lists envFrom sources followed by env entries, the order in which
+// kubernetes applies them
+func analyzeEnv(containerSpec v1.Container) []*EnvVar {
+	var env []*EnvVar
+	for _, envFrom := range containerSpec.EnvFrom {
+		if envFrom.ConfigMapRef != nil {
+			env = append(env, &EnvVar{Source: EnvVarSourceEnvFromConfigMap, Reference: envFrom.ConfigMapRef.Name, Prefix: envFrom.Prefix})
+		} else if envFrom.SecretRef != nil {
+			env = append(env, &EnvVar{Source: EnvVarSourceEnvFromSecret, Reference: envFrom.SecretRef.Name, Prefix: envFrom.Prefix})
+		}
+	}
+	for _, envVar := range containerSpec.Env {
+		from := envVar.ValueFrom
+		switch {
+		case from == nil:
+			env = append(env, &EnvVar{Name: envVar.Name, Source: EnvVarSourceLiteral, Value: envVar.Value})
+		case from.SecretKeyRef != nil:
+			env = append(env, &EnvVar{Name: envVar.Name, Source: EnvVarSourceSecretKeyRef, Reference: from.SecretKeyRef.Name, Key: from.SecretKeyRef.Key})
+		case from.ConfigMapKeyRef != nil:
+			env = append(env, &EnvVar{Name: envVar.Name, Source: EnvVarSourceConfigMapKeyRef, Reference: from.ConfigMapKeyRef.Name, Key: from.ConfigMapKeyRef.Key})
+		case from.FieldRef != nil:
+			env = append(env, &EnvVar{Name: envVar.Name, Source: EnvVarSourceFieldRef, Reference: from.FieldRef.FieldPath})
+		case from.ResourceFieldRef != nil:
+			env = append(env, &EnvVar{Name: envVar.Name, Source: EnvVarSourceResourceFieldRef, Reference: from.ResourceFieldRef.Resource})
+		}
+	}
+	return env
+}
+
+// ResolvedEnvVar is a variable as the container sees it
+type ResolvedEnvVar struct {
+	Name string
+	Var  *EnvVar
+	// Value is a literal's value after interpolating `$(VAR)`s; references to
+	// variables which aren't literals show where the value comes from, such as
+	// `<secretKeyRef db/password>`, and references to undefined variables are
+	// marked `<unresolved $(VAR)>`
+	Value string
+	// Shadows lists earlier definitions of Name, which this overrides
+	Shadows []*EnvVar
+}
+
+type ContainerEnv struct {
+	Vars []*ResolvedEnvVar
+	// UnknownSources are envFrom sources not in the chart, whose keys aren't
+	// known
+	UnknownSources []*EnvVar
+	// Command and Args are interpolated with Vars
+	Command []string
+	Args    []string
+	// Undefined lists variables referenced in command or args which aren't
+	// defined -- and which kubernetes leaves as is.  Variables which may come
+	// from UnknownSources aren't included.
+	Undefined []string
+}
+
+// ResolveContainerEnv applies kubernetes' rules: envFrom sources are applied in
+// order, then env entries, each overriding earlier variables of the same name;
+// env values may reference variables defined before them, and command and args
+// may reference any.  Keys of envFrom sources are known for secrets and config
+// maps in the chart.
+func (m *Model) ResolveContainerEnv(container *Container) *ContainerEnv {
+	env := &ContainerEnv{}
+	byName := map[string]*ResolvedEnvVar{}
+	lookup := func(name string) (string, bool) {
+		resolved, ok := byName[name]
+		if !ok {
+			return "", false
+		}
+		if resolved.Var.Source == EnvVarSourceLiteral {
+			return resolved.Value, true
+		}
+		return fmt.Sprintf("<%s>", resolved.Var.Description()), true
+	}
+	define := func(name string, envVar *EnvVar, value string) {
+		resolved := &ResolvedEnvVar{Name: name, Var: envVar, Value: value}
+		byName[name] = resolved
+		for i, existing := range env.Vars {
+			if existing.Name == name {
+				resolved.Shadows = append(append([]*EnvVar{}, existing.Shadows...), existing.Var)
+				env.Vars[i] = resolved
+				return
+			}
+		}
+		env.Vars = append(env.Vars, resolved)
+	}
+
+	for _, envVar := range container.Env {
+		if !envVar.IsEnvFrom() {
+			define(envVar.Name, envVar, expandEnvReferences(envVar.Value, lookup, nil))
+			continue
+		}
+		var keys []string
+		var ok bool
+		if envVar.Source == EnvVarSourceEnvFromConfigMap {
+			keys, ok = m.ConfigMapKeys[envVar.Reference]
+		} else {
+			keys, ok = m.SecretKeys[envVar.Reference]
+		}
+		if !ok {
+			env.UnknownSources = append(env.UnknownSources, envVar)
+			continue
+		}
+		for _, key := range keys {
+			define(envVar.Prefix+key, &EnvVar{Source: envVar.Source, Reference: envVar.Reference, Key: key, Prefix: envVar.Prefix}, "")
+		}
+	}
+
+	undefined := map[string]bool{}
+	for _, arg := range container.Command {
+		env.Command = append(env.Command, expandEnvReferences(arg, lookup, undefined))
+	}
+	for _, arg := range container.Args {
+		env.Args = append(env.Args, expandEnvReferences(arg, lookup, undefined))
+	}
+	// variables may come from envFrom sources whose keys aren't known
+	mayBeDefined := func(name string) bool {
+		return slice.Any(func(source *EnvVar) bool { return strings.HasPrefix(name, source.Prefix) }, env.UnknownSources)
+	}
+	env.Undefined = slice.Filter(func(name string) bool { return !mayBeDefined(name) }, slice.Sort(maps.Keys(undefined)))
+	return env
+}
+
+// expandEnvReferences replaces `$(VAR)` with VAR's value and `$$` with `$`.
+// Kubernetes leaves references to undefined variables as is, which would look
+// the same as an escaped `$$(VAR)`, so they're marked `<unresolved $(VAR)>`
+// instead -- and recorded in undefined, if not nil.
+func expandEnvReferences(s string, lookup func(string) (string, bool), undefined map[string]bool) string {
+	expanded := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '$' && i+1 < len(s) {
+			if s[i+1] == '$' {
+				expanded.WriteByte('$')
+				i++
+				continue
+			}
+			if end := strings.IndexByte(s[i+1:], ')'); s[i+1] == '(' && end >= 0 {
+				name := s[i+2 : i+1+end]
+				if value, ok := lookup(name); ok {
+					expanded.WriteString(value)
+				} else {
+					expanded.WriteString(fmt.Sprintf("<unresolved %s>", s[i:i+2+end]))
+					if undefined != nil {
+						undefined[name] = true
+					}
+				}
+				i += 1 + end
+				continue
+			}
+		}
+		expanded.WriteByte(s[i])
+	}
+	return expanded.String()
+}
+
+// shadowNote describes what a variable overrides: envFrom sources overriding
+// each other, env overriding envFrom, or duplicate env entries
+func shadowNote(resolved *ResolvedEnvVar) string {
+	var notes []string
+	for _, shadowed := range resolved.Shadows {
+		switch {
+		case !resolved.Var.IsEnvFrom() && !shadowed.IsEnvFrom():
+			notes = append(notes, "duplicate: overrides earlier env entry")
+		default:
+			notes = append(notes, fmt.Sprintf("shadows %s", shadowed.Description()))
+		}
+	}
+	return strings.Join(notes, "\n")
+}
+
+func (m *Model) EnvTable() string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetAutoMergeCellsByColumnIndex([]int{0, 1})
+	table.SetHeader([]string{"Resource", "Container", "Name", "Source", "Value", "Notes"})
+	m.forEachContainer(func(resource string, container *Container) {
+		env := m.ResolveContainerEnv(container)
+		for _, resolved := range env.Vars {
+			table.Append([]string{resource, container.Name, resolved.Name, resolved.Var.Description(), resolved.Value, shadowNote(resolved)})
+		}
+		for _, source := range env.UnknownSources {
+			table.Append([]string{resource, container.Name, source.Prefix + "*", source.Description(), "", "keys unknown: not in chart"})
+		}
+	})
+	table.Render()
+	return tableString.String()
+}
+
+// CommandsTable shows command and args after interpolating env vars
+func (m *Model) CommandsTable() string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetAutoMergeCellsByColumnIndex([]int{0})
+	table.SetHeader([]string{"Resource", "Container", "Command", "Args", "Undefined"})
+	m.forEachContainer(func(resource string, container *Container) {
+		if len(container.Command) == 0 && len(container.Args) == 0 {
+			return
+		}
+		env := m.ResolveContainerEnv(container)
+		table.Append([]string{resource, container.Name, strings.Join(env.Command, "\n"), strings.Join(env.Args, "\n"), strings.Join(env.Undefined, "\n")})
+	})
+	table.Render()
+	return tableString.String()
+}
+
+func (m *Model) forEachContainer(f func(resource string, container *Container)) {
+	for _, kind := range slice.Sort(maps.Keys(m.Pods)) {
+		for _, name := range slice.Sort(maps.Keys(m.Pods[kind])) {
+			for _, container := range slice.SortOn(func(c *Container) string { return c.Name }, m.Pods[kind][name].Containers) {
+				f(fmt.Sprintf("%s/%s", kind, name), container)
+			}
+		}
+	}
+}
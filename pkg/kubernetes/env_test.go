@@ -0,0 +1,132 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandEnvReferences(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		value, ok := map[string]string{"X": "x", "EMPTY": ""}[name]
+		return value, ok
+	}
+	for _, testCase := range []struct {
+		Input     string
+		Expected  string
+		Undefined []string
+	}{
+		{Input: "$(X)-$(X)", Expected: "x-x"},
+		{Input: "a$(EMPTY)b", Expected: "ab"},
+		{Input: "$$(X)", Expected: "$(X)"},
+		{Input: "$$$(X)", Expected: "$x"},
+		{Input: "$(Y)", Expected: "<unresolved $(Y)>", Undefined: []string{"Y"}},
+		{Input: "$$(Y) $(Y)", Expected: "$(Y) <unresolved $(Y)>", Undefined: []string{"Y"}},
+		{Input: "cost: $5 $$", Expected: "cost: $5 $"},
+		{Input: "$(X", Expected: "$(X"},
+		{Input: "trailing $", Expected: "trailing $"},
+	} {
+		undefined := map[string]bool{}
+		expanded := expandEnvReferences(testCase.Input, lookup, undefined)
+		if expanded != testCase.Expected {
+			t.Errorf("%s: expected %s, found %s", testCase.Input, testCase.Expected, expanded)
+		}
+		var undefinedNames []string
+		for name := range undefined {
+			undefinedNames = append(undefinedNames, name)
+		}
+		if !reflect.DeepEqual(undefinedNames, testCase.Undefined) {
+			t.Errorf("%s: expected undefined %+v, found %+v", testCase.Input, testCase.Undefined, undefinedNames)
+		}
+	}
+}
+
+func TestResolveContainerEnv(t *testing.T) {
+	model := parseTestModel(t, `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  HOST: db.local
+  PORT: "5432"
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: creds
+stringData:
+  PASSWORD: hunter2
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1.0
+        envFrom:
+        - prefix: APP_
+          configMapRef: {name: config}
+        - secretRef: {name: creds}
+        - configMapRef: {name: config}
+        - prefix: EXTERNAL_
+          secretRef: {name: external}
+        env:
+        - name: PORT
+          value: "8080"
+        - name: PASSWORD
+          valueFrom:
+            secretKeyRef: {name: other, key: pw}
+        - name: MODE
+          value: a
+        - name: MODE
+          value: b
+        - name: URL
+          value: http://$(HOST):$(PORT)/$(MODE)
+        command: [sh, -c]
+        args:
+        - echo $(URL) $$(HOME) $(MISSING) $(EXTERNAL_TOKEN)
+`)
+	env := model.ResolveContainerEnv(model.Pods["Deployment"]["app"].Containers[0])
+
+	type resolvedVar struct {
+		Name        string
+		Description string
+		Value       string
+		Notes       string
+	}
+	var vars []resolvedVar
+	for _, resolved := range env.Vars {
+		vars = append(vars, resolvedVar{resolved.Name, resolved.Var.Description(), resolved.Value, shadowNote(resolved)})
+	}
+	expectedVars := []resolvedVar{
+		{"APP_HOST", "envFrom configMapRef config/HOST", "", ""},
+		{"APP_PORT", "envFrom configMapRef config/PORT", "", ""},
+		{"PASSWORD", "secretKeyRef other/pw", "", "shadows envFrom secretRef creds/PASSWORD"},
+		{"HOST", "envFrom configMapRef config/HOST", "", ""},
+		{"PORT", "literal", "8080", "shadows envFrom configMapRef config/PORT"},
+		{"MODE", "literal", "b", "duplicate: overrides earlier env entry"},
+		{"URL", "literal", "http://<envFrom configMapRef config/HOST>:8080/b", ""},
+	}
+	if !reflect.DeepEqual(vars, expectedVars) {
+		t.Errorf("expected vars %+v, found %+v", expectedVars, vars)
+	}
+
+	if len(env.UnknownSources) != 1 || env.UnknownSources[0].Reference != "external" || env.UnknownSources[0].Prefix != "EXTERNAL_" {
+		t.Errorf("expected unknown source external with prefix EXTERNAL_, found %+v", env.UnknownSources)
+	}
+	if !reflect.DeepEqual(env.Command, []string{"sh", "-c"}) {
+		t.Errorf("expected command [sh -c], found %+v", env.Command)
+	}
+	expectedArgs := []string{"echo http://<envFrom configMapRef config/HOST>:8080/b $(HOME) <unresolved $(MISSING)> <unresolved $(EXTERNAL_TOKEN)>"}
+	if !reflect.DeepEqual(env.Args, expectedArgs) {
+		t.Errorf("expected args %+v, found %+v", expectedArgs, env.Args)
+	}
+	// EXTERNAL_TOKEN may come from the external secret, whose keys aren't known
+	if !reflect.DeepEqual(env.Undefined, []string{"MISSING"}) {
+		t.Errorf("expected undefined [MISSING], found %+v", env.Undefined)
+	}
+}
@@ -61,10 +61,10 @@ func (m *Model) SecretsTable() string {
 
 	secretsComparison, _ := m.GetUsedUnusedSecretsAndConfigMaps()
 	for _, secret := range secretsComparison.JustA {
-		table.Append([]string{secret, "chart", "(none)"})
+		table.Append([]string{secret, m.SecretSource(secret), "(none)"})
 	}
 	for _, secret := range secretsComparison.Both {
-		table.Append([]string{secret, "chart", strings.Join(m.SecretUsages(secret), "\n")})
+		table.Append([]string{secret, m.SecretSource(secret), strings.Join(m.SecretUsages(secret), "\n")})
 	}
 	for _, secret := range secretsComparison.JustB {
 		table.Append([]string{secret, "unknown", strings.Join(m.SecretUsages(secret), "\n")})
@@ -0,0 +1,216 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/mattfenwick/collections/pkg/yaml"
+	"github.com/mattfenwick/kube-utils/pkg/graph"
+	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"os"
+	"path/filepath"
+)
+
+// node classes
+const (
+	NodeClassWorkload       = "workload"
+	NodeClassContainer      = "container"
+	NodeClassInitContainer  = "initContainer"
+	NodeClassSecret         = "secret"
+	NodeClassConfigMap      = "configMap"
+	NodeClassSecretProducer = "secretProducer"
+	NodeClassServiceAccount = "serviceAccount"
+	NodeClassRoleBinding    = "roleBinding"
+	NodeClassRole           = "role"
+)
+
+// edge classes; edges point from a resource to what it depends on
+const (
+	EdgeClassContainer       = "container"
+	EdgeClassMount           = "mount"
+	EdgeClassEnv             = "env"
+	EdgeClassImagePullSecret = "imagePullSecret"
+	EdgeClassProducedBy      = "producedBy"
+	EdgeClassServiceAccount  = "serviceAccount"
+	EdgeClassRBAC            = "rbac"
+)
+
+var DefaultGraphTheme = &graph.Theme{
+	Graph:     graph.Attributes{"rankdir": "LR", "fontname": "Helvetica"},
+	Subgraphs: graph.Attributes{"style": "rounded", "color": "gray60"},
+	Nodes: map[string]graph.Attributes{
+		NodeClassWorkload:       {"shape": "box", "style": "filled", "fillcolor": "lightblue"},
+		NodeClassContainer:      {"shape": "box", "style": "rounded"},
+		NodeClassInitContainer:  {"shape": "box", "style": "rounded,dashed"},
+		NodeClassSecret:         {"shape": "note", "style": "filled", "fillcolor": "mistyrose"},
+		NodeClassConfigMap:      {"shape": "note", "style": "filled", "fillcolor": "lightyellow"},
+		NodeClassSecretProducer: {"shape": "component", "style": "filled", "fillcolor": "lavender"},
+		NodeClassServiceAccount: {"shape": "ellipse", "style": "filled", "fillcolor": "palegreen"},
+		NodeClassRoleBinding:    {"shape": "hexagon"},
+		NodeClassRole:           {"shape": "hexagon", "style": "filled", "fillcolor": "honeydew"},
+	},
+	Edges: map[string]graph.Attributes{
+		EdgeClassContainer:       {"color": "gray40", "arrowhead": "none"},
+		EdgeClassMount:           {"color": "blue", "penwidth": "2"},
+		EdgeClassEnv:             {"color": "darkorange", "penwidth": "2", "style": "dashed"},
+		EdgeClassImagePullSecret: {"color": "red", "style": "dotted"},
+		EdgeClassProducedBy:      {"color": "purple", "style": "dashed"},
+		EdgeClassServiceAccount:  {"color": "darkgreen"},
+		EdgeClassRBAC:            {"color": "darkgreen", "style": "dashed"},
+	},
+}
+
+// ResourceNodeID is `<kind>/<name>`: `Secret/my-secret`
+func ResourceNodeID(kind string, name string) string {
+	return fmt.Sprintf("%s/%s", kind, name)
+}
+
+// ContainerNodeID is `<kind>/<name>/<container>`: `Deployment/my-app/nginx`
+func ContainerNodeID(kind string, name string, container string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, name, container)
+}
+
+func resourceNode(kind string, name string, class string) *graph.Node {
+	return &graph.Node{ID: ResourceNodeID(kind, name), Label: name, Class: class}
+}
+
+// Graph links workloads to their containers, service accounts and image pull
+// secrets; containers to the secrets and config maps they mount or use as env
+// vars; secrets to the resources producing them; and service accounts to their
+// role bindings and roles
+func (m *Model) Graph() *graph.Graph {
+	yamlGraph := graph.NewGraph("", "")
+	yamlGraph.Theme = DefaultGraphTheme
+
+	secretsComparison, configMapsComparison := m.GetUsedUnusedSecretsAndConfigMaps()
+
+	secretsGraph := graph.NewGraph("secrets", "secrets")
+	unusedSecretsGraph := graph.NewGraph("unused secrets", "unused secrets")
+	unknownSourceSecretsGraph := graph.NewGraph("unknown source secrets", "unknown source secrets")
+	for _, secret := range secretsComparison.JustA {
+		unusedSecretsGraph.AddNode(resourceNode("Secret", secret, NodeClassSecret))
+	}
+	for _, secret := range secretsComparison.Both {
+		secretsGraph.AddNode(resourceNode("Secret", secret, NodeClassSecret))
+	}
+	for _, secret := range secretsComparison.JustB {
+		unknownSourceSecretsGraph.AddNode(resourceNode("Secret", secret, NodeClassSecret))
+	}
+	producersGraph := graph.NewGraph("secret producers", "secret producers")
+	for _, secret := range slice.Sort(maps.Keys(m.SecretProducers)) {
+		for _, producer := range m.SecretProducers[secret] {
+			producerNode := &graph.Node{ID: producer.Source(), Label: producer.Source(), Class: NodeClassSecretProducer}
+			producersGraph.AddNode(producerNode)
+			yamlGraph.AddEdge(&graph.Edge{From: ResourceNodeID("Secret", secret), To: producerNode.ID, Class: EdgeClassProducedBy})
+		}
+	}
+	yamlGraph.AddSubgraph(secretsGraph)
+	yamlGraph.AddSubgraph(unusedSecretsGraph)
+	yamlGraph.AddSubgraph(producersGraph)
+	yamlGraph.AddSubgraph(unknownSourceSecretsGraph)
+
+	cmsGraph := graph.NewGraph("configmaps", "configmaps")
+	unusedConfigMapsGraph := graph.NewGraph("unused configmaps", "unused configmaps")
+	unknownSourceConfigMapsGraph := graph.NewGraph("unknown source configmaps", "unknown source configmaps")
+	for _, configMap := range configMapsComparison.JustA {
+		unusedConfigMapsGraph.AddNode(resourceNode("ConfigMap", configMap, NodeClassConfigMap))
+	}
+	for _, configMap := range configMapsComparison.Both {
+		cmsGraph.AddNode(resourceNode("ConfigMap", configMap, NodeClassConfigMap))
+	}
+	for _, configMap := range configMapsComparison.JustB {
+		unknownSourceConfigMapsGraph.AddNode(resourceNode("ConfigMap", configMap, NodeClassConfigMap))
+	}
+	yamlGraph.AddSubgraph(cmsGraph)
+	yamlGraph.AddSubgraph(unusedConfigMapsGraph)
+	yamlGraph.AddSubgraph(unknownSourceConfigMapsGraph)
+
+	rbacGraph := graph.NewGraph("rbac", "rbac")
+	for _, binding := range m.RoleBindings {
+		bindingNode := resourceNode(binding.Kind, binding.Name, NodeClassRoleBinding)
+		roleNode := resourceNode(binding.RoleKind, binding.RoleName, NodeClassRole)
+		rbacGraph.AddNode(bindingNode)
+		rbacGraph.AddNode(roleNode)
+		yamlGraph.AddEdge(&graph.Edge{From: bindingNode.ID, To: roleNode.ID, Class: EdgeClassRBAC})
+		for _, serviceAccount := range binding.ServiceAccounts {
+			serviceAccountNode := resourceNode("ServiceAccount", serviceAccount, NodeClassServiceAccount)
+			rbacGraph.AddNode(serviceAccountNode)
+			yamlGraph.AddEdge(&graph.Edge{From: serviceAccountNode.ID, To: bindingNode.ID, Class: EdgeClassRBAC})
+		}
+	}
+	yamlGraph.AddSubgraph(rbacGraph)
+
+	for _, kind := range slice.Sort(maps.Keys(m.Pods)) {
+		for _, name := range slice.Sort(maps.Keys(m.Pods[kind])) {
+			spec := m.Pods[kind][name]
+			workloadNode := resourceNode(kind, name, NodeClassWorkload)
+			workloadNode.Label = workloadNode.ID
+			yamlGraph.AddNode(workloadNode)
+			if spec.ServiceAccount != "" {
+				serviceAccountNode := resourceNode("ServiceAccount", spec.ServiceAccount, NodeClassServiceAccount)
+				if _, ok := yamlGraph.FindNode(serviceAccountNode.ID); !ok {
+					rbacGraph.AddNode(serviceAccountNode)
+				}
+				yamlGraph.AddEdge(&graph.Edge{From: workloadNode.ID, To: serviceAccountNode.ID, Class: EdgeClassServiceAccount})
+			}
+			for _, secret := range spec.ImagePullSecrets {
+				secretNode := resourceNode("Secret", secret, NodeClassSecret)
+				if _, ok := yamlGraph.FindNode(secretNode.ID); !ok {
+					unknownSourceSecretsGraph.AddNode(secretNode)
+				}
+				yamlGraph.AddEdge(&graph.Edge{From: workloadNode.ID, To: secretNode.ID, Class: EdgeClassImagePullSecret})
+			}
+			for _, container := range spec.Containers {
+				containerNode := &graph.Node{ID: ContainerNodeID(kind, name, container.Name), Label: container.Name, Class: NodeClassContainer}
+				if container.IsInit {
+					containerNode.Label, containerNode.Class = container.Name+" (init)", NodeClassInitContainer
+				}
+				yamlGraph.AddNode(containerNode)
+				yamlGraph.AddEdge(&graph.Edge{From: workloadNode.ID, To: containerNode.ID, Class: EdgeClassContainer})
+				for _, configMap := range container.MountedConfigMaps.ToSlice() {
+					yamlGraph.AddEdge(&graph.Edge{From: containerNode.ID, To: ResourceNodeID("ConfigMap", configMap), Class: EdgeClassMount})
+				}
+				for _, secret := range container.MountedSecrets.ToSlice() {
+					yamlGraph.AddEdge(&graph.Edge{From: containerNode.ID, To: ResourceNodeID("Secret", secret), Class: EdgeClassMount})
+				}
+				for _, envVar := range container.Env {
+					switch envVar.Source {
+					case EnvVarSourceConfigMapKeyRef, EnvVarSourceEnvFromConfigMap:
+						yamlGraph.AddEdge(&graph.Edge{From: containerNode.ID, To: ResourceNodeID("ConfigMap", envVar.Reference), Class: EdgeClassEnv})
+					case EnvVarSourceSecretKeyRef, EnvVarSourceEnvFromSecret:
+						yamlGraph.AddEdge(&graph.Edge{From: containerNode.ID, To: ResourceNodeID("Secret", envVar.Reference), Class: EdgeClassEnv})
+					}
+				}
+			}
+		}
+	}
+	return yamlGraph
+}
+
+type GraphArgs struct {
+	ChartPath string
+	Output    string
+}
+
+func RunGraph(args *GraphArgs) {
+	objs, err := yaml.ParseManyFromFile[map[string]interface{}](args.ChartPath)
+	utils.DoOrDie(err)
+	g := NewModelFromYaml(objs).Graph()
+
+	switch args.Output {
+	case "dot":
+		fmt.Printf("%s\n", g.RenderAsDot())
+	case "svg":
+		fmt.Printf("%s\n", g.RenderAsSvg())
+	case "html":
+		fmt.Printf("%s\n", g.RenderAsHtml(filepath.Base(args.ChartPath)))
+	case "png":
+		out, err := g.RenderAsPng()
+		utils.DoOrDie(err)
+		_, err = os.Stdout.Write(out)
+		utils.DoOrDie(errors.Wrapf(err, "unable to write png"))
+	default:
+		utils.DoOrDie(errors.Errorf("invalid output format '%s'", args.Output))
+	}
+}
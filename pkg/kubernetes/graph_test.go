@@ -0,0 +1,58 @@
+package kubernetes
+
+import (
+	"github.com/mattfenwick/kube-utils/pkg/graph"
+	"testing"
+)
+
+const expectedGoldenDot = `digraph "chart" {
+  label="chart";
+  fontname="Helvetica";
+  rankdir="LR";
+  "Deployment/web" [fillcolor="lightblue", label="Deployment/web", shape="box", style="filled"];
+  "Secret/say \"hi\"\\path\nnext" [fillcolor="mistyrose", label="label \"quoted\"\\back\nslash", shape="note", style="filled"];
+
+  "Deployment/web" -> "Deployment/web/web" [arrowhead="none", color="gray40"];
+  "Deployment/web" -> "Secret/regcred" [color="red", style="dotted"];
+  "Deployment/web" -> "Secret/say \"hi\"\\path\nnext" [label="uses \"it\"\n"];
+  "Deployment/web" -> "ServiceAccount/app" [color="darkgreen"];
+  "Deployment/web/web" -> "ConfigMap/settings" [color="blue", penwidth="2"];
+  "Deployment/web/web" -> "Secret/say \"hi\"\\path\nnext" [color="darkorange", penwidth="2", style="dashed"];
+  "Secret/say \"hi\"\\path\nnext" -> "ExternalSecret/db" [color="purple", style="dashed"];
+  "ServiceAccount/app" -> "RoleBinding/app" [color="darkgreen", style="dashed"];
+
+  subgraph "cluster_rbac" {
+    label="rbac \"roles\"";
+    color="gray60";
+    style="rounded";
+    "ServiceAccount/app" [fillcolor="palegreen", label="app", shape="ellipse", style="filled"];
+
+
+  }
+}`
+
+func TestRenderAsDotGolden(t *testing.T) {
+	g := graph.NewGraph("chart", "chart")
+	g.Theme = DefaultGraphTheme
+	weird := "Secret/say \"hi\"\\path\nnext"
+	g.AddNode(&graph.Node{ID: "Deployment/web", Class: NodeClassWorkload})
+	g.AddNode(&graph.Node{ID: weird, Label: "label \"quoted\"\\back\nslash", Class: NodeClassSecret})
+	sub := graph.NewGraph("rbac", "rbac \"roles\"")
+	sub.AddNode(&graph.Node{ID: "ServiceAccount/app", Label: "app", Class: NodeClassServiceAccount})
+	g.AddSubgraph(sub)
+	for _, edge := range []*graph.Edge{
+		{From: "Deployment/web", To: "Deployment/web/web", Class: EdgeClassContainer},
+		{From: "Deployment/web/web", To: "ConfigMap/settings", Class: EdgeClassMount},
+		{From: "Deployment/web/web", To: weird, Class: EdgeClassEnv},
+		{From: "Deployment/web", To: "Secret/regcred", Class: EdgeClassImagePullSecret},
+		{From: weird, To: "ExternalSecret/db", Class: EdgeClassProducedBy},
+		{From: "Deployment/web", To: "ServiceAccount/app", Class: EdgeClassServiceAccount},
+		{From: "ServiceAccount/app", To: "RoleBinding/app", Class: EdgeClassRBAC},
+		{From: "Deployment/web", To: weird, Label: "uses \"it\"\n", Class: "unstyled"},
+	} {
+		g.AddEdge(edge)
+	}
+	if found := g.RenderAsDot(); found != expectedGoldenDot {
+		t.Errorf("expected\n%s\nfound\n%s", expectedGoldenDot, found)
+	}
+}
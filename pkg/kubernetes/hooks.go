@@ -0,0 +1,332 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/collections/pkg/set"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/mattfenwick/collections/pkg/yaml"
+	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"strconv"
+	"strings"
+)
+
+const (
+	helmHookAnnotation             = "helm.sh/hook"
+	helmHookWeightAnnotation       = "helm.sh/hook-weight"
+	helmHookDeletePolicyAnnotation = "helm.sh/hook-delete-policy"
+
+	helmHookDeletePolicyDefault   = "before-hook-creation"
+	helmHookDeletePolicySucceeded = "hook-succeeded"
+)
+
+// HelmHook is parsed from an object's `helm.sh/hook*` annotations
+type HelmHook struct {
+	// Phases are hook events: `pre-install`, `post-upgrade`, `test`, etc.
+	Phases         []string `json:"phases"`
+	Weight         int      `json:"weight"`
+	DeletePolicies []string `json:"deletePolicies"`
+}
+
+func (h *HelmHook) HasPhase(phase string) bool {
+	return slice.Any(func(p string) bool { return p == phase }, h.Phases)
+}
+
+func (h *HelmHook) HasDeletePolicy(policy string) bool {
+	return slice.Any(func(p string) bool { return p == policy }, h.DeletePolicies)
+}
+
+func splitAnnotationList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ParseHelmHook returns nil if annotations don't make an object a hook.  Like
+// helm, it treats an invalid weight as 0; the error is returned along with the
+// hook, so that callers can report it.
+func ParseHelmHook(annotations map[string]string) (*HelmHook, error) {
+	phases := splitAnnotationList(annotations[helmHookAnnotation])
+	if len(phases) == 0 {
+		return nil, nil
+	}
+	hook := &HelmHook{Phases: phases, DeletePolicies: splitAnnotationList(annotations[helmHookDeletePolicyAnnotation])}
+	if len(hook.DeletePolicies) == 0 {
+		hook.DeletePolicies = []string{helmHookDeletePolicyDefault}
+	}
+	if weight := strings.TrimSpace(annotations[helmHookWeightAnnotation]); weight != "" {
+		parsed, err := strconv.Atoi(weight)
+		if err != nil {
+			return hook, errors.Errorf("invalid %s annotation '%s'", helmHookWeightAnnotation, weight)
+		}
+		hook.Weight = parsed
+	}
+	return hook, nil
+}
+
+// Resource is any object, with what affects when it exists: its hook
+// annotations and ownerReferences
+type Resource struct {
+	Kind            string                  `json:"kind"`
+	Name            string                  `json:"name"`
+	Hook            *HelmHook               `json:"hook,omitempty"`
+	OwnerReferences []metav1.OwnerReference `json:"ownerReferences,omitempty"`
+	// AnnotationProblems are hook annotations which couldn't be parsed
+	AnnotationProblems []string `json:"annotationProblems,omitempty"`
+}
+
+func (r *Resource) String() string {
+	return fmt.Sprintf("%s/%s", r.Kind, r.Name)
+}
+
+func AnalyzeResource(obj map[string]interface{}) *Resource {
+	u := &unstructured.Unstructured{Object: obj}
+	resource := &Resource{Kind: u.GetKind(), Name: u.GetName(), OwnerReferences: u.GetOwnerReferences()}
+	hook, err := ParseHelmHook(u.GetAnnotations())
+	if err != nil {
+		logrus.Warnf("%s: %s", resource, err.Error())
+		resource.AnnotationProblems = append(resource.AnnotationProblems, err.Error())
+	}
+	resource.Hook = hook
+	return resource
+}
+
+// helmInstallOrder is the order in which helm creates kinds, both among hooks of
+// the same weight and in the main release; other kinds come last
+var helmInstallOrder = []string{
+	"Namespace", "NetworkPolicy", "ResourceQuota", "LimitRange", "PodSecurityPolicy", "PodDisruptionBudget",
+	"ServiceAccount", "Secret", "SecretList", "ConfigMap", "StorageClass", "PersistentVolume", "PersistentVolumeClaim",
+	"CustomResourceDefinition", "ClusterRole", "ClusterRoleList", "ClusterRoleBinding", "ClusterRoleBindingList",
+	"Role", "RoleList", "RoleBinding", "RoleBindingList", "Service", "DaemonSet", "Pod", "ReplicationController",
+	"ReplicaSet", "Deployment", "HorizontalPodAutoscaler", "StatefulSet", "Job", "CronJob", "IngressClass",
+	"Ingress", "APIService",
+}
+
+func helmKindOrder(kind string) int {
+	for i, k := range helmInstallOrder {
+		if k == kind {
+			return i
+		}
+	}
+	return len(helmInstallOrder)
+}
+
+func sortForHelm(resources []*Resource) []*Resource {
+	return slice.SortOn(func(r *Resource) string {
+		weight := 0
+		if r.Hook != nil {
+			weight = r.Hook.Weight
+		}
+		// offset weights so that negative weights sort first as strings
+		return fmt.Sprintf("%012d %03d %s", int64(weight)+1<<32, helmKindOrder(r.Kind), r.Name)
+	}, resources)
+}
+
+const mainReleasePhase = "main release"
+
+// InstallStep is when a resource is created during a helm event: a hook
+// phase, or the main release
+type InstallStep struct {
+	Index    int       `json:"index"`
+	Phase    string    `json:"phase"`
+	Resource *Resource `json:"resource"`
+}
+
+type InstallOrderProblem struct {
+	Resource string `json:"resource"`
+	Problem  string `json:"problem"`
+}
+
+type InstallOrderReport struct {
+	Event    string                 `json:"event"`
+	Steps    []*InstallStep         `json:"steps"`
+	Problems []*InstallOrderProblem `json:"problems"`
+}
+
+// InstallOrder orders resources for a helm event -- install, upgrade, delete or
+// rollback -- the way helm runs them: `pre-<event>` hooks one at a time by
+// weight, kind and name, then the main release, then `post-<event>` hooks.
+// Hooks for other events are left out.
+func (m *Model) InstallOrder(event string) *InstallOrderReport {
+	report := &InstallOrderReport{Event: event}
+	var pre, main, post []*Resource
+	for _, resource := range m.Resources {
+		switch {
+		case resource.Hook == nil:
+			main = append(main, resource)
+		case resource.Hook.HasPhase("pre-" + event):
+			pre = append(pre, resource)
+		case resource.Hook.HasPhase("post-" + event):
+			post = append(post, resource)
+		}
+	}
+	index := 0
+	for _, resource := range sortForHelm(pre) {
+		index++
+		report.Steps = append(report.Steps, &InstallStep{Index: index, Phase: "pre-" + event, Resource: resource})
+	}
+	// the main release is applied at once, in kind order
+	index++
+	for _, resource := range sortForHelm(main) {
+		report.Steps = append(report.Steps, &InstallStep{Index: index, Phase: mainReleasePhase, Resource: resource})
+	}
+	for _, resource := range sortForHelm(post) {
+		index++
+		report.Steps = append(report.Steps, &InstallStep{Index: index, Phase: "post-" + event, Resource: resource})
+	}
+	report.Problems = m.installOrderProblems(report.Steps)
+	return report
+}
+
+// installOrderProblems finds hooks using secrets and config maps which don't
+// exist yet when they run, main release resources using secrets and config
+// maps deleted by hooks, ownerReferences which the api server will reject, and
+// invalid hook annotations
+func (m *Model) installOrderProblems(steps []*InstallStep) []*InstallOrderProblem {
+	var problems []*InstallOrderProblem
+	created := map[string]*InstallStep{}
+	for _, step := range steps {
+		created[step.Resource.String()] = step
+		if step.Resource.Kind == "Secret" || step.Resource.Kind == "ConfigMap" {
+			continue
+		}
+		// secrets produced by operators exist once their producers do
+		for _, producers := range m.SecretProducers {
+			for _, producer := range producers {
+				if producer.Source() == step.Resource.String() {
+					created["Secret/"+producer.SecretName] = step
+				}
+			}
+		}
+	}
+
+	for _, step := range steps {
+		resource := step.Resource
+		for _, problem := range resource.AnnotationProblems {
+			problems = append(problems, &InstallOrderProblem{Resource: resource.String(), Problem: problem + "; helm treats it as 0"})
+		}
+		spec, ok := m.Pods[resource.Kind][resource.Name]
+		if ok {
+			for _, dependency := range podSpecDependencies(spec) {
+				source, exists := created[dependency]
+				switch {
+				case !exists:
+					// not in the chart; SecretsTable and ConfigMapsTable report these
+				case source.Index > step.Index:
+					message := fmt.Sprintf("uses %s, which is created later, in step %d (%s)", dependency, source.Index, source.Phase)
+					if strings.HasPrefix(step.Phase, "pre-upgrade") {
+						message += "; on upgrade it exists only if a previous release created it"
+					}
+					problems = append(problems, &InstallOrderProblem{Resource: resource.String(), Problem: message})
+				case source.Resource.Hook != nil && source.Resource.Hook.HasDeletePolicy(helmHookDeletePolicySucceeded) && source.Index < step.Index:
+					problems = append(problems, &InstallOrderProblem{
+						Resource: resource.String(),
+						Problem:  fmt.Sprintf("uses %s, which is deleted after its %s hook succeeds", dependency, source.Phase),
+					})
+				}
+			}
+		}
+		for _, owner := range resource.OwnerReferences {
+			if owner.UID == "" {
+				problems = append(problems, &InstallOrderProblem{
+					Resource: resource.String(),
+					Problem:  fmt.Sprintf("ownerReference to %s/%s has no uid, which the api server requires; uids aren't known until objects are created", owner.Kind, owner.Name),
+				})
+			}
+		}
+	}
+	return problems
+}
+
+func podSpecDependencies(spec *PodSpec) []string {
+	var dependencies []string
+	for _, secret := range spec.ImagePullSecrets {
+		dependencies = append(dependencies, "Secret/"+secret)
+	}
+	for _, container := range spec.Containers {
+		for _, secret := range container.SecretsSlice() {
+			dependencies = append(dependencies, "Secret/"+secret)
+		}
+		for _, configMap := range container.ConfigMapsSlice() {
+			dependencies = append(dependencies, "ConfigMap/"+configMap)
+		}
+	}
+	return slice.Sort(set.FromSlice(dependencies).ToSlice())
+}
+
+func (r *InstallOrderReport) StepsTable() string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetAutoMergeCellsByColumnIndex([]int{0, 1})
+	table.SetHeader([]string{"Step", "Phase", "Resource", "Weight", "Delete Policy", "Owners"})
+	for _, step := range r.Steps {
+		weight, deletePolicy := "", ""
+		if hook := step.Resource.Hook; hook != nil {
+			weight, deletePolicy = fmt.Sprintf("%d", hook.Weight), strings.Join(hook.DeletePolicies, "\n")
+		}
+		owners := slice.Map(func(o metav1.OwnerReference) string { return fmt.Sprintf("%s/%s", o.Kind, o.Name) }, step.Resource.OwnerReferences)
+		table.Append([]string{fmt.Sprintf("%d", step.Index), step.Phase, step.Resource.String(), weight, deletePolicy, strings.Join(owners, "\n")})
+	}
+	table.Render()
+	return tableString.String()
+}
+
+func (r *InstallOrderReport) ProblemsTable() string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetAutoMergeCells(true)
+	table.SetHeader([]string{"Resource", "Problem"})
+	for _, problem := range r.Problems {
+		table.Append([]string{problem.Resource, problem.Problem})
+	}
+	table.Render()
+	return tableString.String()
+}
+
+type InstallOrderArgs struct {
+	ChartPath string
+	Event     string
+	Output    string
+	Check     bool
+}
+
+var helmEvents = []string{"install", "upgrade", "delete", "rollback"}
+
+func RunInstallOrder(args *InstallOrderArgs) {
+	if !slice.Any(func(e string) bool { return e == args.Event }, helmEvents) {
+		utils.DoOrDie(errors.Errorf("invalid event '%s'; must be one of %v", args.Event, helmEvents))
+	}
+	objs, err := yaml.ParseManyFromFile[map[string]interface{}](args.ChartPath)
+	utils.DoOrDie(err)
+	report := NewModelFromYaml(objs).InstallOrder(args.Event)
+
+	switch args.Output {
+	case "table":
+		fmt.Printf("%s install order:\n%s\n\n", args.Event, report.StepsTable())
+		fmt.Printf("problems:\n%s\n\n", report.ProblemsTable())
+	case "json":
+		json.PrintOptions(report, &json.MarshalOptions{EscapeHTML: false, Indent: true, Sort: false})
+	default:
+		utils.DoOrDie(errors.Errorf("invalid output format '%s'", args.Output))
+	}
+
+	if len(report.Problems) > 0 {
+		logrus.Warnf("found %d install order problems", len(report.Problems))
+		if args.Check {
+			utils.DoOrDie(errors.Errorf("found %d install order problems", len(report.Problems)))
+		}
+	}
+}
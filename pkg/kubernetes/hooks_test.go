@@ -0,0 +1,139 @@
+package kubernetes
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHelmHook(t *testing.T) {
+	for _, testCase := range []struct {
+		Name        string
+		Annotations map[string]string
+		Expected    *HelmHook
+		IsError     bool
+	}{
+		{Name: "not a hook", Annotations: map[string]string{"helm.sh/hook-weight": "3"}, Expected: nil},
+		{
+			Name:        "defaults",
+			Annotations: map[string]string{"helm.sh/hook": "pre-install"},
+			Expected:    &HelmHook{Phases: []string{"pre-install"}, DeletePolicies: []string{"before-hook-creation"}},
+		},
+		{
+			Name:        "lists and weight",
+			Annotations: map[string]string{"helm.sh/hook": " pre-install, pre-upgrade ,", "helm.sh/hook-weight": "-5", "helm.sh/hook-delete-policy": "hook-succeeded,hook-failed"},
+			Expected:    &HelmHook{Phases: []string{"pre-install", "pre-upgrade"}, Weight: -5, DeletePolicies: []string{"hook-succeeded", "hook-failed"}},
+		},
+		{
+			Name:        "invalid weight",
+			Annotations: map[string]string{"helm.sh/hook": "post-install", "helm.sh/hook-weight": "first"},
+			Expected:    &HelmHook{Phases: []string{"post-install"}, DeletePolicies: []string{"before-hook-creation"}},
+			IsError:     true,
+		},
+	} {
+		hook, err := ParseHelmHook(testCase.Annotations)
+		if (err != nil) != testCase.IsError {
+			t.Errorf("%s: expected error %t, found %+v", testCase.Name, testCase.IsError, err)
+		}
+		if !reflect.DeepEqual(hook, testCase.Expected) {
+			t.Errorf("%s: expected %+v, found %+v", testCase.Name, testCase.Expected, hook)
+		}
+	}
+}
+
+func TestSortForHelm(t *testing.T) {
+	hook := func(weight int) *HelmHook { return &HelmHook{Phases: []string{"pre-install"}, Weight: weight} }
+	resources := []*Resource{
+		{Kind: "Job", Name: "migrate", Hook: hook(5)},
+		{Kind: "Deployment", Name: "b"},
+		{Kind: "Widget", Name: "a"},
+		{Kind: "Secret", Name: "creds", Hook: hook(-10)},
+		{Kind: "Deployment", Name: "a"},
+		{Kind: "ConfigMap", Name: "settings"},
+		{Kind: "Job", Name: "early", Hook: hook(-2)},
+	}
+	var found []string
+	for _, resource := range sortForHelm(resources) {
+		found = append(found, resource.String())
+	}
+	expected := []string{"Secret/creds", "Job/early", "ConfigMap/settings", "Deployment/a", "Deployment/b", "Widget/a", "Job/migrate"}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected %v, found %v", expected, found)
+	}
+}
+
+func TestInstallOrderProblems(t *testing.T) {
+	model := parseTestModel(t, `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: bootstrap
+  annotations:
+    helm.sh/hook: pre-install
+    helm.sh/hook-delete-policy: hook-succeeded
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-install
+    helm.sh/hook-weight: "1"
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          envFrom:
+            - secretRef:
+                name: db
+            - configMapRef:
+                name: settings
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          envFrom:
+            - secretRef:
+                name: bootstrap
+            - secretRef:
+                name: db
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: smoke
+  annotations:
+    helm.sh/hook: post-install
+    helm.sh/hook-weight: soon
+`)
+	var found []string
+	for _, problem := range model.InstallOrder("install").Problems {
+		found = append(found, problem.Resource+": "+problem.Problem)
+	}
+	expected := []string{
+		"Job/migrate: uses ConfigMap/settings, which is created later, in step 3 (main release)",
+		"Job/migrate: uses Secret/db, which is created later, in step 3 (main release)",
+		"Deployment/app: uses Secret/bootstrap, which is deleted after its pre-install hook succeeds",
+		"Job/smoke: invalid helm.sh/hook-weight annotation 'soon'; helm treats it as 0",
+	}
+	if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\nfound\n%s", strings.Join(expected, "\n"), strings.Join(found, "\n"))
+	}
+}
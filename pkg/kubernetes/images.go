@@ -0,0 +1,294 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/collections/pkg/set"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/mattfenwick/collections/pkg/yaml"
+	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"regexp"
+	"strings"
+)
+
+const (
+	dockerHubRegistry  = "docker.io"
+	dockerHubNamespace = "library"
+	defaultImageTag    = "latest"
+)
+
+// ImageReference is a parsed container image, normalized the way docker and
+// kubernetes do: `nginx` is `docker.io/library/nginx:latest`
+type ImageReference struct {
+	Raw        string `json:"raw"`
+	Registry   string `json:"registry"`
+	Repository string `json:"repository"`
+	// Tag is empty when only a digest is given
+	Tag    string `json:"tag,omitempty"`
+	Digest string `json:"digest,omitempty"`
+}
+
+var (
+	imageDigestRegex = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$`)
+	imageTagRegex    = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127}$`)
+	imagePathRegex   = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+)
+
+func ParseImageReference(raw string) (*ImageReference, error) {
+	image := &ImageReference{Raw: raw}
+	rest := raw
+	if at := strings.Index(rest, "@"); at >= 0 {
+		rest, image.Digest = rest[:at], rest[at+1:]
+		if !imageDigestRegex.MatchString(image.Digest) {
+			return nil, errors.Errorf("invalid digest '%s' in image '%s'", image.Digest, raw)
+		}
+	}
+	if colon := strings.LastIndex(rest, ":"); colon > strings.LastIndex(rest, "/") {
+		rest, image.Tag = rest[:colon], rest[colon+1:]
+		if !imageTagRegex.MatchString(image.Tag) {
+			return nil, errors.Errorf("invalid tag '%s' in image '%s'", image.Tag, raw)
+		}
+	}
+
+	// the first component is a registry if it looks like a host
+	image.Registry, image.Repository = dockerHubRegistry, rest
+	if slash := strings.Index(rest, "/"); slash >= 0 {
+		first := rest[:slash]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			image.Registry, image.Repository = first, rest[slash+1:]
+		}
+	}
+	if image.Registry == "index.docker.io" {
+		image.Registry = dockerHubRegistry
+	}
+	if image.Registry == dockerHubRegistry && !strings.Contains(image.Repository, "/") {
+		image.Repository = fmt.Sprintf("%s/%s", dockerHubNamespace, image.Repository)
+	}
+	if !imagePathRegex.MatchString(image.Repository) {
+		return nil, errors.Errorf("invalid repository '%s' in image '%s'", image.Repository, raw)
+	}
+
+	if image.Tag == "" && image.Digest == "" {
+		image.Tag = defaultImageTag
+	}
+	return image, nil
+}
+
+// Name is the registry and repository: `docker.io/library/nginx`
+func (i *ImageReference) Name() string {
+	return fmt.Sprintf("%s/%s", i.Registry, i.Repository)
+}
+
+// Version is the tag and/or digest: `1.23`, `sha256:abc...`, `1.23@sha256:abc...`
+func (i *ImageReference) Version() string {
+	switch {
+	case i.Digest == "":
+		return i.Tag
+	case i.Tag == "":
+		return i.Digest
+	default:
+		return fmt.Sprintf("%s@%s", i.Tag, i.Digest)
+	}
+}
+
+// String is the fully qualified reference
+func (i *ImageReference) String() string {
+	s := i.Name()
+	if i.Tag != "" {
+		s += ":" + i.Tag
+	}
+	if i.Digest != "" {
+		s += "@" + i.Digest
+	}
+	return s
+}
+
+type ImageInventoryItem struct {
+	Image  *ImageReference `json:"image"`
+	Usages []string        `json:"usages"`
+}
+
+// ImageInventory groups GetImageUsages by normalized reference, so that
+// `nginx` and `docker.io/library/nginx:latest` are one image.  It fails on the
+// first image which can't be parsed; see ParseImageInventory.
+func (m *Model) ImageInventory() ([]*ImageInventoryItem, error) {
+	inventory, invalid := m.ParseImageInventory()
+	if len(invalid) > 0 {
+		return nil, errors.New(invalid[0].Details)
+	}
+	return inventory, nil
+}
+
+// ParseImageInventory is ImageInventory, except that images which can't be parsed
+// are returned as violations, rather than stopping the report.
+func (m *Model) ParseImageInventory() ([]*ImageInventoryItem, []*ImageViolation) {
+	items := map[string]*ImageInventoryItem{}
+	var invalid []*ImageViolation
+	for raw, usages := range m.GetImageUsages() {
+		image, err := ParseImageReference(raw)
+		if err != nil {
+			invalid = append(invalid, &ImageViolation{Rule: ImageViolationRuleInvalid, Image: raw, Usages: slice.Sort(usages), Details: err.Error()})
+			continue
+		}
+		key := image.String()
+		if _, ok := items[key]; !ok {
+			items[key] = &ImageInventoryItem{Image: image}
+		}
+		items[key].Usages = append(items[key].Usages, usages...)
+	}
+	var inventory []*ImageInventoryItem
+	for _, key := range slice.Sort(maps.Keys(items)) {
+		items[key].Usages = slice.Sort(items[key].Usages)
+		inventory = append(inventory, items[key])
+	}
+	return inventory, slice.SortOn(func(v *ImageViolation) string { return v.Image }, invalid)
+}
+
+type ImagePolicy struct {
+	// AllowedRegistries may also include a repository prefix: `gcr.io/my-project`
+	AllowedRegistries []string
+	RequireDigest     bool
+	DisallowLatest    bool
+	// RequireConsistentVersions reports repositories used with more than one
+	// tag or digest
+	RequireConsistentVersions bool
+}
+
+type ImageViolationRule string
+
+const (
+	ImageViolationRuleRegistry      ImageViolationRule = "registry not allowed"
+	ImageViolationRuleDigest        ImageViolationRule = "missing digest"
+	ImageViolationRuleLatest        ImageViolationRule = "latest tag"
+	ImageViolationRuleInconsistency ImageViolationRule = "inconsistent versions"
+	ImageViolationRuleInvalid       ImageViolationRule = "invalid reference"
+)
+
+type ImageViolation struct {
+	Rule    ImageViolationRule `json:"rule"`
+	Image   string             `json:"image"`
+	Usages  []string           `json:"usages"`
+	Details string             `json:"details,omitempty"`
+}
+
+func (p *ImagePolicy) isAllowedRegistry(image *ImageReference) bool {
+	if len(p.AllowedRegistries) == 0 {
+		return true
+	}
+	for _, allowed := range p.AllowedRegistries {
+		allowed = strings.TrimSuffix(allowed, "/")
+		if image.Registry == allowed || strings.HasPrefix(image.Name(), allowed+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Check applies the policy to each image
+func (p *ImagePolicy) Check(inventory []*ImageInventoryItem) []*ImageViolation {
+	var violations []*ImageViolation
+	for _, item := range inventory {
+		image := item.Image
+		if !p.isAllowedRegistry(image) {
+			violations = append(violations, &ImageViolation{Rule: ImageViolationRuleRegistry, Image: image.String(), Usages: item.Usages, Details: fmt.Sprintf("allowed: %s", strings.Join(p.AllowedRegistries, ", "))})
+		}
+		if p.RequireDigest && image.Digest == "" {
+			violations = append(violations, &ImageViolation{Rule: ImageViolationRuleDigest, Image: image.String(), Usages: item.Usages})
+		}
+		if p.DisallowLatest && image.Tag == defaultImageTag && image.Digest == "" {
+			violations = append(violations, &ImageViolation{Rule: ImageViolationRuleLatest, Image: image.String(), Usages: item.Usages, Details: fmt.Sprintf("raw: %s", image.Raw)})
+		}
+	}
+	if p.RequireConsistentVersions {
+		violations = append(violations, InconsistentImageVersions(inventory)...)
+	}
+	return violations
+}
+
+// InconsistentImageVersions finds repositories pinned to more than one tag or
+// digest
+func InconsistentImageVersions(inventory []*ImageInventoryItem) []*ImageViolation {
+	byName := map[string][]*ImageInventoryItem{}
+	for _, item := range inventory {
+		byName[item.Image.Name()] = append(byName[item.Image.Name()], item)
+	}
+	var violations []*ImageViolation
+	for _, name := range slice.Sort(maps.Keys(byName)) {
+		items := byName[name]
+		if len(items) < 2 {
+			continue
+		}
+		var usages, versions []string
+		for _, item := range items {
+			versions = append(versions, item.Image.Version())
+			for _, usage := range item.Usages {
+				usages = append(usages, fmt.Sprintf("%s (%s)", usage, item.Image.Version()))
+			}
+		}
+		violations = append(violations, &ImageViolation{
+			Rule:    ImageViolationRuleInconsistency,
+			Image:   name,
+			Usages:  usages,
+			Details: fmt.Sprintf("versions: %s", strings.Join(slice.Sort(set.FromSlice(versions).ToSlice()), ", ")),
+		})
+	}
+	return violations
+}
+
+func ImageInventoryTable(inventory []*ImageInventoryItem) string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetAutoMergeCells(true)
+	table.SetHeader([]string{"Registry", "Repository", "Tag", "Digest", "Usages"})
+	for _, item := range inventory {
+		table.Append([]string{item.Image.Registry, item.Image.Repository, item.Image.Tag, item.Image.Digest, strings.Join(item.Usages, "\n")})
+	}
+	table.Render()
+	return tableString.String()
+}
+
+func ImageViolationsTable(violations []*ImageViolation) string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetAutoMergeCells(true)
+	table.SetHeader([]string{"Rule", "Image", "Details", "Usages"})
+	for _, violation := range violations {
+		table.Append([]string{string(violation.Rule), violation.Image, violation.Details, strings.Join(violation.Usages, "\n")})
+	}
+	table.Render()
+	return tableString.String()
+}
+
+type ImageReportArgs struct {
+	ChartPath string
+	Policy    *ImagePolicy
+	Output    string
+}
+
+func RunImageReport(args *ImageReportArgs) {
+	objs, err := yaml.ParseManyFromFile[map[string]interface{}](args.ChartPath)
+	utils.DoOrDie(err)
+	inventory, invalid := NewModelFromYaml(objs).ParseImageInventory()
+	violations := append(invalid, args.Policy.Check(inventory)...)
+
+	switch args.Output {
+	case "table":
+		fmt.Printf("images:\n%s\n\n", ImageInventoryTable(inventory))
+		fmt.Printf("image policy violations:\n%s\n\n", ImageViolationsTable(violations))
+	case "json":
+		json.PrintOptions(map[string]interface{}{"images": inventory, "violations": violations}, &json.MarshalOptions{EscapeHTML: false, Indent: true, Sort: false})
+	default:
+		utils.DoOrDie(errors.Errorf("invalid output format '%s'", args.Output))
+	}
+
+	if len(violations) > 0 {
+		utils.DoOrDie(errors.Errorf("found %d image policy violations", len(violations)))
+	}
+}
@@ -0,0 +1,67 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseImageReference(t *testing.T) {
+	digest := "sha256:4c0fdaa8b6341bfdeca5f18f7837462c80cff90527ee35ef185571e1c327beac"
+	for _, testCase := range []struct {
+		Raw      string
+		Expected *ImageReference
+	}{
+		{Raw: "nginx", Expected: &ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}},
+		{Raw: "nginx:1.23", Expected: &ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.23"}},
+		{Raw: "bitnami/redis:7.0", Expected: &ImageReference{Registry: "docker.io", Repository: "bitnami/redis", Tag: "7.0"}},
+		{Raw: "index.docker.io/nginx", Expected: &ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}},
+		{Raw: "docker.io/library/nginx:1.23", Expected: &ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.23"}},
+		// a port belongs to the registry, not the tag
+		{Raw: "localhost:5000/app", Expected: &ImageReference{Registry: "localhost:5000", Repository: "app", Tag: "latest"}},
+		{Raw: "registry.example.com:5000/team/app:2.1", Expected: &ImageReference{Registry: "registry.example.com:5000", Repository: "team/app", Tag: "2.1"}},
+		{Raw: "team/app:5000", Expected: &ImageReference{Registry: "docker.io", Repository: "team/app", Tag: "5000"}},
+		{Raw: "nginx@" + digest, Expected: &ImageReference{Registry: "docker.io", Repository: "library/nginx", Digest: digest}},
+		{Raw: "quay.io/org/app:1.0@" + digest, Expected: &ImageReference{Registry: "quay.io", Repository: "org/app", Tag: "1.0", Digest: digest}},
+		// tags may be uppercase; repositories may not
+		{Raw: "nginx:V1", Expected: &ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "V1"}},
+		{Raw: "Nginx"},
+		{Raw: "registry.example.com/Team/app"},
+		{Raw: ""},
+		{Raw: "nginx:"},
+		{Raw: "nginx:bad tag"},
+		{Raw: "nginx@sha256"},
+		{Raw: "nginx@sha256:"},
+		{Raw: "registry.example.com/"},
+	} {
+		image, err := ParseImageReference(testCase.Raw)
+		if testCase.Expected == nil {
+			if err == nil {
+				t.Errorf("%q: expected error, found %+v", testCase.Raw, image)
+			}
+			continue
+		}
+		testCase.Expected.Raw = testCase.Raw
+		if err != nil {
+			t.Errorf("%q: unexpected error: %+v", testCase.Raw, err)
+		} else if !reflect.DeepEqual(image, testCase.Expected) {
+			t.Errorf("%q: expected %+v, found %+v", testCase.Raw, testCase.Expected, image)
+		}
+	}
+}
+
+func TestImageReferenceString(t *testing.T) {
+	for raw, expected := range map[string]string{
+		"nginx":                      "docker.io/library/nginx:latest",
+		"localhost:5000/app":         "localhost:5000/app:latest",
+		"nginx@sha256:abc123":        "docker.io/library/nginx@sha256:abc123",
+		"nginx:1.23@sha256:abc123":   "docker.io/library/nginx:1.23@sha256:abc123",
+		"ghcr.io/org/tool:v2.0.0-rc": "ghcr.io/org/tool:v2.0.0-rc",
+	} {
+		image, err := ParseImageReference(raw)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %+v", raw, err)
+		} else if image.String() != expected {
+			t.Errorf("%q: expected %s, found %s", raw, expected, image.String())
+		}
+	}
+}
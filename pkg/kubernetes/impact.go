@@ -0,0 +1,123 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/mattfenwick/collections/pkg/yaml"
+	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/exp/maps"
+	"strings"
+)
+
+// Impacted is a node which depends on a changed resource, through Path: the
+// node IDs from it to the changed resource
+type Impacted struct {
+	ID    string   `json:"id"`
+	Class string   `json:"class"`
+	Path  []string `json:"path"`
+}
+
+type ImpactReport struct {
+	Resource   string      `json:"resource"`
+	Workloads  []*Impacted `json:"workloads"`
+	Containers []*Impacted `json:"containers"`
+	// Others are other dependents, such as service accounts of a changed role
+	Others []*Impacted `json:"others"`
+}
+
+// findGraphNode looks up `<kind>/<name>`, matching kind case-insensitively as
+// kubectl does: `secret/my-secret`
+func findGraphNode(ids []string, resource string) (string, error) {
+	for _, id := range ids {
+		if id == resource {
+			return id, nil
+		}
+	}
+	kind, name, ok := strings.Cut(resource, "/")
+	if ok {
+		for _, id := range ids {
+			idKind, idName, _ := strings.Cut(id, "/")
+			if strings.EqualFold(idKind, kind) && idName == name {
+				return id, nil
+			}
+		}
+	}
+	return "", errors.Errorf("resource '%s' not found; expected <kind>/<name>, such as Secret/my-secret", resource)
+}
+
+// Impact finds the workloads and containers affected by changing a resource:
+// those which transitively depend on it in Graph
+func (m *Model) Impact(resource string) (*ImpactReport, error) {
+	g := m.Graph()
+	id, err := findGraphNode(g.NodeIDs(), resource)
+	if err != nil {
+		return nil, err
+	}
+	if cycles := g.Cycles(); len(cycles) > 0 {
+		logrus.Warnf("dependency graph has cycles: %v", cycles)
+	}
+
+	report := &ImpactReport{Resource: id}
+	paths := g.DependentPaths(id)
+	for _, dependent := range slice.Sort(maps.Keys(paths)) {
+		class := ""
+		if node, ok := g.FindNode(dependent); ok {
+			class = node.Class
+		}
+		impacted := &Impacted{ID: dependent, Class: class, Path: paths[dependent]}
+		switch class {
+		case NodeClassWorkload:
+			report.Workloads = append(report.Workloads, impacted)
+		case NodeClassContainer, NodeClassInitContainer:
+			report.Containers = append(report.Containers, impacted)
+		default:
+			report.Others = append(report.Others, impacted)
+		}
+	}
+	return report, nil
+}
+
+func (r *ImpactReport) Table() string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetAutoMergeCellsByColumnIndex([]int{0})
+	table.SetHeader([]string{"Type", "Resource", "Path"})
+	add := func(impactType string, impacted []*Impacted) {
+		for _, i := range impacted {
+			table.Append([]string{impactType, i.ID, strings.Join(i.Path, " -> ")})
+		}
+	}
+	add("workload", r.Workloads)
+	add("container", r.Containers)
+	add("other", r.Others)
+	table.Render()
+	return tableString.String()
+}
+
+type ImpactArgs struct {
+	ChartPath string
+	Resource  string
+	Output    string
+}
+
+func RunImpact(args *ImpactArgs) {
+	objs, err := yaml.ParseManyFromFile[map[string]interface{}](args.ChartPath)
+	utils.DoOrDie(err)
+	report, err := NewModelFromYaml(objs).Impact(args.Resource)
+	utils.DoOrDie(err)
+
+	switch args.Output {
+	case "table":
+		fmt.Printf("impact of changing %s:\n%s\n", report.Resource, report.Table())
+	case "json":
+		json.PrintOptions(report, &json.MarshalOptions{EscapeHTML: false, Indent: true, Sort: false})
+	default:
+		utils.DoOrDie(errors.Errorf("invalid output format '%s'", args.Output))
+	}
+}
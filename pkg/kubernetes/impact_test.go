@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+)
+
+const impactTestYaml = `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: reader
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: reader-binding
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: reader
+subjects:
+  - kind: ServiceAccount
+    name: app
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      serviceAccountName: app
+      containers:
+        - name: web
+          image: web
+          envFrom:
+            - secretRef:
+                name: db
+`
+
+func describeImpacted(impacted []*Impacted) map[string][]string {
+	described := map[string][]string{}
+	for _, i := range impacted {
+		described[i.ID] = i.Path
+	}
+	return described
+}
+
+func TestImpact(t *testing.T) {
+	model := parseTestModel(t, impactTestYaml)
+	for _, testCase := range []struct {
+		Resource   string
+		ID         string
+		Workloads  map[string][]string
+		Containers map[string][]string
+		Others     map[string][]string
+	}{
+		{
+			Resource:   "Role/reader",
+			ID:         "Role/reader",
+			Workloads:  map[string][]string{"Deployment/web": {"Deployment/web", "ServiceAccount/app", "RoleBinding/reader-binding", "Role/reader"}},
+			Containers: map[string][]string{},
+			Others: map[string][]string{
+				"RoleBinding/reader-binding": {"RoleBinding/reader-binding", "Role/reader"},
+				"ServiceAccount/app":         {"ServiceAccount/app", "RoleBinding/reader-binding", "Role/reader"},
+			},
+		},
+		{
+			// kinds match case-insensitively, as with kubectl
+			Resource:   "secret/db",
+			ID:         "Secret/db",
+			Workloads:  map[string][]string{"Deployment/web": {"Deployment/web", "Deployment/web/web", "Secret/db"}},
+			Containers: map[string][]string{"Deployment/web/web": {"Deployment/web/web", "Secret/db"}},
+			Others:     map[string][]string{},
+		},
+	} {
+		report, err := model.Impact(testCase.Resource)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %+v", testCase.Resource, err)
+			continue
+		}
+		if report.Resource != testCase.ID {
+			t.Errorf("%s: expected %s, found %s", testCase.Resource, testCase.ID, report.Resource)
+		}
+		for name, pair := range map[string][2]map[string][]string{
+			"workloads":  {testCase.Workloads, describeImpacted(report.Workloads)},
+			"containers": {testCase.Containers, describeImpacted(report.Containers)},
+			"others":     {testCase.Others, describeImpacted(report.Others)},
+		} {
+			if !reflect.DeepEqual(pair[0], pair[1]) {
+				t.Errorf("%s: expected %s %v, found %v", testCase.Resource, name, pair[0], pair[1])
+			}
+		}
+	}
+}
+
+func TestFindGraphNode(t *testing.T) {
+	ids := []string{"Secret/db", "secret/exact", "ConfigMap/settings"}
+	for _, testCase := range []struct {
+		Resource string
+		Expected string
+	}{
+		{Resource: "Secret/db", Expected: "Secret/db"},
+		{Resource: "secret/db", Expected: "Secret/db"},
+		{Resource: "SECRET/db", Expected: "Secret/db"},
+		{Resource: "secret/exact", Expected: "secret/exact"},
+		{Resource: "Secret/DB"},
+		{Resource: "db"},
+		{Resource: "Secret/missing"},
+	} {
+		found, err := findGraphNode(ids, testCase.Resource)
+		if testCase.Expected == "" {
+			if err == nil {
+				t.Errorf("%s: expected error, found %s", testCase.Resource, found)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %+v", testCase.Resource, err)
+		} else if found != testCase.Expected {
+			t.Errorf("%s: expected %s, found %s", testCase.Resource, testCase.Expected, found)
+		}
+	}
+}
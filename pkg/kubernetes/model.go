@@ -4,11 +4,15 @@ import (
 	"fmt"
 	"github.com/mattfenwick/collections/pkg/set"
 	"github.com/mattfenwick/collections/pkg/slice"
-	"github.com/mattfenwick/kube-utils/pkg/graph"
 	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/exp/maps"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"strings"
 )
 
 type Container struct {
@@ -16,7 +20,16 @@ type Container struct {
 	Name       string
 	ConfigMaps *set.Set[string]
 	Secrets    *set.Set[string]
-	Image      string
+	// MountedConfigMaps and MountedSecrets are those used as volumes, rather
+	// than as env vars
+	MountedConfigMaps *set.Set[string]
+	MountedSecrets    *set.Set[string]
+	Image             string
+	Requests          v1.ResourceList
+	Limits            v1.ResourceList
+	Env               []*EnvVar
+	Command           []string
+	Args              []string
 }
 
 func (c *Container) SecretsSlice() []string {
@@ -28,29 +41,56 @@ func (c *Container) ConfigMapsSlice() []string {
 }
 
 type PodSpec struct {
+	Namespace string
+	// Replicas is the number of pods run at once: a Deployment or StatefulSet's
+	// replicas, or a Job's parallelism
+	Replicas         int32
 	Containers       []*Container
 	ServiceAccount   string
 	ImagePullSecrets []string
-	// TODO env vars
 }
 
 type Model struct {
 	Pods       map[string]map[string]*PodSpec
 	Secrets    []string
 	ConfigMaps []string
-	Skipped    map[string][]string
+	// SecretKeys and ConfigMapKeys are the data keys of secrets and config maps
+	// in the chart, for resolving envFrom
+	SecretKeys    map[string][]string
+	ConfigMapKeys map[string][]string
+	// SecretProducers are resources -- such as ExternalSecrets -- from which
+	// operators create secrets, by secret name
+	SecretProducers map[string][]*SecretProducer
+	// Resources are all objects, including skipped ones, with their hook
+	// annotations and ownerReferences
+	Resources []*Resource
+	// RoleBindings are RoleBindings and ClusterRoleBindings, which grant
+	// service accounts permissions
+	RoleBindings []*RoleBinding
+	Skipped      map[string][]string
 }
 
 func NewModel() *Model {
 	return &Model{
-		Pods:       map[string]map[string]*PodSpec{},
-		Secrets:    nil,
-		ConfigMaps: nil,
-		Skipped:    map[string][]string{},
+		Pods:            map[string]map[string]*PodSpec{},
+		Secrets:         nil,
+		ConfigMaps:      nil,
+		SecretKeys:      map[string][]string{},
+		ConfigMapKeys:   map[string][]string{},
+		SecretProducers: map[string][]*SecretProducer{},
+		Skipped:         map[string][]string{},
 	}
 }
 
 func NewModelFromYaml(objs []map[string]interface{}) *Model {
+	model, err := ParseModel(objs)
+	utils.DoOrDie(err)
+	return model
+}
+
+// ParseModel is NewModelFromYaml, returning an error for objects which don't
+// parse into their kind's type
+func ParseModel(objs []map[string]interface{}) (*Model, error) {
 	model := NewModel()
 	for _, m := range slice.SortOn(getResourceName, objs) {
 		if m == nil {
@@ -60,32 +100,73 @@ func NewModelFromYaml(objs []map[string]interface{}) *Model {
 		resourceName := getResourceName(m)
 		kind := m["kind"].(string)
 		logrus.Debugf("kind, name: %s, %s\n", kind, resourceName)
+		model.Resources = append(model.Resources, AnalyzeResource(m))
 		switch kind {
 		case "Deployment":
 			dep, err := ParseObjectIntoType[appsv1.Deployment](m)
-			utils.DoOrDie(err)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "unable to parse %s/%s", kind, resourceName)
+			}
 			model.AddPodWrapper("Deployment", dep.Name, AnalyzeDeployment(dep))
 		case "StatefulSet":
 			sset, err := ParseObjectIntoType[appsv1.StatefulSet](m)
-			utils.DoOrDie(err)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "unable to parse %s/%s", kind, resourceName)
+			}
 			model.AddPodWrapper("StatefulSet", sset.Name, AnalyzeStatefulSet(sset))
 		case "Job":
 			job, err := ParseObjectIntoType[batchv1.Job](m)
-			utils.DoOrDie(err)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "unable to parse %s/%s", kind, resourceName)
+			}
 			model.AddPodWrapper("Job", job.Name, AnalyzeJob(job))
 		case "CronJob":
 			cj, err := ParseObjectIntoType[batchv1.CronJob](m)
-			utils.DoOrDie(err)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "unable to parse %s/%s", kind, resourceName)
+			}
 			model.AddPodWrapper("CronJob", cj.Name, AnalyzeCronJob(cj))
 		case "Secret":
 			model.Secrets = append(model.Secrets, resourceName)
+			model.SecretKeys[resourceName] = dataKeys(m, "data", "stringData")
 		case "ConfigMap":
 			model.ConfigMaps = append(model.ConfigMaps, resourceName)
+			model.ConfigMapKeys[resourceName] = dataKeys(m, "data", "binaryData")
+		case "RoleBinding":
+			binding, err := ParseObjectIntoType[rbacv1.RoleBinding](m)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "unable to parse %s/%s", kind, resourceName)
+			}
+			model.RoleBindings = append(model.RoleBindings, AnalyzeRoleBinding(kind, binding.Name, binding.RoleRef, binding.Subjects))
+		case "ClusterRoleBinding":
+			binding, err := ParseObjectIntoType[rbacv1.ClusterRoleBinding](m)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "unable to parse %s/%s", kind, resourceName)
+			}
+			model.RoleBindings = append(model.RoleBindings, AnalyzeRoleBinding(kind, binding.Name, binding.RoleRef, binding.Subjects))
+		case "ExternalSecret", "SealedSecret", "Certificate":
+			if producer := AnalyzeSecretProducer(m); producer != nil {
+				model.AddSecretProducer(producer)
+			} else {
+				model.AddSkippedResource(kind, resourceName)
+			}
 		default:
 			model.AddSkippedResource(kind, resourceName)
 		}
 	}
-	return model
+	return model, nil
+}
+
+// dataKeys reads the keys of a Secret or ConfigMap's data fields straight from
+// the object: parsing it into its type would fail on values which aren't valid
+// base64, or on extra fields such as sops metadata, neither of which matter here
+func dataKeys(obj map[string]interface{}, fields ...string) []string {
+	keys := []string{}
+	for _, field := range fields {
+		data, _ := obj[field].(map[string]interface{})
+		keys = append(keys, maps.Keys(data)...)
+	}
+	return slice.Sort(keys)
 }
 
 func (m *Model) AddSkippedResource(kind string, name string) {
@@ -95,6 +176,25 @@ func (m *Model) AddSkippedResource(kind string, name string) {
 	m.Skipped[kind] = append(m.Skipped[kind], name)
 }
 
+func (m *Model) AddSecretProducer(producer *SecretProducer) {
+	m.SecretProducers[producer.SecretName] = append(m.SecretProducers[producer.SecretName], producer)
+	if producer.Keys != nil {
+		m.SecretKeys[producer.SecretName] = producer.Keys
+	}
+}
+
+// SecretSource is where a secret comes from: "chart", the resources producing
+// it, or "unknown"
+func (m *Model) SecretSource(name string) string {
+	if slice.Any(func(secret string) bool { return secret == name }, m.Secrets) {
+		return "chart"
+	}
+	if producers, ok := m.SecretProducers[name]; ok {
+		return strings.Join(slice.Map(func(p *SecretProducer) string { return p.Source() }, producers), "\n")
+	}
+	return "unknown"
+}
+
 func (m *Model) AddPodWrapper(kind string, name string, spec *PodSpec) {
 	if _, ok := m.Pods[kind]; !ok {
 		m.Pods[kind] = map[string]*PodSpec{}
@@ -147,7 +247,8 @@ func (m *Model) GetUsedUnusedSecretsAndConfigMaps() (*KeySetComparison, *KeySetC
 			}
 		}
 	}
-	return CompareKeySets(set.FromSlice(m.Secrets), usedSecrets), CompareKeySets(set.FromSlice(m.ConfigMaps), usedConfigMaps)
+	definedSecrets := set.FromSlice(append(append([]string{}, m.Secrets...), maps.Keys(m.SecretProducers)...))
+	return CompareKeySets(definedSecrets, usedSecrets), CompareKeySets(set.FromSlice(m.ConfigMaps), usedConfigMaps)
 }
 
 func (m *Model) GetImageUsages() map[string][]string {
@@ -162,67 +263,3 @@ func (m *Model) GetImageUsages() map[string][]string {
 	}
 	return usages
 }
-
-func (m *Model) Graph() *graph.Graph {
-	yamlGraph := graph.NewGraph("", "")
-
-	secretsComparison, configMapsComparison := m.GetUsedUnusedSecretsAndConfigMaps()
-
-	secretsGraph := graph.NewGraph("secrets", "secrets")
-	unusedSecretsGraph := graph.NewGraph("unused secrets", "unused secrets")
-	unknownSourceSecretsGraph := graph.NewGraph("unknown source secrets", "unknown source secrets")
-	for _, secret := range secretsComparison.JustA {
-		unusedSecretsGraph.AddNode("secret: "+secret, fmt.Sprintf(`label="%s"`, secret))
-	}
-	for _, secret := range secretsComparison.Both {
-		secretsGraph.AddNode("secret: "+secret, fmt.Sprintf(`label="%s"`, secret))
-	}
-	for _, secret := range secretsComparison.JustB {
-		unknownSourceSecretsGraph.AddNode("secret: "+secret, fmt.Sprintf(`label="%s"`, secret))
-	}
-	yamlGraph.AddSubgraph(secretsGraph)
-	yamlGraph.AddSubgraph(unusedSecretsGraph)
-	yamlGraph.AddSubgraph(unknownSourceSecretsGraph)
-
-	cmsGraph := graph.NewGraph("configmaps", "configmaps")
-	unusedConfigMapsGraph := graph.NewGraph("unused configmaps", "unused configmaps")
-	unknownSourceConfigMapsGraph := graph.NewGraph("unknown source configmaps", "unknown source configmaps")
-	for _, secret := range configMapsComparison.JustA {
-		unusedConfigMapsGraph.AddNode("configmap: "+secret, fmt.Sprintf(`label="%s"`, secret))
-	}
-	for _, secret := range configMapsComparison.Both {
-		cmsGraph.AddNode("configmap: "+secret, fmt.Sprintf(`label="%s"`, secret))
-	}
-	for _, secret := range configMapsComparison.JustB {
-		unknownSourceConfigMapsGraph.AddNode("configmap: "+secret, fmt.Sprintf(`label="%s"`, secret))
-	}
-	yamlGraph.AddSubgraph(cmsGraph)
-	yamlGraph.AddSubgraph(unusedConfigMapsGraph)
-	yamlGraph.AddSubgraph(unknownSourceConfigMapsGraph)
-
-	for kind, objects := range m.Pods {
-		for name, spec := range objects {
-			resourceName := fmt.Sprintf("%s: %s", kind, name)
-			yamlGraph.AddNode(resourceName)
-			// TODO:
-			//spec.ServiceAccount
-			//spec.ImagePullSecrets
-			for _, container := range spec.Containers {
-				initPiece := ""
-				if container.IsInit {
-					initPiece = " (init)"
-				}
-				containerNodeName := fmt.Sprintf("%s: %s/%s%s", kind, name, container.Name, initPiece)
-				yamlGraph.AddNode(containerNodeName, fmt.Sprintf(`label="%s%s"`, container.Name, initPiece))
-				yamlGraph.AddEdge(resourceName, containerNodeName)
-				for _, cm := range container.ConfigMaps.ToSlice() {
-					yamlGraph.AddEdge(containerNodeName, "configmap: "+cm)
-				}
-				for _, secret := range container.Secrets.ToSlice() {
-					yamlGraph.AddEdge(containerNodeName, "secret: "+secret)
-				}
-			}
-		}
-	}
-	return yamlGraph
-}
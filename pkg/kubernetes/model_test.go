@@ -0,0 +1,49 @@
+package kubernetes
+
+import (
+	"github.com/mattfenwick/collections/pkg/yaml"
+	"reflect"
+	"testing"
+)
+
+func parseTestModel(t *testing.T, manifests string) *Model {
+	objs, err := yaml.ParseMany[map[string]interface{}]([]byte(manifests))
+	if err != nil {
+		t.Fatalf("unable to parse manifests: %+v", err)
+	}
+	model, err := ParseModel(objs)
+	if err != nil {
+		t.Fatalf("unable to build model: %+v", err)
+	}
+	return model
+}
+
+func TestParseModelReadsDataKeysLeniently(t *testing.T) {
+	model := parseTestModel(t, `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: encrypted
+data:
+  password: ENC[AES256_GCM,data:abc=,type:str]
+stringData:
+  username: admin
+sops:
+  version: 3.7.3
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  app.properties: debug=true
+binaryData:
+  logo.png: not base64!
+`)
+	if !reflect.DeepEqual(model.SecretKeys["encrypted"], []string{"password", "username"}) {
+		t.Errorf("expected secret keys [password username], found %+v", model.SecretKeys["encrypted"])
+	}
+	if !reflect.DeepEqual(model.ConfigMapKeys["config"], []string{"app.properties", "logo.png"}) {
+		t.Errorf("expected config map keys [app.properties logo.png], found %+v", model.ConfigMapKeys["config"])
+	}
+}
@@ -0,0 +1,325 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/collections/pkg/set"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/mattfenwick/collections/pkg/yaml"
+	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"strconv"
+	"strings"
+)
+
+// ResourceTotals are requests and limits for cpu, memory, ephemeral-storage
+// and extended resources such as `nvidia.com/gpu`
+type ResourceTotals struct {
+	Requests v1.ResourceList `json:"requests"`
+	Limits   v1.ResourceList `json:"limits"`
+	// UnboundedLimits are resources which some container doesn't limit, so that
+	// there's no total limit
+	UnboundedLimits []v1.ResourceName `json:"unboundedLimits,omitempty"`
+}
+
+func NewResourceTotals() *ResourceTotals {
+	return &ResourceTotals{Requests: v1.ResourceList{}, Limits: v1.ResourceList{}}
+}
+
+func (r *ResourceTotals) IsUnbounded(name v1.ResourceName) bool {
+	return slice.Any(func(n v1.ResourceName) bool { return n == name }, r.UnboundedLimits)
+}
+
+// SetUnbounded removes a limit: anything added to an unbounded limit is unbounded
+func (r *ResourceTotals) SetUnbounded(name v1.ResourceName) {
+	delete(r.Limits, name)
+	if !r.IsUnbounded(name) {
+		r.UnboundedLimits = slice.Sort(append(r.UnboundedLimits, name))
+	}
+}
+
+func (r *ResourceTotals) Add(other *ResourceTotals) {
+	addResources(r.Requests, other.Requests)
+	addResources(r.Limits, other.Limits)
+	for _, name := range other.UnboundedLimits {
+		r.SetUnbounded(name)
+	}
+	for _, name := range r.UnboundedLimits {
+		delete(r.Limits, name)
+	}
+}
+
+// Scale multiplies each total by count
+func (r *ResourceTotals) Scale(count int32) *ResourceTotals {
+	return &ResourceTotals{
+		Requests:        scaleResources(r.Requests, count),
+		Limits:          scaleResources(r.Limits, count),
+		UnboundedLimits: append([]v1.ResourceName{}, r.UnboundedLimits...),
+	}
+}
+
+func (r *ResourceTotals) ResourceNames() []v1.ResourceName {
+	return append(append(maps.Keys(r.Requests), maps.Keys(r.Limits)...), r.UnboundedLimits...)
+}
+
+func addResources(total v1.ResourceList, other v1.ResourceList) {
+	for name, quantity := range other {
+		sum := total[name]
+		sum.Add(quantity)
+		total[name] = sum
+	}
+}
+
+func maxResources(max v1.ResourceList, other v1.ResourceList) {
+	for name, quantity := range other {
+		if current, ok := max[name]; !ok || quantity.Cmp(current) > 0 {
+			max[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+func scaleResources(resources v1.ResourceList, count int32) v1.ResourceList {
+	scaled := v1.ResourceList{}
+	for name, quantity := range resources {
+		scaled[name] = scaleQuantity(quantity, count)
+	}
+	return scaled
+}
+
+// scaleQuantity multiplies by repeated doubling and adding, since Quantity.Add
+// switches to arbitrary precision rather than overflowing
+func scaleQuantity(quantity resource.Quantity, count int32) resource.Quantity {
+	scaled := resource.Quantity{Format: quantity.Format}
+	power := quantity.DeepCopy()
+	for n := count; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			scaled.Add(power)
+		}
+		power.Add(power.DeepCopy())
+	}
+	return scaled
+}
+
+// ContainerResources applies kubernetes' defaulting: a limit without a request
+// also sets the request
+func ContainerResources(container *Container) *ResourceTotals {
+	totals := NewResourceTotals()
+	addResources(totals.Limits, container.Limits)
+	addResources(totals.Requests, container.Requests)
+	for name, limit := range container.Limits {
+		if _, ok := container.Requests[name]; !ok {
+			totals.Requests[name] = limit.DeepCopy()
+		}
+	}
+	return totals
+}
+
+// PodResources is what the scheduler reserves for a pod: for each resource, the
+// larger of the sum over app containers and the largest init container, as
+// init containers run one at a time before the app containers start.  A cpu,
+// memory or ephemeral-storage limit is unbounded unless every container, init
+// containers included, sets it; containers not setting an extended resource
+// don't get any.
+func PodResources(spec *PodSpec) *ResourceTotals {
+	containers, initContainers := NewResourceTotals(), NewResourceTotals()
+	for _, container := range spec.Containers {
+		resources := ContainerResources(container)
+		if container.IsInit {
+			maxResources(initContainers.Requests, resources.Requests)
+			maxResources(initContainers.Limits, resources.Limits)
+		} else {
+			containers.Add(resources)
+		}
+	}
+	maxResources(containers.Requests, initContainers.Requests)
+	maxResources(containers.Limits, initContainers.Limits)
+	for _, container := range spec.Containers {
+		for _, name := range standardResourceNames {
+			if _, ok := container.Limits[name]; !ok {
+				containers.SetUnbounded(name)
+			}
+		}
+	}
+	return containers
+}
+
+type WorkloadResources struct {
+	Kind      string          `json:"kind"`
+	Namespace string          `json:"namespace"`
+	Name      string          `json:"name"`
+	Replicas  int32           `json:"replicas"`
+	PerPod    *ResourceTotals `json:"perPod"`
+	Total     *ResourceTotals `json:"total"`
+}
+
+type ResourceReport struct {
+	Workloads  []*WorkloadResources       `json:"workloads"`
+	Namespaces map[string]*ResourceTotals `json:"namespaces"`
+	Overall    *ResourceTotals            `json:"overall"`
+}
+
+func (m *Model) ResourceReport() *ResourceReport {
+	report := &ResourceReport{Namespaces: map[string]*ResourceTotals{}, Overall: NewResourceTotals()}
+	for _, kind := range slice.Sort(maps.Keys(m.Pods)) {
+		for _, name := range slice.Sort(maps.Keys(m.Pods[kind])) {
+			spec := m.Pods[kind][name]
+			perPod := PodResources(spec)
+			workload := &WorkloadResources{
+				Kind:      kind,
+				Namespace: spec.Namespace,
+				Name:      name,
+				Replicas:  spec.Replicas,
+				PerPod:    perPod,
+				Total:     perPod.Scale(spec.Replicas),
+			}
+			report.Workloads = append(report.Workloads, workload)
+			if _, ok := report.Namespaces[spec.Namespace]; !ok {
+				report.Namespaces[spec.Namespace] = NewResourceTotals()
+			}
+			report.Namespaces[spec.Namespace].Add(workload.Total)
+			report.Overall.Add(workload.Total)
+		}
+	}
+	return report
+}
+
+const unboundedLimit = "unbounded"
+
+var standardResourceNames = []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory, v1.ResourceEphemeralStorage}
+
+// ResourceNames lists the standard resources, followed by any extended resources
+// found, sorted
+func (r *ResourceReport) ResourceNames() []v1.ResourceName {
+	extended := set.FromSlice(r.Overall.ResourceNames())
+	for _, name := range standardResourceNames {
+		extended.Delete(name)
+	}
+	return append(append([]v1.ResourceName{}, standardResourceNames...), slice.Sort(extended.ToSlice())...)
+}
+
+// reportRow is a row of the table and csv outputs: a workload, namespace, or
+// the overall total
+type reportRow struct {
+	Scope     string
+	Kind      string
+	Namespace string
+	Name      string
+	Replicas  string
+	Totals    *ResourceTotals
+}
+
+func (r *ResourceReport) rows() []*reportRow {
+	var rows []*reportRow
+	for _, w := range r.Workloads {
+		rows = append(rows, &reportRow{Scope: "workload", Kind: w.Kind, Namespace: namespaceLabel(w.Namespace), Name: w.Name, Replicas: fmt.Sprintf("%d", w.Replicas), Totals: w.Total})
+	}
+	for _, namespace := range slice.Sort(maps.Keys(r.Namespaces)) {
+		rows = append(rows, &reportRow{Scope: "namespace", Namespace: namespaceLabel(namespace), Totals: r.Namespaces[namespace]})
+	}
+	return append(rows, &reportRow{Scope: "overall", Totals: r.Overall})
+}
+
+// namespaceLabel shows objects without a namespace, which are created in
+// whatever namespace is used at deploy time
+func namespaceLabel(namespace string) string {
+	if namespace == "" {
+		return "(unset)"
+	}
+	return namespace
+}
+
+func (r *ResourceReport) header() []string {
+	header := []string{"Scope", "Kind", "Namespace", "Name", "Replicas"}
+	for _, name := range r.ResourceNames() {
+		header = append(header, fmt.Sprintf("%s request", name), fmt.Sprintf("%s limit", name))
+	}
+	return header
+}
+
+// Table shows totals -- accounting for replicas -- as kubernetes quantities
+func (r *ResourceReport) Table() string {
+	formatQuantity := func(resources v1.ResourceList, name v1.ResourceName) string {
+		if quantity, ok := resources[name]; ok {
+			return quantity.String()
+		}
+		return ""
+	}
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetHeader(r.header())
+	for _, row := range r.rows() {
+		line := []string{row.Scope, row.Kind, row.Namespace, row.Name, row.Replicas}
+		for _, name := range r.ResourceNames() {
+			limit := formatQuantity(row.Totals.Limits, name)
+			if row.Totals.IsUnbounded(name) {
+				limit = unboundedLimit
+			}
+			line = append(line, formatQuantity(row.Totals.Requests, name), limit)
+		}
+		table.Append(line)
+	}
+	table.Render()
+	return tableString.String()
+}
+
+// CSV shows totals as plain numbers, for spreadsheets: cpu in cores, and
+// everything else -- such as memory -- in its base unit, bytes.  Unbounded
+// limits are `unbounded`.
+func (r *ResourceReport) CSV() (string, error) {
+	formatQuantity := func(resources v1.ResourceList, name v1.ResourceName) string {
+		if quantity, ok := resources[name]; ok {
+			return strconv.FormatFloat(quantity.AsApproximateFloat64(), 'f', -1, 64)
+		}
+		return ""
+	}
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+	records := [][]string{r.header()}
+	for _, row := range r.rows() {
+		record := []string{row.Scope, row.Kind, row.Namespace, row.Name, row.Replicas}
+		for _, name := range r.ResourceNames() {
+			limit := formatQuantity(row.Totals.Limits, name)
+			if row.Totals.IsUnbounded(name) {
+				limit = unboundedLimit
+			}
+			record = append(record, formatQuantity(row.Totals.Requests, name), limit)
+		}
+		records = append(records, record)
+	}
+	if err := writer.WriteAll(records); err != nil {
+		return "", errors.Wrapf(err, "unable to write csv")
+	}
+	return buffer.String(), nil
+}
+
+type ResourceReportArgs struct {
+	ChartPath string
+	Output    string
+}
+
+func RunResourceReport(args *ResourceReportArgs) {
+	objs, err := yaml.ParseManyFromFile[map[string]interface{}](args.ChartPath)
+	utils.DoOrDie(err)
+	report := NewModelFromYaml(objs).ResourceReport()
+
+	switch args.Output {
+	case "table":
+		fmt.Printf("%s\n", report.Table())
+	case "csv":
+		out, err := report.CSV()
+		utils.DoOrDie(err)
+		fmt.Printf("%s", out)
+	case "json":
+		json.PrintOptions(report, &json.MarshalOptions{EscapeHTML: false, Indent: true, Sort: false})
+	default:
+		utils.DoOrDie(errors.Errorf("invalid output format '%s'", args.Output))
+	}
+}
@@ -0,0 +1,124 @@
+package kubernetes
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const resourcesTestYaml = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: apps
+spec:
+  replicas: 3
+  template:
+    spec:
+      initContainers:
+        - name: migrate
+          image: migrate
+          resources:
+            requests: {cpu: 500m, memory: 32Mi}
+            limits: {cpu: "1", memory: 32Mi}
+      containers:
+        - name: web
+          image: web
+          resources:
+            requests: {cpu: 100m, memory: 64Mi}
+            limits: {cpu: 200m, memory: 64Mi}
+        - name: sidecar
+          image: sidecar
+          resources:
+            requests: {cpu: 200m, memory: 64Mi}
+            limits: {cpu: 300m, memory: 64Mi}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+  namespace: apps
+spec:
+  replicas: 2
+  template:
+    spec:
+      initContainers:
+        - name: wait
+          image: wait
+          resources:
+            limits: {cpu: 100m}
+      containers:
+        - name: worker
+          image: worker
+          resources:
+            limits: {cpu: "2", memory: 5Ei}
+`
+
+func formatResources(resources v1.ResourceList) map[string]string {
+	formatted := map[string]string{}
+	for name, quantity := range resources {
+		formatted[string(name)] = quantity.String()
+	}
+	return formatted
+}
+
+func TestResourceReport(t *testing.T) {
+	report := parseTestModel(t, resourcesTestYaml).ResourceReport()
+	workloads := map[string]*WorkloadResources{}
+	for _, workload := range report.Workloads {
+		workloads[workload.Name] = workload
+	}
+	for _, testCase := range []struct {
+		Name      string
+		Totals    *ResourceTotals
+		Requests  map[string]string
+		Limits    map[string]string
+		Unbounded []v1.ResourceName
+	}{
+		{
+			// the init container's cpu request is larger than the app containers' sum
+			Name:      "web per pod",
+			Totals:    workloads["web"].PerPod,
+			Requests:  map[string]string{"cpu": "500m", "memory": "128Mi"},
+			Limits:    map[string]string{"cpu": "1", "memory": "128Mi"},
+			Unbounded: []v1.ResourceName{"ephemeral-storage"},
+		},
+		{
+			Name:      "web total",
+			Totals:    workloads["web"].Total,
+			Requests:  map[string]string{"cpu": "1500m", "memory": "384Mi"},
+			Limits:    map[string]string{"cpu": "3", "memory": "384Mi"},
+			Unbounded: []v1.ResourceName{"ephemeral-storage"},
+		},
+		{
+			// the init container doesn't limit memory, and 10Ei overflows int64
+			Name:      "worker total",
+			Totals:    workloads["worker"].Total,
+			Requests:  map[string]string{"cpu": "4", "memory": "10Ei"},
+			Limits:    map[string]string{"cpu": "4"},
+			Unbounded: []v1.ResourceName{"ephemeral-storage", "memory"},
+		},
+		{
+			Name:      "namespace",
+			Totals:    report.Namespaces["apps"],
+			Requests:  map[string]string{"cpu": "5500m", "memory": "10995116278144Mi"},
+			Limits:    map[string]string{"cpu": "7"},
+			Unbounded: []v1.ResourceName{"ephemeral-storage", "memory"},
+		},
+	} {
+		if found := formatResources(testCase.Totals.Requests); !reflect.DeepEqual(found, testCase.Requests) {
+			t.Errorf("%s: expected requests %v, found %v", testCase.Name, testCase.Requests, found)
+		}
+		if found := formatResources(testCase.Totals.Limits); !reflect.DeepEqual(found, testCase.Limits) {
+			t.Errorf("%s: expected limits %v, found %v", testCase.Name, testCase.Limits, found)
+		}
+		if !reflect.DeepEqual(testCase.Totals.UnboundedLimits, testCase.Unbounded) {
+			t.Errorf("%s: expected unbounded limits %v, found %v", testCase.Name, testCase.Unbounded, testCase.Totals.UnboundedLimits)
+		}
+	}
+	if table := report.Table(); !strings.Contains(table, "unbounded") {
+		t.Errorf("expected unbounded limits in table:\n%s", table)
+	}
+}
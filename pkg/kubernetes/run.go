@@ -1,23 +1,100 @@
 package kubernetes
 
 import (
+	"context"
 	"fmt"
 	"github.com/mattfenwick/collections/pkg/set"
 	"github.com/mattfenwick/collections/pkg/slice"
 	"github.com/mattfenwick/collections/pkg/yaml"
+	"github.com/mattfenwick/kube-utils/pkg/swagger"
 	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/exp/maps"
 )
 
 type YamlAnalysisArgs struct {
-	ChartPath    string
+	ChartPath string
+	// FromCluster reads objects from the cluster selected by Cluster, instead of
+	// from ChartPath
+	FromCluster  bool
+	Cluster      *ClusterArgs
 	PrintSkipped bool
 	Resources    []string
+	// TargetVersion, if set, flags objects whose apiVersion isn't served by
+	// that kubernetes version
+	TargetVersion    string
+	SwaggerDir       string
+	SwaggerPath      string
+	ApiResourcesPath string
+	// ApiVersionsPath, if set, lists the group/versions served alongside the
+	// preferred ones in ApiResourcesPath
+	ApiVersionsPath string
+	Validate        bool
+	// VulnerabilityReportsDir, if set, is a directory of trivy or CycloneDX
+	// JSON reports to match to the chart's images
+	VulnerabilityReportsDir string
+}
+
+// ReadSwaggerIndex reads the spec at SwaggerPath, or else the spec for
+// TargetVersion from SwaggerDir
+func (a *YamlAnalysisArgs) ReadSwaggerIndex() (*swagger.Index, error) {
+	if a.SwaggerPath != "" {
+		return swagger.ReadIndex(a.SwaggerPath)
+	}
+	if a.TargetVersion == "" {
+		return nil, errors.Errorf("one of --swagger-path or --target-version is required")
+	}
+	path, err := swagger.FindSpecPath(a.SwaggerDir, a.TargetVersion)
+	if err != nil {
+		return nil, err
+	}
+	return swagger.ReadIndex(path)
+}
+
+func (a *YamlAnalysisArgs) ReadServedApis(index *swagger.Index) (*ServedApis, error) {
+	if a.ApiResourcesPath != "" {
+		served, err := ReadApiResources(a.ApiResourcesPath)
+		if err != nil {
+			return nil, err
+		}
+		if a.ApiVersionsPath != "" {
+			if err := served.ReadApiVersions(a.ApiVersionsPath); err != nil {
+				return nil, err
+			}
+		}
+		return served, nil
+	}
+	return ServedApisFromSwagger(index), nil
+}
+
+// ReadObjects reads objects from ChartPath, or from the cluster
+func (a *YamlAnalysisArgs) ReadObjects() ([]map[string]interface{}, error) {
+	switch {
+	case a.FromCluster && a.ChartPath != "":
+		return nil, errors.Errorf("only one of --chart-path or --from-cluster may be used")
+	case a.FromCluster:
+		client, namespace, err := a.Cluster.NewClientset()
+		if err != nil {
+			return nil, err
+		}
+		return ReadClusterObjects(context.TODO(), client, namespace)
+	case a.ChartPath != "":
+		return yaml.ParseManyFromFile[map[string]interface{}](a.ChartPath)
+	default:
+		return nil, errors.Errorf("one of --chart-path or --from-cluster is required")
+	}
 }
 
 func RunYamlAnalysis(args *YamlAnalysisArgs) {
-	objs, err := yaml.ParseManyFromFile[map[string]interface{}](args.ChartPath)
+	objs, err := args.ReadObjects()
 	utils.DoOrDie(err)
+
+	// checks run first, as objects which fail them may not parse into the model
+	if failures := runYamlChecks(args, objs); failures > 0 {
+		utils.DoOrDie(errors.Errorf("found %d problems", failures))
+	}
+
 	model := NewModelFromYaml(objs)
 
 	//fmt.Printf("%s\n", model.Graph().RenderAsDot())
@@ -42,9 +119,68 @@ func RunYamlAnalysis(args *YamlAnalysisArgs) {
 		fmt.Printf("images:\n%s\n\n", images)
 	}
 
+	if allow("env") {
+		fmt.Printf("env vars:\n%s\n\n", model.EnvTable())
+		fmt.Printf("commands:\n%s\n\n", model.CommandsTable())
+	}
+
+	if args.VulnerabilityReportsDir != "" {
+		reports, err := ReadVulnerabilityReports(args.VulnerabilityReportsDir)
+		utils.DoOrDie(err)
+		inventory, invalid := model.ParseImageInventory()
+		for _, violation := range invalid {
+			logrus.Warnf("skipping vulnerabilities of image '%s': %s", violation.Image, violation.Details)
+		}
+		joined := JoinVulnerabilityReports(inventory, reports)
+		fmt.Printf("image vulnerabilities:\n%s\n\n", VulnerabilitiesTable(joined))
+		fmt.Printf("critical and high vulnerabilities:\n%s\n\n", VulnerabilityUsagesTable(joined))
+	}
+
 	for _, kind := range slice.Sort(maps.Keys(pods)) {
 		if allow(kind) {
 			fmt.Printf("\nkind: %s\n%s\n", kind, pods[kind])
 		}
 	}
 }
+
+// runYamlChecks runs the enabled checks, printing their results, and returns the
+// number of problems found
+func runYamlChecks(args *YamlAnalysisArgs, objs []map[string]interface{}) int {
+	var err error
+	var index *swagger.Index
+	if args.Validate || (args.TargetVersion != "" && args.ApiResourcesPath == "") {
+		index, err = args.ReadSwaggerIndex()
+		utils.DoOrDie(err)
+	}
+
+	failures := 0
+	if args.TargetVersion != "" || args.ApiResourcesPath != "" {
+		served, err := args.ReadServedApis(index)
+		utils.DoOrDie(err)
+		target := args.TargetVersion
+		if args.ApiResourcesPath != "" {
+			target = args.ApiResourcesPath
+		}
+		problems := CheckApiVersions(objs, served)
+		fmt.Printf("apiVersions not served by %s:\n%s\n\n", target, ApiVersionProblemsTable(problems))
+		if count := removedApiVersionCount(problems); count > 0 {
+			logrus.Errorf("found %d objects using apiVersions not served by %s", count, target)
+			failures += count
+		}
+		if count := notPreferredApiVersionCount(problems); count > 0 {
+			logrus.Warnf("found %d objects using apiVersions which may be served by %s, but aren't preferred", count, target)
+		}
+	}
+
+	if args.Validate {
+		validationErrors, err := ValidateObjects(index, objs)
+		utils.DoOrDie(err)
+		fmt.Printf("schema validation errors:\n%s\n\n", ValidationErrorsTable(validationErrors))
+		if len(validationErrors) > 0 {
+			logrus.Errorf("found %d schema validation errors", len(validationErrors))
+			failures += len(validationErrors)
+		}
+	}
+
+	return failures
+}
@@ -0,0 +1,103 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"golang.org/x/exp/maps"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// SecretProducer is a custom resource from which an operator creates a Secret
+type SecretProducer struct {
+	Kind       string
+	Name       string
+	SecretName string
+	// Keys are the secret's keys, or nil if they aren't known until the
+	// operator creates the secret
+	Keys []string
+}
+
+func (p *SecretProducer) Source() string {
+	return fmt.Sprintf("%s/%s", p.Kind, p.Name)
+}
+
+// secretProducerGroups are the api groups of the kinds recognized as secret
+// producers, so that other CRDs with the same kind aren't mistaken for them
+var secretProducerGroups = map[string][]string{
+	// external-secrets, and its predecessor kubernetes-external-secrets
+	"ExternalSecret": {"external-secrets.io", "kubernetes-client.io"},
+	// sealed-secrets
+	"SealedSecret": {"bitnami.com"},
+	// cert-manager
+	"Certificate": {"cert-manager.io"},
+}
+
+func IsSecretProducer(obj map[string]interface{}) bool {
+	u := &unstructured.Unstructured{Object: obj}
+	gvk := u.GroupVersionKind()
+	return slice.Any(func(group string) bool { return group == gvk.Group }, secretProducerGroups[gvk.Kind])
+}
+
+// AnalyzeSecretProducer finds the secret created from an ExternalSecret,
+// SealedSecret or Certificate, or returns nil for other kinds
+func AnalyzeSecretProducer(obj map[string]interface{}) *SecretProducer {
+	if !IsSecretProducer(obj) {
+		return nil
+	}
+	u := &unstructured.Unstructured{Object: obj}
+	producer := &SecretProducer{Kind: u.GetKind(), Name: u.GetName(), SecretName: u.GetName()}
+	switch u.GetKind() {
+	case "ExternalSecret":
+		analyzeExternalSecret(u, producer)
+	case "SealedSecret":
+		if name, ok, _ := unstructured.NestedString(obj, "spec", "template", "metadata", "name"); ok && name != "" {
+			producer.SecretName = name
+		}
+		encrypted, _ := nestedFieldNoCopy(obj, "spec", "encryptedData").(map[string]interface{})
+		templateData, _ := nestedFieldNoCopy(obj, "spec", "template", "data").(map[string]interface{})
+		producer.Keys = slice.Sort(append(maps.Keys(encrypted), maps.Keys(templateData)...))
+	case "Certificate":
+		if name, ok, _ := unstructured.NestedString(obj, "spec", "secretName"); ok && name != "" {
+			producer.SecretName = name
+		}
+		producer.Keys = []string{"ca.crt", "tls.crt", "tls.key"}
+	}
+	return producer
+}
+
+// analyzeExternalSecret reads the target secret -- `spec.target.name`, which
+// defaults to the ExternalSecret's name -- and its keys, which are known unless
+// they come from `dataFrom` or a template
+func analyzeExternalSecret(u *unstructured.Unstructured, producer *SecretProducer) {
+	obj := u.Object
+	if name, ok, _ := unstructured.NestedString(obj, "spec", "target", "name"); ok && name != "" {
+		producer.SecretName = name
+	}
+	dataFrom, _ := nestedFieldNoCopy(obj, "spec", "dataFrom").([]interface{})
+	if len(dataFrom) > 0 || nestedFieldNoCopy(obj, "spec", "target", "template") != nil {
+		return
+	}
+	// external-secrets.io uses `secretKey`, kubernetes-client.io uses `name`
+	keyField := "secretKey"
+	if schema.FromAPIVersionAndKind(u.GetAPIVersion(), u.GetKind()).Group == "kubernetes-client.io" {
+		keyField = "name"
+	}
+	data, _ := nestedFieldNoCopy(obj, "spec", "data").([]interface{})
+	keys := []string{}
+	for _, item := range data {
+		if entry, ok := item.(map[string]interface{}); ok {
+			if key, ok := entry[keyField].(string); ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	producer.Keys = slice.Sort(keys)
+}
+
+// nestedFieldNoCopy is unstructured.NestedFieldNoCopy, ignoring errors: the
+// NestedX functions which copy panic on the ints yaml parsing produces
+func nestedFieldNoCopy(obj map[string]interface{}, fields ...string) interface{} {
+	value, _, _ := unstructured.NestedFieldNoCopy(obj, fields...)
+	return value
+}
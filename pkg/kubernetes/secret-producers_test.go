@@ -0,0 +1,249 @@
+package kubernetes
+
+import (
+	"github.com/mattfenwick/collections/pkg/yaml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeSecretProducer(t *testing.T) {
+	for _, testCase := range []struct {
+		Name     string
+		Manifest string
+		Expected *SecretProducer
+	}{
+		{
+			Name: "ExternalSecret without a target name",
+			Manifest: `
+apiVersion: external-secrets.io/v1beta1
+kind: ExternalSecret
+metadata:
+  name: db
+spec:
+  data:
+  - secretKey: password
+    remoteRef: {key: db/password}
+  - secretKey: username
+    remoteRef: {key: db/username}
+`,
+			Expected: &SecretProducer{Kind: "ExternalSecret", Name: "db", SecretName: "db", Keys: []string{"password", "username"}},
+		},
+		{
+			Name: "ExternalSecret with a target name",
+			Manifest: `
+apiVersion: external-secrets.io/v1beta1
+kind: ExternalSecret
+metadata:
+  name: db
+spec:
+  target:
+    name: db-credentials
+  data:
+  - secretKey: password
+    remoteRef: {key: db/password}
+`,
+			Expected: &SecretProducer{Kind: "ExternalSecret", Name: "db", SecretName: "db-credentials", Keys: []string{"password"}},
+		},
+		{
+			Name: "kubernetes-client.io ExternalSecret",
+			Manifest: `
+apiVersion: kubernetes-client.io/v1
+kind: ExternalSecret
+metadata:
+  name: legacy
+spec:
+  backendType: secretsManager
+  data:
+  - key: prod/token
+    name: token
+`,
+			Expected: &SecretProducer{Kind: "ExternalSecret", Name: "legacy", SecretName: "legacy", Keys: []string{"token"}},
+		},
+		{
+			Name: "ExternalSecret with dataFrom",
+			Manifest: `
+apiVersion: external-secrets.io/v1beta1
+kind: ExternalSecret
+metadata:
+  name: everything
+spec:
+  dataFrom:
+  - extract: {key: app}
+`,
+			Expected: &SecretProducer{Kind: "ExternalSecret", Name: "everything", SecretName: "everything"},
+		},
+		{
+			Name: "ExternalSecret with a template",
+			Manifest: `
+apiVersion: external-secrets.io/v1beta1
+kind: ExternalSecret
+metadata:
+  name: templated
+spec:
+  target:
+    template:
+      data:
+        url: "postgres://{{ .password }}@db"
+  data:
+  - secretKey: password
+    remoteRef: {key: db/password}
+`,
+			Expected: &SecretProducer{Kind: "ExternalSecret", Name: "templated", SecretName: "templated"},
+		},
+		{
+			Name: "SealedSecret with a template name",
+			Manifest: `
+apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: sealed
+spec:
+  encryptedData:
+    token: AgBy8hCi
+  template:
+    metadata:
+      name: api-token
+    data:
+      url: https://example.com
+`,
+			Expected: &SecretProducer{Kind: "SealedSecret", Name: "sealed", SecretName: "api-token", Keys: []string{"token", "url"}},
+		},
+		{
+			Name: "SealedSecret without a template",
+			Manifest: `
+apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: sealed
+spec:
+  encryptedData:
+    token: AgBy8hCi
+`,
+			Expected: &SecretProducer{Kind: "SealedSecret", Name: "sealed", SecretName: "sealed", Keys: []string{"token"}},
+		},
+		{
+			Name: "Certificate",
+			Manifest: `
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: web
+spec:
+  secretName: web-tls
+  dnsNames: [example.com]
+`,
+			Expected: &SecretProducer{Kind: "Certificate", Name: "web", SecretName: "web-tls", Keys: []string{"ca.crt", "tls.crt", "tls.key"}},
+		},
+		{
+			Name: "Certificate from another group",
+			Manifest: `
+apiVersion: example.com/v1
+kind: Certificate
+metadata:
+  name: other
+spec:
+  secretName: other-tls
+`,
+			Expected: nil,
+		},
+	} {
+		obj, err := yaml.Parse[map[string]interface{}]([]byte(testCase.Manifest))
+		if err != nil {
+			t.Fatalf("%s: unable to parse manifest: %+v", testCase.Name, err)
+		}
+		producer := AnalyzeSecretProducer(*obj)
+		if !reflect.DeepEqual(producer, testCase.Expected) {
+			t.Errorf("%s: expected %+v, found %+v", testCase.Name, testCase.Expected, producer)
+		}
+	}
+}
+
+func TestSecretsTableShowsProducers(t *testing.T) {
+	model := parseTestModel(t, `
+apiVersion: external-secrets.io/v1beta1
+kind: ExternalSecret
+metadata:
+  name: db
+spec:
+  data:
+  - secretKey: password
+    remoteRef: {key: db/password}
+---
+apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: sealed
+spec:
+  encryptedData:
+    token: AgBy8hCi
+  template:
+    metadata:
+      name: api-token
+---
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: web
+spec:
+  secretName: web-tls
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1.0
+        env:
+        - name: DB_PASSWORD
+          valueFrom:
+            secretKeyRef: {name: db, key: password}
+        - name: API_TOKEN
+          valueFrom:
+            secretKeyRef: {name: api-token, key: token}
+        - name: MISSING
+          valueFrom:
+            secretKeyRef: {name: missing, key: value}
+      volumes:
+      - name: tls
+        secret:
+          secretName: web-tls
+`)
+	for secret, expected := range map[string]string{
+		"db":        "ExternalSecret/db",
+		"api-token": "SealedSecret/sealed",
+		"web-tls":   "Certificate/web",
+		"missing":   "unknown",
+	} {
+		if source := model.SecretSource(secret); source != expected {
+			t.Errorf("%s: expected source %s, found %s", secret, expected, source)
+		}
+	}
+
+	secretsComparison, _ := model.GetUsedUnusedSecretsAndConfigMaps()
+	if !reflect.DeepEqual(secretsComparison.JustB, []string{"missing"}) {
+		t.Errorf("expected only missing to be undefined, found %+v", secretsComparison.JustB)
+	}
+
+	rows := map[string]string{}
+	for _, line := range strings.Split(model.SecretsTable(), "\n") {
+		cells := strings.Split(line, "|")
+		if len(cells) > 2 {
+			rows[strings.TrimSpace(cells[1])] = strings.TrimSpace(cells[2])
+		}
+	}
+	for secret, expected := range map[string]string{
+		"db":        "ExternalSecret/db",
+		"api-token": "SealedSecret/sealed",
+		"web-tls":   "Certificate/web",
+		"missing":   "unknown",
+	} {
+		if rows[secret] != expected {
+			t.Errorf("%s: expected table source %s, found %s", secret, expected, rows[secret])
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package kubernetes
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/file"
+	"github.com/mattfenwick/collections/pkg/set"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/mattfenwick/kube-utils/pkg/swagger"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/version"
+	"sort"
+	"strings"
+)
+
+// ServedApis records which apiVersions serve each kind in a kubernetes version
+type ServedApis struct {
+	KindApiVersions map[string]*set.Set[string]
+	// PreferredOnly is set when KindApiVersions only has each group's preferred
+	// version, as `kubectl api-resources` lists; other versions may be served
+	PreferredOnly bool
+	// GroupVersions, if known, lists every served group/version, as `kubectl
+	// api-versions` lists
+	GroupVersions *set.Set[string]
+}
+
+func NewServedApis() *ServedApis {
+	return &ServedApis{KindApiVersions: map[string]*set.Set[string]{}}
+}
+
+func (s *ServedApis) Add(apiVersion string, kind string) {
+	if _, ok := s.KindApiVersions[kind]; !ok {
+		s.KindApiVersions[kind] = set.FromSlice[string](nil)
+	}
+	s.KindApiVersions[kind].Add(apiVersion)
+}
+
+func (s *ServedApis) IsServed(apiVersion string, kind string) bool {
+	apiVersions, ok := s.KindApiVersions[kind]
+	return ok && apiVersions.Contains(apiVersion)
+}
+
+// Groups lists every api group with at least one served kind; the core group is
+// the empty string
+func (s *ServedApis) Groups() *set.Set[string] {
+	groups := set.FromSlice[string](nil)
+	for _, apiVersions := range s.KindApiVersions {
+		for _, apiVersion := range apiVersions.ToSlice() {
+			groups.Add(apiVersionGroup(apiVersion))
+		}
+	}
+	return groups
+}
+
+// Replacements lists the apiVersions serving kind, best first: apiVersions in
+// the same group as apiVersion, then by stability (GA, beta, alpha) and version
+func (s *ServedApis) Replacements(apiVersion string, kind string) []string {
+	apiVersions, ok := s.KindApiVersions[kind]
+	if !ok {
+		return nil
+	}
+	group := apiVersionGroup(apiVersion)
+	replacements := apiVersions.ToSlice()
+	sort.Slice(replacements, func(i, j int) bool {
+		a, b := replacements[i], replacements[j]
+		if sameA, sameB := apiVersionGroup(a) == group, apiVersionGroup(b) == group; sameA != sameB {
+			return sameA
+		}
+		if c := version.CompareKubeAwareVersionStrings(apiVersionVersion(a), apiVersionVersion(b)); c != 0 {
+			return c > 0
+		}
+		return a < b
+	})
+	return replacements
+}
+
+func apiVersionGroup(apiVersion string) string {
+	if slash := strings.Index(apiVersion, "/"); slash >= 0 {
+		return apiVersion[:slash]
+	}
+	return ""
+}
+
+func apiVersionVersion(apiVersion string) string {
+	return apiVersion[strings.Index(apiVersion, "/")+1:]
+}
+
+func ServedApisFromSwagger(index *swagger.Index) *ServedApis {
+	served := NewServedApis()
+	for _, kind := range index.Kinds() {
+		served.Add(kind.GVK.ApiVersion(), kind.GVK.Kind)
+	}
+	return served
+}
+
+// ReadApiResources reads the output of `kubectl api-resources -o wide`, as
+// collected by kube/collect-data.sh.  The columns are located by the header's
+// positions, since SHORTNAMES is often blank.  Only preferred versions are
+// listed; add the output of `kubectl api-versions` with ReadApiVersions to know
+// which other versions are served.
+func ReadApiResources(path string) (*ServedApis, error) {
+	contents, err := file.ReadString(path)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	if !scanner.Scan() {
+		return nil, errors.Errorf("unable to read api resources from %s: empty file", path)
+	}
+	header := scanner.Text()
+	columnStarts := map[string]int{}
+	for i := 0; i < len(header); i++ {
+		if header[i] != ' ' && (i == 0 || header[i-1] == ' ') {
+			columnStarts[strings.Fields(header[i:])[0]] = i
+		}
+	}
+	apiVersionStart, ok := columnStarts["APIVERSION"]
+	if !ok {
+		return nil, errors.Errorf("unable to read api resources from %s: no APIVERSION column in header '%s'; older kubectl versions print APIGROUP instead", path, header)
+	}
+	kindStart, ok := columnStarts["KIND"]
+	if !ok {
+		return nil, errors.Errorf("unable to read api resources from %s: no KIND column in header '%s'", path, header)
+	}
+	column := func(line string, start int) string {
+		if start >= len(line) {
+			return ""
+		}
+		fields := strings.Fields(line[start:])
+		if len(fields) == 0 {
+			return ""
+		}
+		return fields[0]
+	}
+
+	served := NewServedApis()
+	served.PreferredOnly = true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		apiVersion, kind := column(line, apiVersionStart), column(line, kindStart)
+		if apiVersion == "" || kind == "" {
+			return nil, errors.Errorf("unable to parse api resources line '%s' from %s", line, path)
+		}
+		served.Add(apiVersion, kind)
+	}
+	return served, errors.Wrapf(scanner.Err(), "unable to read api resources from %s", path)
+}
+
+// ReadApiVersions reads the output of `kubectl api-versions`, as collected by
+// kube/collect-data.sh, into served's GroupVersions
+func (s *ServedApis) ReadApiVersions(path string) error {
+	contents, err := file.ReadString(path)
+	if err != nil {
+		return err
+	}
+	s.GroupVersions = set.FromSlice[string](nil)
+	for _, line := range strings.Split(contents, "\n") {
+		if groupVersion := strings.TrimSpace(line); groupVersion != "" {
+			s.GroupVersions.Add(groupVersion)
+		}
+	}
+	if s.GroupVersions.Len() == 0 {
+		return errors.Errorf("unable to read api versions from %s: empty file", path)
+	}
+	return nil
+}
+
+// MaybeServed is true for apiVersions which aren't known to serve kind, but
+// might: when only preferred versions are known, and apiVersion is served --
+// or isn't known not to be -- by a group that serves kind at another version
+func (s *ServedApis) MaybeServed(apiVersion string, kind string) bool {
+	if !s.PreferredOnly {
+		return false
+	}
+	if s.GroupVersions != nil && !s.GroupVersions.Contains(apiVersion) {
+		return false
+	}
+	return slice.Any(func(a string) bool { return apiVersionGroup(a) == apiVersionGroup(apiVersion) }, s.Replacements(apiVersion, kind))
+}
+
+type ApiVersionStatus string
+
+const (
+	// ApiVersionStatusRemoved means the kind isn't served at this apiVersion, but
+	// is served at others -- or its group is built in
+	ApiVersionStatusRemoved ApiVersionStatus = "removed"
+	// ApiVersionStatusNotPreferred means the kind is served at its group's
+	// preferred version, and may also be served at this apiVersion
+	ApiVersionStatusNotPreferred ApiVersionStatus = "not preferred"
+	// ApiVersionStatusUnknown means neither the kind nor its group are built in,
+	// so it's probably a custom resource whose CRD isn't in the input
+	ApiVersionStatusUnknown ApiVersionStatus = "unknown"
+)
+
+type ApiVersionProblem struct {
+	Kind         string
+	Name         string
+	ApiVersion   string
+	Status       ApiVersionStatus
+	Replacements []string
+}
+
+// CheckApiVersions finds objects whose apiVersion/kind isn't served.  Kinds
+// defined by CustomResourceDefinitions in objs count as served.
+func CheckApiVersions(objs []map[string]interface{}, served *ServedApis) []*ApiVersionProblem {
+	customResources := customResourceApis(objs)
+	groups := served.Groups()
+
+	var problems []*ApiVersionProblem
+	for _, obj := range objs {
+		if obj == nil {
+			continue
+		}
+		apiVersion, _ := obj["apiVersion"].(string)
+		kind, _ := obj["kind"].(string)
+		if served.IsServed(apiVersion, kind) || customResources.IsServed(apiVersion, kind) {
+			continue
+		}
+		problem := &ApiVersionProblem{
+			Kind:         kind,
+			Name:         objectName(obj),
+			ApiVersion:   apiVersion,
+			Status:       ApiVersionStatusUnknown,
+			Replacements: served.Replacements(apiVersion, kind),
+		}
+		if served.MaybeServed(apiVersion, kind) {
+			problem.Status = ApiVersionStatusNotPreferred
+		} else if len(problem.Replacements) > 0 || groups.Contains(apiVersionGroup(apiVersion)) {
+			problem.Status = ApiVersionStatusRemoved
+		}
+		problems = append(problems, problem)
+	}
+	return slice.SortOn(func(p *ApiVersionProblem) string {
+		return fmt.Sprintf("%s/%s/%s", p.Status, p.Kind, p.Name)
+	}, problems)
+}
+
+// objectName is like getResourceName, but tolerates objects without a name
+func objectName(obj map[string]interface{}) string {
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	return name
+}
+
+func customResourceApis(objs []map[string]interface{}) *ServedApis {
+	served := NewServedApis()
+	for _, obj := range objs {
+		if obj == nil || obj["kind"] != "CustomResourceDefinition" {
+			continue
+		}
+		spec, _ := obj["spec"].(map[string]interface{})
+		group, _ := spec["group"].(string)
+		names, _ := spec["names"].(map[string]interface{})
+		kind, _ := names["kind"].(string)
+		// apiextensions.k8s.io/v1 lists versions; v1beta1 allowed a single version
+		if versions, ok := spec["versions"].([]interface{}); ok {
+			for _, v := range versions {
+				if name, ok := v.(map[string]interface{})["name"].(string); ok {
+					served.Add(fmt.Sprintf("%s/%s", group, name), kind)
+				}
+			}
+		}
+		if v, ok := spec["version"].(string); ok {
+			served.Add(fmt.Sprintf("%s/%s", group, v), kind)
+		}
+	}
+	return served
+}
+
+func ApiVersionProblemsTable(problems []*ApiVersionProblem) string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetAutoMergeCells(true)
+	table.SetHeader([]string{"Status", "Kind", "Name", "ApiVersion", "Suggested ApiVersion"})
+	for _, problem := range problems {
+		suggestion := "(none)"
+		if len(problem.Replacements) > 0 {
+			suggestion = problem.Replacements[0]
+		}
+		table.Append([]string{string(problem.Status), problem.Kind, problem.Name, problem.ApiVersion, suggestion})
+	}
+	table.Render()
+	return tableString.String()
+}
+
+// removedApiVersionCount counts the problems which should fail a CI check
+func removedApiVersionCount(problems []*ApiVersionProblem) int {
+	return len(slice.Filter(func(p *ApiVersionProblem) bool { return p.Status == ApiVersionStatusRemoved }, problems))
+}
+
+// notPreferredApiVersionCount counts the problems which are only warnings
+func notPreferredApiVersionCount(problems []*ApiVersionProblem) int {
+	return len(slice.Filter(func(p *ApiVersionProblem) bool { return p.Status == ApiVersionStatusNotPreferred }, problems))
+}
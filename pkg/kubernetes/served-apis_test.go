@@ -0,0 +1,61 @@
+package kubernetes
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+var apiResources = `NAME                        SHORTNAMES   APIVERSION       NAMESPACED   KIND                      VERBS
+configmaps                  cm           v1               true         ConfigMap                 [create delete get list patch update watch]
+deployments                 deploy       apps/v1          true         Deployment                [create delete get list patch update watch]
+horizontalpodautoscalers    hpa          autoscaling/v2   true         HorizontalPodAutoscaler   [create delete get list patch update watch]
+ingresses                   ing          networking.k8s.io/v1   true   Ingress                   [create delete get list patch update watch]
+`
+
+var apiVersions = `apps/v1
+autoscaling/v1
+autoscaling/v2
+autoscaling/v2beta2
+networking.k8s.io/v1
+v1
+`
+
+func checkApiVersionStatuses(t *testing.T, served *ServedApis, expected map[string]ApiVersionStatus) {
+	var objs []map[string]interface{}
+	for apiVersionKind := range expected {
+		apiVersion, kind := filepath.Dir(apiVersionKind), filepath.Base(apiVersionKind)
+		objs = append(objs, map[string]interface{}{"apiVersion": apiVersion, "kind": kind, "metadata": map[string]interface{}{"name": "x"}})
+	}
+	actual := map[string]ApiVersionStatus{}
+	for _, problem := range CheckApiVersions(objs, served) {
+		actual[problem.ApiVersion+"/"+problem.Kind] = problem.Status
+	}
+	for apiVersionKind, status := range expected {
+		if actual[apiVersionKind] != status {
+			t.Errorf("expected %s to be '%s', found '%s'", apiVersionKind, status, actual[apiVersionKind])
+		}
+	}
+}
+
+func TestApiResourcesOnlyListPreferredVersions(t *testing.T) {
+	served, err := ReadApiResources(filepath.Join(writeTestFiles(t, map[string]string{"api-resources.txt": apiResources}), "api-resources.txt"))
+	if err != nil {
+		t.Fatalf("unable to read api resources: %+v", err)
+	}
+	// "" means served
+	checkApiVersionStatuses(t, served, map[string]ApiVersionStatus{
+		"apps/v1/Deployment":                          "",
+		"autoscaling/v2beta2/HorizontalPodAutoscaler": ApiVersionStatusNotPreferred,
+		"extensions/v1beta1/Deployment":               ApiVersionStatusRemoved,
+	})
+
+	if err := served.ReadApiVersions(filepath.Join(writeTestFiles(t, map[string]string{"api-versions.txt": apiVersions}), "api-versions.txt")); err != nil {
+		t.Fatalf("unable to read api versions: %+v", err)
+	}
+	checkApiVersionStatuses(t, served, map[string]ApiVersionStatus{
+		"apps/v1/Deployment":                          "",
+		"autoscaling/v2beta2/HorizontalPodAutoscaler": ApiVersionStatusNotPreferred,
+		"autoscaling/v2beta1/HorizontalPodAutoscaler": ApiVersionStatusRemoved,
+		"networking.k8s.io/v1beta1/Ingress":           ApiVersionStatusRemoved,
+	})
+}
@@ -0,0 +1,19 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestFiles writes files, by name, to a temporary directory, and returns
+// the directory
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0600); err != nil {
+			t.Fatalf("unable to write %s: %+v", name, err)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,57 @@
+package kubernetes
+
+import (
+	"github.com/mattfenwick/kube-utils/pkg/swagger"
+	"github.com/olekukonko/tablewriter"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+type ObjectValidationError struct {
+	Kind  string
+	Name  string
+	Error *swagger.ValidationError
+}
+
+// ValidateObjects validates each object against its schema, using schemas from
+// CustomResourceDefinitions in objs for custom resources.  Objects without a
+// schema are skipped.
+func ValidateObjects(index *swagger.Index, objs []map[string]interface{}) ([]*ObjectValidationError, error) {
+	crds, err := index.AddCustomResourceDefinitions(objs)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Debugf("added CustomResourceDefinition schemas: %+v", crds)
+
+	var validationErrors []*ObjectValidationError
+	for _, obj := range objs {
+		if obj == nil {
+			continue
+		}
+		kind, _ := obj["kind"].(string)
+		name := objectName(obj)
+		errs, err := index.ValidateObject(obj)
+		if err != nil {
+			logrus.Warnf("skipping validation of %s/%s: %s", kind, name, err.Error())
+			continue
+		}
+		for _, e := range errs {
+			validationErrors = append(validationErrors, &ObjectValidationError{Kind: kind, Name: name, Error: e})
+		}
+	}
+	return validationErrors, nil
+}
+
+func ValidationErrorsTable(validationErrors []*ObjectValidationError) string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetAutoMergeCells(true)
+	table.SetHeader([]string{"Kind", "Name", "Path", "Error", "Details"})
+	for _, e := range validationErrors {
+		table.Append([]string{e.Kind, e.Name, e.Error.Path, string(e.Error.Type), e.Error.Message})
+	}
+	table.Render()
+	return tableString.String()
+}
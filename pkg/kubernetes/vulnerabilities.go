@@ -0,0 +1,371 @@
+package kubernetes
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/file"
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/collections/pkg/set"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/exp/maps"
+	"io/fs"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+type VulnerabilitySeverity string
+
+const (
+	VulnerabilitySeverityCritical VulnerabilitySeverity = "critical"
+	VulnerabilitySeverityHigh     VulnerabilitySeverity = "high"
+	VulnerabilitySeverityMedium   VulnerabilitySeverity = "medium"
+	VulnerabilitySeverityLow      VulnerabilitySeverity = "low"
+	VulnerabilitySeverityUnknown  VulnerabilitySeverity = "unknown"
+)
+
+var vulnerabilitySeverityRanks = map[VulnerabilitySeverity]int{
+	VulnerabilitySeverityCritical: 4,
+	VulnerabilitySeverityHigh:     3,
+	VulnerabilitySeverityMedium:   2,
+	VulnerabilitySeverityLow:      1,
+	VulnerabilitySeverityUnknown:  0,
+}
+
+func parseVulnerabilitySeverity(s string) VulnerabilitySeverity {
+	severity := VulnerabilitySeverity(strings.ToLower(s))
+	if _, ok := vulnerabilitySeverityRanks[severity]; !ok {
+		return VulnerabilitySeverityUnknown
+	}
+	return severity
+}
+
+type Vulnerability struct {
+	ID       string                `json:"id"`
+	Severity VulnerabilitySeverity `json:"severity"`
+}
+
+// VulnerabilityReport is a scan of an image: the references it was scanned
+// as, and the vulnerabilities found -- each listed once, even if several
+// packages have it
+type VulnerabilityReport struct {
+	Path            string            `json:"path"`
+	Format          string            `json:"format"`
+	Images          []*ImageReference `json:"images"`
+	Vulnerabilities []*Vulnerability  `json:"vulnerabilities"`
+}
+
+// Matches compares by digest if the image has one, and by tag otherwise
+func (r *VulnerabilityReport) Matches(image *ImageReference) bool {
+	return slice.Any(func(reported *ImageReference) bool {
+		if reported.Name() != image.Name() {
+			return false
+		}
+		if image.Digest != "" {
+			return reported.Digest == image.Digest
+		}
+		return reported.Tag != "" && reported.Tag == image.Tag
+	}, r.Images)
+}
+
+type cycloneDxMetadata struct {
+	Component *struct {
+		Name       string `json:"name"`
+		Version    string `json:"version"`
+		Purl       string `json:"purl"`
+		Properties []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"properties"`
+	} `json:"component"`
+}
+
+// ErrUnrecognizedVulnerabilityReport is the cause of errors for JSON files
+// which aren't trivy or CycloneDX reports
+var ErrUnrecognizedVulnerabilityReport = errors.New("unrecognized vulnerability report format: expected CycloneDX or trivy JSON")
+
+// ReadVulnerabilityReports reads every `.json` file under dir as a trivy or
+// CycloneDX report.  Other JSON files are skipped, but finding no reports is an
+// error.
+func ReadVulnerabilityReports(dir string) ([]*VulnerabilityReport, error) {
+	var reports []*VulnerabilityReport
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		contents, err := file.Read(path)
+		if err != nil {
+			return err
+		}
+		report, err := ParseVulnerabilityReport(path, contents)
+		if errors.Cause(err) == ErrUnrecognizedVulnerabilityReport {
+			logrus.Warnf("skipping %s: not a trivy or CycloneDX report", path)
+			return nil
+		} else if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read vulnerability reports from %s", dir)
+	}
+	if len(reports) == 0 {
+		return nil, errors.Errorf("unable to read vulnerability reports from %s: no trivy or CycloneDX reports found", dir)
+	}
+	return reports, nil
+}
+
+func ParseVulnerabilityReport(path string, contents []byte) (*VulnerabilityReport, error) {
+	// CycloneDX and trivy both use `metadata` and `vulnerabilities`, with
+	// different structures, so each is parsed separately
+	format, err := json.Parse[struct {
+		BomFormat    string `json:"bomFormat"`
+		ArtifactName string
+	}](contents)
+	switch {
+	case err != nil:
+		logrus.Debugf("unable to parse %s: %+v", path, err)
+		return nil, errors.Wrapf(ErrUnrecognizedVulnerabilityReport, "unable to parse %s", path)
+	case format.BomFormat == "CycloneDX":
+		return parseCycloneDxReport(path, contents)
+	case format.ArtifactName != "":
+		return parseTrivyReport(path, contents)
+	default:
+		return nil, errors.Wrapf(ErrUnrecognizedVulnerabilityReport, "unable to parse %s", path)
+	}
+}
+
+func parseTrivyReport(path string, contents []byte) (*VulnerabilityReport, error) {
+	trivy, err := json.Parse[struct {
+		ArtifactName string
+		Metadata     struct {
+			RepoTags    []string
+			RepoDigests []string
+		}
+		Results []struct {
+			Vulnerabilities []struct {
+				VulnerabilityID string
+				Severity        string
+			}
+		}
+	}](contents)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse trivy report %s", path)
+	}
+	report := &VulnerabilityReport{Path: path, Format: "trivy"}
+	report.addImages(append(append([]string{trivy.ArtifactName}, trivy.Metadata.RepoTags...), trivy.Metadata.RepoDigests...)...)
+	var vulnerabilities []*Vulnerability
+	for _, result := range trivy.Results {
+		for _, v := range result.Vulnerabilities {
+			vulnerabilities = append(vulnerabilities, &Vulnerability{ID: v.VulnerabilityID, Severity: parseVulnerabilitySeverity(v.Severity)})
+		}
+	}
+	report.addVulnerabilities(vulnerabilities)
+	return report, nil
+}
+
+func parseCycloneDxReport(path string, contents []byte) (*VulnerabilityReport, error) {
+	bom, err := json.Parse[struct {
+		Metadata        cycloneDxMetadata `json:"metadata"`
+		Vulnerabilities []struct {
+			ID      string `json:"id"`
+			Ratings []struct {
+				Severity string `json:"severity"`
+			} `json:"ratings"`
+		} `json:"vulnerabilities"`
+	}](contents)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse CycloneDX report %s", path)
+	}
+	report := &VulnerabilityReport{Path: path, Format: "CycloneDX"}
+	if component := bom.Metadata.Component; component != nil {
+		report.addImages(component.Name)
+		// the version is the image's tag or digest, if not in the name
+		if component.Version != "" {
+			separator := ":"
+			if strings.Contains(component.Version, ":") {
+				separator = "@"
+			}
+			report.addImages(component.Name + separator + component.Version)
+		}
+		for _, property := range component.Properties {
+			if strings.HasSuffix(property.Name, ":RepoTag") || strings.HasSuffix(property.Name, ":RepoDigest") {
+				report.addImages(property.Value)
+			}
+		}
+		report.addImages(purlImages(component.Purl)...)
+	}
+	var vulnerabilities []*Vulnerability
+	for _, v := range bom.Vulnerabilities {
+		severity := VulnerabilitySeverityUnknown
+		for _, rating := range v.Ratings {
+			if s := parseVulnerabilitySeverity(rating.Severity); vulnerabilitySeverityRanks[s] > vulnerabilitySeverityRanks[severity] {
+				severity = s
+			}
+		}
+		vulnerabilities = append(vulnerabilities, &Vulnerability{ID: v.ID, Severity: severity})
+	}
+	report.addVulnerabilities(vulnerabilities)
+	return report, nil
+}
+
+// purlImages finds image references in an oci package url:
+// `pkg:oci/nginx@sha256%3Aabc?repository_url=docker.io/library/nginx&tag=1.23`
+func purlImages(purl string) []string {
+	if !strings.HasPrefix(purl, "pkg:oci/") {
+		return nil
+	}
+	path, query, _ := strings.Cut(strings.TrimPrefix(purl, "pkg:oci/"), "?")
+	name, version, _ := strings.Cut(path, "@")
+	qualifiers, err := url.ParseQuery(query)
+	if err != nil {
+		return nil
+	}
+	if repository := qualifiers.Get("repository_url"); repository != "" {
+		name = repository
+	}
+	var images []string
+	if digest, err := url.PathUnescape(version); err == nil && digest != "" {
+		images = append(images, name+"@"+digest)
+	}
+	if tag := qualifiers.Get("tag"); tag != "" {
+		images = append(images, name+":"+tag)
+	}
+	return images
+}
+
+// addImages adds the references which parse; scanners also report names, such
+// as local paths, which aren't references
+func (r *VulnerabilityReport) addImages(raws ...string) {
+	for _, raw := range raws {
+		if image, err := ParseImageReference(raw); err == nil {
+			r.Images = append(r.Images, image)
+		}
+	}
+}
+
+func (r *VulnerabilityReport) addVulnerabilities(vulnerabilities []*Vulnerability) {
+	byId := map[string]*Vulnerability{}
+	for _, v := range vulnerabilities {
+		if existing, ok := byId[v.ID]; !ok || vulnerabilitySeverityRanks[v.Severity] > vulnerabilitySeverityRanks[existing.Severity] {
+			byId[v.ID] = v
+		}
+	}
+	for _, id := range slice.Sort(maps.Keys(byId)) {
+		r.Vulnerabilities = append(r.Vulnerabilities, byId[id])
+	}
+}
+
+// ImageVulnerabilities is a container's image, and the critical and high
+// vulnerabilities of the reports matching it
+type ImageVulnerabilities struct {
+	// Usage is `<kind>/<name>: <container>`
+	Usage    string          `json:"usage"`
+	Image    *ImageReference `json:"image"`
+	Reports  []string        `json:"reports"`
+	Critical []string        `json:"critical"`
+	High     []string        `json:"high"`
+}
+
+func JoinVulnerabilityReports(inventory []*ImageInventoryItem, reports []*VulnerabilityReport) []*ImageVulnerabilities {
+	var joined []*ImageVulnerabilities
+	for _, item := range inventory {
+		var paths []string
+		critical, high := set.FromSlice[string](nil), set.FromSlice[string](nil)
+		for _, report := range reports {
+			if !report.Matches(item.Image) {
+				continue
+			}
+			paths = append(paths, report.Path)
+			for _, v := range report.Vulnerabilities {
+				switch v.Severity {
+				case VulnerabilitySeverityCritical:
+					critical.Add(v.ID)
+				case VulnerabilitySeverityHigh:
+					high.Add(v.ID)
+				}
+			}
+		}
+		for _, usage := range item.Usages {
+			joined = append(joined, &ImageVulnerabilities{
+				Usage:    usage,
+				Image:    item.Image,
+				Reports:  paths,
+				Critical: slice.Sort(critical.ToSlice()),
+				High:     slice.Sort(high.ToSlice()),
+			})
+		}
+	}
+	return slice.SortOn(func(v *ImageVulnerabilities) string { return v.Usage }, joined)
+}
+
+// VulnerabilitiesTable shows, per workload and container, the counts of critical
+// and high vulnerabilities in its image
+func VulnerabilitiesTable(joined []*ImageVulnerabilities) string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	// only workloads are merged, as counts of different images may be equal
+	table.SetAutoMergeCellsByColumnIndex([]int{0})
+	table.SetHeader([]string{"Workload", "Container", "Image", "Critical", "High", "Reports"})
+	for _, v := range joined {
+		workload, container, _ := strings.Cut(v.Usage, ": ")
+		critical, high := fmt.Sprintf("%d", len(v.Critical)), fmt.Sprintf("%d", len(v.High))
+		if len(v.Reports) == 0 {
+			critical, high = "-", "-"
+		}
+		table.Append([]string{workload, container, v.Image.String(), critical, high, strings.Join(v.Reports, "\n")})
+	}
+	table.Render()
+	return tableString.String()
+}
+
+// VulnerabilityUsagesTable shows, per critical or high vulnerability, the images
+// and workloads which have it: its blast radius
+func VulnerabilityUsagesTable(joined []*ImageVulnerabilities) string {
+	severities := map[string]VulnerabilitySeverity{}
+	images, workloads := map[string]*set.Set[string]{}, map[string]*set.Set[string]{}
+	add := func(id string, severity VulnerabilitySeverity, v *ImageVulnerabilities) {
+		if _, ok := severities[id]; !ok {
+			severities[id] = severity
+			images[id] = set.FromSlice[string](nil)
+			workloads[id] = set.FromSlice[string](nil)
+		}
+		images[id].Add(v.Image.String())
+		workloads[id].Add(v.Usage)
+	}
+	for _, v := range joined {
+		for _, id := range v.Critical {
+			add(id, VulnerabilitySeverityCritical, v)
+		}
+		for _, id := range v.High {
+			add(id, VulnerabilitySeverityHigh, v)
+		}
+	}
+
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetHeader([]string{"Vulnerability", "Severity", "Images", "Workloads"})
+	ids := slice.SortOn(func(id string) string {
+		return fmt.Sprintf("%d %s", 9-vulnerabilitySeverityRanks[severities[id]], id)
+	}, maps.Keys(severities))
+	for _, id := range ids {
+		table.Append([]string{
+			id,
+			string(severities[id]),
+			strings.Join(slice.Sort(images[id].ToSlice()), "\n"),
+			strings.Join(slice.Sort(workloads[id].ToSlice()), "\n"),
+		})
+	}
+	table.Render()
+	return tableString.String()
+}
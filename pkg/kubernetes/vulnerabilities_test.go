@@ -0,0 +1,28 @@
+package kubernetes
+
+import (
+	"testing"
+)
+
+func TestReadVulnerabilityReportsSkipsUnrecognizedFiles(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"nginx.json":  `{"ArtifactName": "nginx:1.25", "Results": [{"Vulnerabilities": [{"VulnerabilityID": "CVE-1", "Severity": "HIGH"}]}]}`,
+		"config.json": `{"auths": {}}`,
+		"list.json":   `[1, 2, 3]`,
+		"notes.txt":   `not json`,
+	})
+	reports, err := ReadVulnerabilityReports(dir)
+	if err != nil {
+		t.Fatalf("unable to read reports: %+v", err)
+	}
+	if len(reports) != 1 || reports[0].Format != "trivy" {
+		t.Fatalf("expected 1 trivy report, found %+v", reports)
+	}
+}
+
+func TestReadVulnerabilityReportsRequiresReports(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{"config.json": `{"auths": {}}`})
+	if _, err := ReadVulnerabilityReports(dir); err == nil {
+		t.Fatalf("expected error for directory without reports")
+	}
+}
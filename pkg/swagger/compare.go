@@ -0,0 +1,147 @@
+package swagger
+
+import (
+	"github.com/mattfenwick/collections/pkg/set"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"golang.org/x/exp/maps"
+	"strings"
+)
+
+type VersionComparison struct {
+	From  string
+	To    string
+	Kinds []*KindComparison
+}
+
+type KindComparison struct {
+	Kind               string
+	AddedApiVersions   []string
+	RemovedApiVersions []string
+	// ApiVersions compares the fields of apiVersions served by both versions
+	ApiVersions []*ApiVersionComparison
+}
+
+type ApiVersionComparison struct {
+	ApiVersion string
+	Changes    []*FieldChange
+}
+
+type FieldChange struct {
+	Type  utils.DiffType
+	Field string
+	Old   string
+	New   string
+}
+
+// Flatten maps the dotted path of every field below f -- `spec.replicas` -- to
+// its node
+func (f *FieldNode) Flatten() map[string]*FieldNode {
+	fields := map[string]*FieldNode{}
+	var helper func(prefix string, node *FieldNode)
+	helper = func(prefix string, node *FieldNode) {
+		for _, child := range node.Children {
+			path := child.Name
+			if prefix != "" {
+				path = prefix + "." + child.Name
+			}
+			fields[path] = child
+			helper(path, child)
+		}
+	}
+	helper("", f)
+	return fields
+}
+
+// Signature is what's compared across versions: a field's type and whether it's
+// required
+func (f *FieldNode) Signature() string {
+	if f.Required {
+		return f.Type + " (required)"
+	}
+	return f.Type
+}
+
+// KindApiVersions maps each apiVersion serving kind to its definition
+func (i *Index) KindApiVersions(kind string) map[string]string {
+	apiVersions := map[string]string{}
+	for _, definition := range i.FindDefinitions(kind) {
+		for _, gvk := range i.DefinitionToGVKs[definition] {
+			apiVersions[gvk.ApiVersion()] = definition
+		}
+	}
+	return apiVersions
+}
+
+func CompareVersions(fromVersion string, from *Index, toVersion string, to *Index, kinds []string) (*VersionComparison, error) {
+	comparison := &VersionComparison{From: fromVersion, To: toVersion}
+	for _, kind := range kinds {
+		kindComparison, err := CompareKind(from, to, kind)
+		if err != nil {
+			return nil, err
+		}
+		comparison.Kinds = append(comparison.Kinds, kindComparison)
+	}
+	return comparison, nil
+}
+
+func CompareKind(from *Index, to *Index, kind string) (*KindComparison, error) {
+	fromApiVersions := from.KindApiVersions(kind)
+	toApiVersions := to.KindApiVersions(kind)
+	fromSet, toSet := set.FromSlice(maps.Keys(fromApiVersions)), set.FromSlice(maps.Keys(toApiVersions))
+
+	comparison := &KindComparison{
+		Kind:               kind,
+		AddedApiVersions:   slice.Sort(toSet.Difference(fromSet).ToSlice()),
+		RemovedApiVersions: slice.Sort(fromSet.Difference(toSet).ToSlice()),
+	}
+	for _, apiVersion := range slice.Sort(fromSet.Intersect(toSet).ToSlice()) {
+		fromTree, err := from.BuildFieldTree(fromApiVersions[apiVersion], -1)
+		if err != nil {
+			return nil, err
+		}
+		toTree, err := to.BuildFieldTree(toApiVersions[apiVersion], -1)
+		if err != nil {
+			return nil, err
+		}
+		comparison.ApiVersions = append(comparison.ApiVersions, &ApiVersionComparison{
+			ApiVersion: apiVersion,
+			Changes:    CompareFields(fromTree, toTree),
+		})
+	}
+	return comparison, nil
+}
+
+// CompareFields reports fields added, removed, or whose signature changed,
+// sorted by field path.  Fields nested under an added or removed field aren't
+// reported separately.
+func CompareFields(from *FieldNode, to *FieldNode) []*FieldChange {
+	fromFields, toFields := from.Flatten(), to.Flatten()
+	var changes []*FieldChange
+	for _, path := range slice.Sort(maps.Keys(fromFields)) {
+		fromField := fromFields[path]
+		if toField, ok := toFields[path]; !ok {
+			if _, parentOk := toFields[parentField(path)]; parentOk || parentField(path) == "" {
+				changes = append(changes, &FieldChange{Type: utils.DiffTypeRemove, Field: path, Old: fromField.Signature()})
+			}
+		} else if fromField.Signature() != toField.Signature() {
+			changes = append(changes, &FieldChange{Type: utils.DiffTypeChange, Field: path, Old: fromField.Signature(), New: toField.Signature()})
+		}
+	}
+	for path, toField := range toFields {
+		if _, ok := fromFields[path]; !ok {
+			if _, parentOk := fromFields[parentField(path)]; parentOk || parentField(path) == "" {
+				changes = append(changes, &FieldChange{Type: utils.DiffTypeAdd, Field: path, New: toField.Signature()})
+			}
+		}
+	}
+	return slice.SortOn(func(c *FieldChange) string { return c.Field }, changes)
+}
+
+func parentField(path string) string {
+	dot := strings.LastIndex(path, ".")
+	if dot < 0 {
+		return ""
+	}
+	return path[:dot]
+}
@@ -0,0 +1,138 @@
+package swagger
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+const objectMetaDefinition = "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"
+
+// CrdDefinitionName names a custom resource's definition like the built in
+// ones: group `example.com`, version `v1` and kind `Foo` become
+// `com.example.v1.Foo`
+func CrdDefinitionName(gvk *GroupVersionKind) string {
+	return strings.Join(append(slice.Reverse(strings.Split(gvk.Group, ".")), gvk.Version, gvk.Kind), ".")
+}
+
+// AddCustomResourceDefinitions adds the schema of every version of every
+// CustomResourceDefinition in objs to the index.  Both apiextensions.k8s.io/v1
+// (a schema per version) and v1beta1 (optionally, a single top-level schema)
+// are supported.  Returns the names of the added definitions.
+func (i *Index) AddCustomResourceDefinitions(objs []map[string]interface{}) ([]string, error) {
+	var added []string
+	for _, obj := range objs {
+		if obj == nil || obj["kind"] != "CustomResourceDefinition" {
+			continue
+		}
+		spec, _ := obj["spec"].(map[string]interface{})
+		group, _ := spec["group"].(string)
+		names, _ := spec["names"].(map[string]interface{})
+		kind, _ := names["kind"].(string)
+
+		topLevelSchema := nestedValue(spec, "validation", "openAPIV3Schema")
+		versionSchemas := map[string]interface{}{}
+		if versions, ok := spec["versions"].([]interface{}); ok {
+			for _, v := range versions {
+				version, _ := v.(map[string]interface{})
+				name, _ := version["name"].(string)
+				versionSchema := nestedValue(version, "schema", "openAPIV3Schema")
+				if versionSchema == nil {
+					versionSchema = topLevelSchema
+				}
+				versionSchemas[name] = versionSchema
+			}
+		} else if version, ok := spec["version"].(string); ok {
+			versionSchemas[version] = topLevelSchema
+		}
+
+		for version, rawSchema := range versionSchemas {
+			gvk := &GroupVersionKind{Group: group, Version: version, Kind: kind}
+			schema, err := crdSchema(rawSchema)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "unable to read schema for %s", gvk)
+			}
+			schema.GroupVersionKinds = []*GroupVersionKind{gvk}
+			i.addImplicitCustomResourceFields(schema)
+			name := CrdDefinitionName(gvk)
+			i.AddDefinition(name, schema)
+			added = append(added, name)
+		}
+	}
+	return slice.Sort(added), nil
+}
+
+func nestedValue(obj map[string]interface{}, keys ...string) interface{} {
+	var current interface{} = obj
+	for _, key := range keys {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+// crdSchema converts an openAPIV3Schema to a Schema.  A missing schema allows
+// anything.
+func crdSchema(raw interface{}) (*Schema, error) {
+	if raw == nil {
+		return &Schema{Type: "object", PreserveUnknownFields: true}, nil
+	}
+	bytes, err := json.Marshal(normalizeAdditionalProperties(raw))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to marshal json")
+	}
+	schema := &Schema{}
+	if err := json.Unmarshal(bytes, schema); err != nil {
+		return nil, errors.Wrapf(err, "unable to unmarshal json")
+	}
+	return schema, nil
+}
+
+// normalizeAdditionalProperties handles CRD schemas' boolean form of
+// additionalProperties, which a Schema can't hold: `true` becomes a schema
+// allowing anything, and `false` -- the default -- is dropped
+func normalizeAdditionalProperties(raw interface{}) interface{} {
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for key, value := range v {
+			if key == "additionalProperties" {
+				if allowed, ok := value.(bool); ok {
+					if allowed {
+						out[key] = map[string]interface{}{"x-kubernetes-preserve-unknown-fields": true}
+					}
+					continue
+				}
+			}
+			out[key] = normalizeAdditionalProperties(value)
+		}
+		return out
+	case []interface{}:
+		return slice.Map(normalizeAdditionalProperties, v)
+	default:
+		return v
+	}
+}
+
+// addImplicitCustomResourceFields adds the fields every resource has, which
+// CRD schemas usually leave out
+func (i *Index) addImplicitCustomResourceFields(schema *Schema) {
+	if schema.Properties == nil {
+		schema.Properties = map[string]*Schema{}
+	}
+	for _, field := range []string{"apiVersion", "kind"} {
+		if _, ok := schema.Properties[field]; !ok {
+			schema.Properties[field] = &Schema{Type: "string"}
+		}
+	}
+	if _, ok := i.Spec.Definitions[objectMetaDefinition]; ok {
+		schema.Properties["metadata"] = &Schema{Ref: fmt.Sprintf("%s%s", definitionRefPrefix, objectMetaDefinition)}
+	} else if _, ok := schema.Properties["metadata"]; !ok {
+		schema.Properties["metadata"] = &Schema{Type: "object", PreserveUnknownFields: true}
+	}
+}
@@ -0,0 +1,220 @@
+package swagger
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// Explanation documents a kind, or one of its fields, like `kubectl explain`
+type Explanation struct {
+	GVK *GroupVersionKind
+	// Path is the field path below the kind: `spec.replicas`; empty for the kind
+	// itself
+	Path  string
+	Field *FieldNode
+}
+
+// Explain looks up a kind -- anything FindDefinition accepts -- and optionally a
+// dotted field path within it.  Fields are expanded maxDepth levels below the
+// explained field, or without limit if negative.
+func (i *Index) Explain(typeName string, fieldPath string, maxDepth int) (*Explanation, error) {
+	definition, err := i.FindDefinition(typeName)
+	if err != nil {
+		return nil, err
+	}
+	var gvk *GroupVersionKind
+	if gvks := i.DefinitionToGVKs[definition]; len(gvks) > 0 {
+		gvk = gvks[0]
+	}
+
+	var path []string
+	if fieldPath != "" {
+		path = strings.Split(fieldPath, ".")
+	}
+	depth := maxDepth
+	if depth >= 0 {
+		depth += len(path)
+	}
+	tree, err := i.BuildFieldTree(definition, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	field := tree
+	for index, name := range path {
+		var child *FieldNode
+		for _, c := range field.Children {
+			if c.Name == name {
+				child = c
+				break
+			}
+		}
+		if child == nil {
+			return nil, errors.Errorf("field '%s' not found in %s", strings.Join(path[:index+1], "."), tree.Name)
+		}
+		if child.Recursive {
+			// expand the recursive type here, since it's what was asked for
+			child, err = i.expandField(child, maxDepth)
+			if err != nil {
+				return nil, err
+			}
+		}
+		field = child
+	}
+	return &Explanation{GVK: gvk, Path: fieldPath, Field: field}, nil
+}
+
+func (i *Index) expandField(field *FieldNode, maxDepth int) (*FieldNode, error) {
+	tree, err := i.BuildFieldTree(field.Definition, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	expanded := *field
+	expanded.Recursive = false
+	expanded.Children = tree.Children
+	return &expanded, nil
+}
+
+func (f *FieldNode) typeLabel() string {
+	label := fmt.Sprintf("<%s>", f.Type)
+	if f.Required {
+		label += " -required-"
+	}
+	return label
+}
+
+func enumString(enum []interface{}) string {
+	return strings.Join(slice.Map(func(v interface{}) string { return fmt.Sprintf("%v", v) }, enum), ", ")
+}
+
+func indentLines(s string, indent string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.Join(slice.Map(func(line string) string { return strings.TrimRight(indent+line, " ") }, lines), "\n")
+}
+
+// RenderText renders the explanation like `kubectl explain`: the field's
+// description followed by its immediate fields' descriptions, or -- if
+// recursive -- the names and types of all nested fields
+func (e *Explanation) RenderText(recursive bool) string {
+	var lines []string
+	if e.GVK != nil {
+		lines = append(lines, fmt.Sprintf("KIND:     %s", e.GVK.Kind), fmt.Sprintf("VERSION:  %s", e.GVK.ApiVersion()), "")
+	}
+	if e.Path == "" {
+		lines = append(lines, "DESCRIPTION:")
+	} else {
+		lines = append(lines, fmt.Sprintf("FIELD:    %s %s", e.Field.Name, e.Field.typeLabel()), "", "DESCRIPTION:")
+	}
+	if e.Field.Description == "" {
+		lines = append(lines, "     <empty>")
+	} else {
+		lines = append(lines, indentLines(e.Field.Description, "     "))
+	}
+	if len(e.Field.Enum) > 0 {
+		lines = append(lines, "", fmt.Sprintf("ENUM:     %s", enumString(e.Field.Enum)))
+	}
+
+	if len(e.Field.Children) > 0 {
+		lines = append(lines, "", "FIELDS:")
+		if recursive {
+			var helper func(indent string, node *FieldNode)
+			helper = func(indent string, node *FieldNode) {
+				for _, child := range node.Children {
+					lines = append(lines, fmt.Sprintf("%s%s\t%s", indent, child.Name, child.typeLabel()))
+					helper(indent+"   ", child)
+				}
+			}
+			helper("   ", e.Field)
+		} else {
+			for _, child := range e.Field.Children {
+				lines = append(lines, fmt.Sprintf("   %s\t%s", child.Name, child.typeLabel()))
+				if child.Description != "" {
+					lines = append(lines, indentLines(child.Description, "     "))
+				}
+				if len(child.Enum) > 0 {
+					lines = append(lines, fmt.Sprintf("     Enum: %s", enumString(child.Enum)))
+				}
+				lines = append(lines, "")
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RenderMarkdown renders a section per field with nested fields, each with a
+// table of its fields, for use in docs
+func (e *Explanation) RenderMarkdown() string {
+	title := e.Field.Name
+	if e.GVK != nil {
+		title = e.GVK.Kind
+		if e.Path != "" {
+			title += "." + e.Path
+		}
+		title = fmt.Sprintf("%s (%s)", title, e.GVK.ApiVersion())
+	}
+	var sections []string
+	var helper func(level int, heading string, path string, node *FieldNode)
+	helper = func(level int, heading string, path string, node *FieldNode) {
+		section := []string{fmt.Sprintf("%s %s", strings.Repeat("#", level), heading), ""}
+		if node.Description != "" {
+			section = append(section, strings.TrimSpace(node.Description), "")
+		}
+		if node == e.Field && e.Path != "" {
+			section = append(section, fmt.Sprintf("Type: `%s`", node.Type))
+			if node.Required {
+				section = append(section, "", "Required")
+			}
+			if len(node.Enum) > 0 {
+				section = append(section, "", "Enum: "+enumString(node.Enum))
+			}
+			section = append(section, "")
+		}
+		if len(node.Children) == 0 {
+			sections = append(sections, strings.TrimSpace(strings.Join(section, "\n")))
+			return
+		}
+		section = append(section, "| Field | Type | Required | Description |", "| --- | --- | --- | --- |")
+		for _, child := range node.Children {
+			description := child.Description
+			if len(child.Enum) > 0 {
+				description = strings.TrimSpace(description + "\n\nEnum: " + enumString(child.Enum))
+			}
+			if child.Recursive {
+				description = strings.TrimSpace(description + "\n\n(recursive: see " + child.Type + ")")
+			}
+			required := ""
+			if child.Required {
+				required = "yes"
+			}
+			section = append(section, fmt.Sprintf("| `%s` | `%s` | %s | %s |", child.Name, child.Type, required, markdownTableCell(description)))
+		}
+		sections = append(sections, strings.Join(section, "\n"))
+
+		for _, child := range node.Children {
+			if len(child.Children) > 0 {
+				childPath := child.Name
+				if path != "" {
+					childPath = path + "." + child.Name
+				}
+				// markdown only has 6 levels of headings
+				helper(minInt(level+1, 6), fmt.Sprintf("`%s`", childPath), childPath, child)
+			}
+		}
+	}
+	helper(1, title, e.Path, e.Field)
+	return strings.Join(sections, "\n\n") + "\n"
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func markdownTableCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(strings.TrimSpace(s), "\n", "<br>")
+}
@@ -0,0 +1,157 @@
+package swagger
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/set"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"strings"
+)
+
+// FieldNode is one field of a type, along with its nested fields
+type FieldNode struct {
+	Name        string
+	Type        string
+	Definition  string
+	Description string
+	Required    bool
+	Enum        []interface{}
+	// Recursive is set when the field's type is already being expanded higher
+	// up the tree, in which case Children is left empty
+	Recursive bool
+	Children  []*FieldNode
+}
+
+// TypeString describes a schema's type Go-style: `[]Container`,
+// `map[string]string`, `ObjectMeta`, `integer`
+func TypeString(schema *Schema) string {
+	switch {
+	case schema == nil:
+		return "<unknown>"
+	case schema.Ref != "":
+		definition, err := RefDefinition(schema.Ref)
+		if err != nil {
+			return schema.Ref
+		}
+		return ShortName(definition)
+	case schema.Type == "array":
+		return "[]" + TypeString(schema.Items)
+	case schema.Type == "object" && schema.AdditionalProperties != nil:
+		return "map[string]" + TypeString(schema.AdditionalProperties)
+	case schema.Type == "" && len(schema.OneOf) > 0:
+		return strings.Join(slice.Map(TypeString, schema.OneOf), "|")
+	case schema.Type == "":
+		if schema.Format != "" {
+			return schema.Format
+		}
+		return "object"
+	default:
+		return schema.Type
+	}
+}
+
+// fieldTypeSchema is the schema whose properties become a field's children:
+// for arrays and maps, that's the element's schema
+func fieldTypeSchema(schema *Schema) *Schema {
+	for schema != nil {
+		if schema.Type == "array" && schema.Items != nil {
+			schema = schema.Items
+		} else if schema.Type == "object" && schema.AdditionalProperties != nil {
+			schema = schema.AdditionalProperties
+		} else {
+			break
+		}
+	}
+	return schema
+}
+
+// BuildFieldTree expands a definition's fields, following `$ref`s, down to
+// maxDepth levels (no limit if negative)
+func (i *Index) BuildFieldTree(definition string, maxDepth int) (*FieldNode, error) {
+	schema, ok := i.Spec.Definitions[definition]
+	if !ok {
+		return nil, errors.Errorf("definition '%s' not found", definition)
+	}
+	root := &FieldNode{
+		Name:        ShortName(definition),
+		Type:        ShortName(definition),
+		Definition:  definition,
+		Description: schema.Description,
+	}
+	expanding := set.FromSlice([]string{definition})
+	children, err := i.buildFieldChildren(schema, maxDepth, expanding)
+	if err != nil {
+		return nil, err
+	}
+	root.Children = children
+	return root, nil
+}
+
+func (i *Index) buildFieldChildren(schema *Schema, depth int, expanding *set.Set[string]) ([]*FieldNode, error) {
+	if depth == 0 {
+		return nil, nil
+	}
+	required := set.FromSlice(schema.Required)
+	var children []*FieldNode
+	for _, name := range slice.Sort(maps.Keys(schema.Properties)) {
+		property := schema.Properties[name]
+		child := &FieldNode{
+			Name:        name,
+			Type:        TypeString(property),
+			Description: property.Description,
+			Required:    required.Contains(name),
+			Enum:        property.Enum,
+		}
+		typeSchema := fieldTypeSchema(property)
+		if typeSchema.Ref != "" {
+			definition, resolved, err := i.ResolveRef(typeSchema.Ref)
+			if err != nil {
+				return nil, err
+			}
+			child.Definition = definition
+			if child.Description == "" {
+				child.Description = resolved.Description
+			}
+			if expanding.Contains(definition) {
+				child.Recursive = true
+			} else {
+				expanding.Add(definition)
+				child.Children, err = i.buildFieldChildren(resolved, depth-1, expanding)
+				expanding.Delete(definition)
+				if err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			var err error
+			child.Children, err = i.buildFieldChildren(typeSchema, depth-1, expanding)
+			if err != nil {
+				return nil, err
+			}
+		}
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+// RenderTree renders the field tree with one field per line, indented by depth
+func (f *FieldNode) RenderTree() string {
+	var lines []string
+	f.renderTreeHelper("", &lines)
+	return strings.Join(lines, "\n")
+}
+
+func (f *FieldNode) renderTreeHelper(indent string, lines *[]string) {
+	line := fmt.Sprintf("%s%s <%s>", indent, f.Name, f.Type)
+	if f.Required {
+		line += " (required)"
+	}
+	if f.Recursive {
+		line += " (recursive)"
+	}
+	*lines = append(*lines, line)
+	for _, child := range f.Children {
+		child.renderTreeHelper(indent+"  ", lines)
+	}
+}
@@ -0,0 +1,232 @@
+package swagger
+
+import (
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"k8s.io/apimachinery/pkg/version"
+	"sort"
+	"strings"
+)
+
+type Index struct {
+	Spec              *Spec
+	DefinitionToGVKs  map[string][]*GroupVersionKind
+	KindToDefinitions map[string][]string
+}
+
+func NewIndex(spec *Spec) *Index {
+	index := &Index{
+		Spec:              spec,
+		DefinitionToGVKs:  map[string][]*GroupVersionKind{},
+		KindToDefinitions: map[string][]string{},
+	}
+	for _, name := range slice.Sort(maps.Keys(spec.Definitions)) {
+		index.indexDefinition(name)
+	}
+	return index
+}
+
+func (i *Index) indexDefinition(name string) {
+	gvks := i.Spec.Definitions[name].GroupVersionKinds
+	if len(gvks) == 0 {
+		return
+	}
+	i.DefinitionToGVKs[name] = gvks
+	for _, gvk := range gvks {
+		i.KindToDefinitions[gvk.Kind] = append(i.KindToDefinitions[gvk.Kind], name)
+	}
+}
+
+// AddDefinition adds a schema -- such as one from a CustomResourceDefinition --
+// to the spec and index, replacing any definition with the same name
+func (i *Index) AddDefinition(name string, schema *Schema) {
+	if _, ok := i.Spec.Definitions[name]; ok {
+		for _, gvk := range i.DefinitionToGVKs[name] {
+			i.KindToDefinitions[gvk.Kind] = slice.Filter(func(d string) bool { return d != name }, i.KindToDefinitions[gvk.Kind])
+		}
+		delete(i.DefinitionToGVKs, name)
+	}
+	i.Spec.Definitions[name] = schema
+	i.indexDefinition(name)
+}
+
+func ReadIndex(path string) (*Index, error) {
+	spec, err := ReadSpec(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewIndex(spec), nil
+}
+
+type KindDefinition struct {
+	GVK        *GroupVersionKind
+	Definition string
+}
+
+// Kinds lists each kind with its definition, sorted by kind, then group and
+// version.  Definitions shared by many kinds -- such as DeleteOptions and
+// WatchEvent, which are tagged with every group version -- are skipped, as they
+// aren't resources.
+func (i *Index) Kinds() []*KindDefinition {
+	var kinds []*KindDefinition
+	for definition, gvks := range i.DefinitionToGVKs {
+		if len(gvks) != 1 {
+			continue
+		}
+		kinds = append(kinds, &KindDefinition{GVK: gvks[0], Definition: definition})
+	}
+	return slice.SortOn(func(k *KindDefinition) string {
+		return strings.Join([]string{k.GVK.Kind, k.GVK.Group, k.GVK.Version}, "/")
+	}, kinds)
+}
+
+// FindDefinitions resolves a type name to definitions.  The name may be a full
+// definition name (`io.k8s.api.apps.v1.Deployment`), a kind (`Deployment`), or
+// a kind qualified by apiVersion (`apps/v1.Deployment`).  A bare kind can match
+// several definitions, one per group version serving it, and may be in any case.
+func (i *Index) FindDefinitions(name string) []string {
+	if _, ok := i.Spec.Definitions[name]; ok {
+		return []string{name}
+	}
+	if definitions, ok := i.KindToDefinitions[name]; ok {
+		return slice.Filter(func(d string) bool { return len(i.DefinitionToGVKs[d]) == 1 }, definitions)
+	}
+	if dot := strings.LastIndex(name, "."); dot > 0 {
+		apiVersion, kind := name[:dot], name[dot+1:]
+		var definitions []string
+		for _, definition := range i.KindToDefinitions[kind] {
+			for _, gvk := range i.DefinitionToGVKs[definition] {
+				if gvk.ApiVersion() == apiVersion {
+					definitions = append(definitions, definition)
+				}
+			}
+		}
+		return definitions
+	}
+	// kubectl style: kinds are case insensitive
+	for kind := range i.KindToDefinitions {
+		if strings.EqualFold(kind, name) {
+			return i.FindDefinitions(kind)
+		}
+	}
+	return nil
+}
+
+// FindDefinition is FindDefinitions for names which must resolve to one
+// definition.  Like kubectl, a bare kind served by several group versions
+// resolves to the preferred one -- see PreferredDefinition -- so an apiVersion
+// is only needed to choose another.
+func (i *Index) FindDefinition(name string) (string, error) {
+	definitions := i.FindDefinitions(name)
+	switch {
+	case len(definitions) == 0:
+		return "", errors.Errorf("no definition found for type '%s'", name)
+	case len(definitions) == 1:
+		return definitions[0], nil
+	case !strings.Contains(name, "."):
+		return i.PreferredDefinition(definitions), nil
+	default:
+		return "", errors.Errorf("type '%s' is ambiguous, use a full definition name; found: %+v", name, definitions)
+	}
+}
+
+// PreferredDefinition picks the definition with the most stable, then newest,
+// version -- GA before beta before alpha.  Ties go to the core group, and away
+// from the deprecated extensions group, which kubernetes ranks last, and then
+// to groups by name.
+func (i *Index) PreferredDefinition(definitions []string) string {
+	gvk := func(definition string) *GroupVersionKind {
+		if gvks := i.DefinitionToGVKs[definition]; len(gvks) > 0 {
+			return gvks[0]
+		}
+		return &GroupVersionKind{}
+	}
+	sorted := append([]string{}, definitions...)
+	sort.SliceStable(sorted, func(x, y int) bool {
+		a, b := gvk(sorted[x]), gvk(sorted[y])
+		if c := version.CompareKubeAwareVersionStrings(a.Version, b.Version); c != 0 {
+			return c > 0
+		}
+		rank := func(group string) int {
+			switch group {
+			case "":
+				return 0
+			case "extensions":
+				return 2
+			}
+			return 1
+		}
+		if rankA, rankB := rank(a.Group), rank(b.Group); rankA != rankB {
+			return rankA < rankB
+		}
+		return a.Group < b.Group
+	})
+	return sorted[0]
+}
+
+// ResolveRef looks up the definition a `$ref` points to
+func (i *Index) ResolveRef(ref string) (string, *Schema, error) {
+	definition, err := RefDefinition(ref)
+	if err != nil {
+		return "", nil, err
+	}
+	schema, ok := i.Spec.Definitions[definition]
+	if !ok {
+		return "", nil, errors.Errorf("unable to resolve $ref '%s': definition not found", ref)
+	}
+	return definition, schema, nil
+}
+
+// Dereference returns a copy of schema with every `$ref` replaced by the schema
+// it points to, down to maxDepth levels of nesting (no limit if negative).  A
+// ref back to a definition which is already being expanded is left in place,
+// since recursive types such as JSONSchemaProps would otherwise never end.
+func (i *Index) Dereference(schema *Schema, maxDepth int) (*Schema, error) {
+	return i.dereferenceHelper(schema, maxDepth, map[string]bool{})
+}
+
+func (i *Index) dereferenceHelper(schema *Schema, depth int, expanding map[string]bool) (*Schema, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	out := *schema
+	if schema.Ref != "" {
+		definition, resolved, err := i.ResolveRef(schema.Ref)
+		if err != nil {
+			return nil, err
+		}
+		if expanding[definition] || depth == 0 {
+			return &out, nil
+		}
+		expanding[definition] = true
+		defer delete(expanding, definition)
+		expanded, err := i.dereferenceHelper(resolved, depth, expanding)
+		if err != nil {
+			return nil, err
+		}
+		if schema.Description != "" {
+			expanded.Description = schema.Description
+		}
+		return expanded, nil
+	}
+	if depth == 0 {
+		return &out, nil
+	}
+	var err error
+	if out.Items, err = i.dereferenceHelper(schema.Items, depth-1, expanding); err != nil {
+		return nil, err
+	}
+	if out.AdditionalProperties, err = i.dereferenceHelper(schema.AdditionalProperties, depth-1, expanding); err != nil {
+		return nil, err
+	}
+	if schema.Properties != nil {
+		out.Properties = map[string]*Schema{}
+		for name, property := range schema.Properties {
+			if out.Properties[name], err = i.dereferenceHelper(property, depth-1, expanding); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &out, nil
+}
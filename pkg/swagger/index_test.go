@@ -0,0 +1,44 @@
+package swagger
+
+import "testing"
+
+func newTestIndex(gvks ...*GroupVersionKind) *Index {
+	spec := &Spec{Definitions: map[string]*Schema{}}
+	for _, gvk := range gvks {
+		name := "io.k8s.api." + gvk.Group + "." + gvk.Version + "." + gvk.Kind
+		spec.Definitions[name] = &Schema{Type: "object", GroupVersionKinds: []*GroupVersionKind{gvk}}
+	}
+	return NewIndex(spec)
+}
+
+func TestFindDefinitionPrefersStableVersions(t *testing.T) {
+	// as in kubernetes 1.18
+	index := newTestIndex(
+		&GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		&GroupVersionKind{Group: "apps", Version: "v1beta1", Kind: "Deployment"},
+		&GroupVersionKind{Group: "apps", Version: "v1beta2", Kind: "Deployment"},
+		&GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Deployment"},
+		&GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Ingress"},
+		&GroupVersionKind{Group: "networking.k8s.io", Version: "v1beta1", Kind: "Ingress"},
+		&GroupVersionKind{Group: "", Version: "v1", Kind: "Event"},
+		&GroupVersionKind{Group: "events.k8s.io", Version: "v1", Kind: "Event"},
+	)
+	for name, expected := range map[string]string{
+		"Deployment":                    "io.k8s.api.apps.v1.Deployment",
+		"deployment":                    "io.k8s.api.apps.v1.Deployment",
+		"extensions/v1beta1.Deployment": "io.k8s.api.extensions.v1beta1.Deployment",
+		"apps/v1beta2.Deployment":       "io.k8s.api.apps.v1beta2.Deployment",
+		"Ingress":                       "io.k8s.api.networking.k8s.io.v1beta1.Ingress",
+		"Event":                         "io.k8s.api..v1.Event",
+	} {
+		definition, err := index.FindDefinition(name)
+		if err != nil {
+			t.Errorf("unable to find %s: %+v", name, err)
+		} else if definition != expected {
+			t.Errorf("expected %s to resolve to %s, found %s", name, expected, definition)
+		}
+	}
+	if _, err := index.FindDefinition("apps/v2.Deployment"); err == nil {
+		t.Errorf("expected error for unserved apiVersion")
+	}
+}
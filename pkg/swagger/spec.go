@@ -0,0 +1,180 @@
+package swagger
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/file"
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Spec holds the schemas of a swagger 2.0 spec, or of an OpenAPI v3 spec with
+// its `components.schemas` stored as Definitions
+type Spec struct {
+	Swagger     string             `json:"swagger,omitempty"`
+	OpenApi     string             `json:"openapi,omitempty"`
+	Definitions map[string]*Schema `json:"definitions"`
+	Components  *Components        `json:"components,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+type Schema struct {
+	Ref                  string              `json:"$ref,omitempty"`
+	Type                 string              `json:"type,omitempty"`
+	Format               string              `json:"format,omitempty"`
+	Description          string              `json:"description,omitempty"`
+	Required             []string            `json:"required,omitempty"`
+	Enum                 []interface{}       `json:"enum,omitempty"`
+	Items                *Schema             `json:"items,omitempty"`
+	Properties           map[string]*Schema  `json:"properties,omitempty"`
+	AdditionalProperties *Schema             `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema           `json:"allOf,omitempty"`
+	OneOf                []*Schema           `json:"oneOf,omitempty"`
+	AnyOf                []*Schema           `json:"anyOf,omitempty"`
+	GroupVersionKinds    []*GroupVersionKind `json:"x-kubernetes-group-version-kind,omitempty"`
+	// these are used by CustomResourceDefinition schemas
+	IntOrString           bool `json:"x-kubernetes-int-or-string,omitempty"`
+	PreserveUnknownFields bool `json:"x-kubernetes-preserve-unknown-fields,omitempty"`
+	EmbeddedResource      bool `json:"x-kubernetes-embedded-resource,omitempty"`
+}
+
+type GroupVersionKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// ApiVersion is the apiVersion field used in manifests: `v1` for the core group,
+// `apps/v1` otherwise
+func (g *GroupVersionKind) ApiVersion() string {
+	if g.Group == "" {
+		return g.Version
+	}
+	return fmt.Sprintf("%s/%s", g.Group, g.Version)
+}
+
+func (g *GroupVersionKind) String() string {
+	return fmt.Sprintf("%s.%s", g.ApiVersion(), g.Kind)
+}
+
+// SpecPath is where kube/collect-swagger-data.sh stores the spec for a version
+func SpecPath(dir string, version string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s-swagger-spec.json", strings.TrimPrefix(version, "v")))
+}
+
+// FindSpecPath is SpecPath, but also accepts a minor version such as `1.25`, in
+// which case the latest patch version found in dir is used
+func FindSpecPath(dir string, version string) (string, error) {
+	path := SpecPath(dir, version)
+	if file.Exists(path) {
+		return path, nil
+	}
+	matches, err := filepath.Glob(SpecPath(dir, version+".*"))
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to search for swagger specs in %s", dir)
+	}
+	if len(matches) == 0 {
+		return "", errors.Errorf("no swagger spec found for version %s in %s", version, dir)
+	}
+	return slice.SortOn(func(match string) int {
+		patch := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), strings.TrimPrefix(version, "v")+"."), "-swagger-spec.json")
+		number, err := strconv.Atoi(patch)
+		if err != nil {
+			return -1
+		}
+		return number
+	}, matches)[len(matches)-1], nil
+}
+
+// ReadSpec reads a swagger 2.0 or OpenAPI v3 spec.  path may also be a
+// directory of OpenAPI v3 specs -- one per group version, as served from
+// `/openapi/v3` -- which are merged.
+func ReadSpec(path string) (*Spec, error) {
+	paths := []string{path}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		paths, err = filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to search for specs in %s", path)
+		}
+		if len(paths) == 0 {
+			return nil, errors.Errorf("no specs found in %s", path)
+		}
+	}
+
+	merged := &Spec{Definitions: map[string]*Schema{}}
+	for _, p := range slice.Sort(paths) {
+		spec, err := json.ParseFile[Spec](p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read swagger spec from %s", p)
+		}
+		if spec.Swagger != "" {
+			merged.Swagger = spec.Swagger
+		}
+		if spec.OpenApi != "" {
+			merged.OpenApi = spec.OpenApi
+		}
+		for name, schema := range spec.Definitions {
+			merged.Definitions[name] = schema
+		}
+		if spec.Components != nil {
+			for name, schema := range spec.Components.Schemas {
+				merged.Definitions[name] = normalizeOpenApiV3Schema(schema)
+			}
+		}
+	}
+	return merged, nil
+}
+
+const openApiV3RefPrefix = "#/components/schemas/"
+
+// normalizeOpenApiV3Schema rewrites a schema to look like swagger 2.0: refs
+// point to `#/definitions/`, and `allOf` wrapping a single ref -- which v3 uses
+// to attach a description to a ref -- becomes a plain ref
+func normalizeOpenApiV3Schema(schema *Schema) *Schema {
+	if schema == nil {
+		return nil
+	}
+	if strings.HasPrefix(schema.Ref, openApiV3RefPrefix) {
+		schema.Ref = definitionRefPrefix + strings.TrimPrefix(schema.Ref, openApiV3RefPrefix)
+	}
+	for _, s := range [][]*Schema{schema.AllOf, schema.OneOf, schema.AnyOf} {
+		for _, alternative := range s {
+			normalizeOpenApiV3Schema(alternative)
+		}
+	}
+	if schema.Ref == "" && len(schema.AllOf) == 1 && schema.AllOf[0].Ref != "" {
+		schema.Ref = schema.AllOf[0].Ref
+		schema.AllOf = nil
+	}
+	normalizeOpenApiV3Schema(schema.Items)
+	normalizeOpenApiV3Schema(schema.AdditionalProperties)
+	for _, property := range schema.Properties {
+		normalizeOpenApiV3Schema(property)
+	}
+	return schema
+}
+
+const definitionRefPrefix = "#/definitions/"
+
+// RefDefinition extracts the definition name from a ref such as
+// `#/definitions/io.k8s.api.apps.v1.DeploymentSpec`
+func RefDefinition(ref string) (string, error) {
+	if !strings.HasPrefix(ref, definitionRefPrefix) {
+		return "", errors.Errorf("unsupported $ref '%s': expected prefix '%s'", ref, definitionRefPrefix)
+	}
+	return strings.TrimPrefix(ref, definitionRefPrefix), nil
+}
+
+// ShortName drops the package from a definition name:
+// `io.k8s.api.apps.v1.DeploymentSpec` becomes `DeploymentSpec`
+func ShortName(definition string) string {
+	pieces := strings.Split(definition, ".")
+	return pieces[len(pieces)-1]
+}
@@ -0,0 +1,193 @@
+package swagger
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/set"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"golang.org/x/exp/maps"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type ValidationErrorType string
+
+const (
+	ValidationErrorTypeUnknownField    ValidationErrorType = "unknown field"
+	ValidationErrorTypeWrongType       ValidationErrorType = "wrong type"
+	ValidationErrorTypeMissingRequired ValidationErrorType = "missing required field"
+)
+
+type ValidationError struct {
+	Type ValidationErrorType
+	// Path is the JSON path of the field: `spec.template.spec.containers[0].image`
+	Path    string
+	Message string
+}
+
+// numberOrStringDefinitions are serialized as strings, but also accept numbers.
+// swagger 2.0 specs describe them as strings only.
+var numberOrStringDefinitions = set.FromSlice([]string{
+	"io.k8s.apimachinery.pkg.api.resource.Quantity",
+})
+
+// ValidateObject validates a resource against the definition for its
+// apiVersion and kind
+func (i *Index) ValidateObject(obj map[string]interface{}) ([]*ValidationError, error) {
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	definition, err := i.FindDefinition(fmt.Sprintf("%s.%s", apiVersion, kind))
+	if err != nil {
+		return nil, err
+	}
+	return i.Validate(obj, &Schema{Ref: definitionRefPrefix + definition}), nil
+}
+
+// Validate reports unknown fields, values of the wrong type, and missing
+// required fields, sorted by path
+func (i *Index) Validate(value interface{}, schema *Schema) []*ValidationError {
+	var validationErrors []*ValidationError
+	i.validateHelper("", value, schema, "", &validationErrors)
+	return slice.SortOn(func(e *ValidationError) string { return e.Path }, validationErrors)
+}
+
+func (i *Index) validateHelper(path string, value interface{}, schema *Schema, definition string, validationErrors *[]*ValidationError) {
+	if schema == nil || value == nil {
+		return
+	}
+	if schema.Ref != "" {
+		resolvedDefinition, resolved, err := i.ResolveRef(schema.Ref)
+		if err != nil {
+			// a broken spec shouldn't be blamed on the manifest
+			return
+		}
+		i.validateHelper(path, value, resolved, resolvedDefinition, validationErrors)
+		return
+	}
+	addError := func(errorType ValidationErrorType, path string, message string) {
+		*validationErrors = append(*validationErrors, &ValidationError{Type: errorType, Path: path, Message: message})
+	}
+	wrongType := func(expected string) {
+		addError(ValidationErrorTypeWrongType, path, fmt.Sprintf("expected %s, found %s", expected, JsonType(value)))
+	}
+
+	actual := JsonType(value)
+	switch {
+	case schema.IntOrString || schema.Format == "int-or-string":
+		if actual != "integer" && actual != "string" {
+			wrongType("integer or string")
+		}
+		return
+	case numberOrStringDefinitions.Contains(definition):
+		if actual != "integer" && actual != "number" && actual != "string" {
+			wrongType("number or string")
+		}
+		return
+	}
+	for _, alternatives := range [][]*Schema{schema.OneOf, schema.AnyOf} {
+		if len(alternatives) == 0 {
+			continue
+		}
+		types := slice.Map(func(s *Schema) string { return i.expectedType(s) }, alternatives)
+		if !slice.Any(func(t string) bool { return typeMatches(t, actual) }, types) {
+			wrongType(strings.Join(types, " or "))
+			return
+		}
+	}
+	for _, s := range schema.AllOf {
+		i.validateHelper(path, value, s, definition, validationErrors)
+	}
+
+	if !typeMatches(schema.Type, actual) {
+		wrongType(schema.Type)
+		return
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		for index, item := range v {
+			i.validateHelper(fmt.Sprintf("%s[%d]", path, index), item, schema.Items, "", validationErrors)
+		}
+	case map[string]interface{}:
+		for _, field := range schema.Required {
+			if _, ok := v[field]; !ok {
+				addError(ValidationErrorTypeMissingRequired, fieldPath(path, field), "required field is missing")
+			}
+		}
+		for _, key := range slice.Sort(maps.Keys(v)) {
+			child := fieldPath(path, key)
+			if property, ok := schema.Properties[key]; ok {
+				i.validateHelper(child, v[key], property, "", validationErrors)
+			} else if schema.AdditionalProperties != nil {
+				i.validateHelper(child, v[key], schema.AdditionalProperties, "", validationErrors)
+			} else if schema.EmbeddedResource && (key == "apiVersion" || key == "kind" || key == "metadata") {
+				continue
+			} else if len(schema.Properties) > 0 && !schema.PreserveUnknownFields {
+				addError(ValidationErrorTypeUnknownField, child, "field is not in the schema")
+			}
+		}
+	}
+}
+
+// expectedType is the type a schema requires, following a `$ref`
+func (i *Index) expectedType(schema *Schema) string {
+	if schema.Ref != "" {
+		if _, resolved, err := i.ResolveRef(schema.Ref); err == nil {
+			return i.expectedType(resolved)
+		}
+	}
+	return schema.Type
+}
+
+func typeMatches(expected string, actual string) bool {
+	switch expected {
+	case "":
+		return true
+	case "number":
+		return actual == "number" || actual == "integer"
+	default:
+		return expected == actual
+	}
+}
+
+// JsonType names a parsed value's JSON schema type; integral floats count as
+// integers, as JSON doesn't distinguish them
+func JsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	case float32:
+		return JsonType(float64(v))
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+var identifierRegex = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$-]*$`)
+
+// fieldPath appends a key to a dotted path, quoting keys -- such as annotation
+// and label names -- which contain other characters: `metadata.labels["app.kubernetes.io/name"]`
+func fieldPath(path string, key string) string {
+	if !identifierRegex.MatchString(key) {
+		return fmt.Sprintf("%s[%s]", path, strconv.Quote(key))
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,361 @@
+package tui
+
+import (
+	"fmt"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/mattfenwick/collections/pkg/yaml"
+	"github.com/mattfenwick/kube-utils/pkg/kubernetes"
+	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"strings"
+)
+
+type mode int
+
+const (
+	modeList mode = iota
+	modeDetails
+	modeManifest
+)
+
+var (
+	activeTabStyle   = lipgloss.NewStyle().Bold(true).Reverse(true).Padding(0, 1)
+	inactiveTabStyle = lipgloss.NewStyle().Padding(0, 1)
+	selectedStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	titleStyle       = lipgloss.NewStyle().Bold(true).Underline(true)
+	helpStyle        = lipgloss.NewStyle().Faint(true)
+)
+
+// Explorer browses a model: tabs of items, each with details, links to related
+// items, and a manifest
+type Explorer struct {
+	tabs      []*tab
+	byID      map[string][2]int
+	manifests map[string]string
+
+	mode    mode
+	tab     int
+	cursors []int
+	// filter applies to the current tab, by title
+	filter    textinput.Model
+	filtering bool
+	// linkCursor selects a link in details mode; history holds items to go
+	// back to after following links
+	linkCursor int
+	history    []string
+	manifest   viewport.Model
+	// manifestReturn is the mode to return to from a manifest
+	manifestReturn mode
+
+	width  int
+	height int
+}
+
+func NewExplorer(model *kubernetes.Model, objs []map[string]interface{}) *Explorer {
+	filter := textinput.New()
+	filter.Prompt = "/"
+	e := &Explorer{
+		tabs:      buildTabs(model),
+		byID:      map[string][2]int{},
+		manifests: buildManifests(objs),
+		filter:    filter,
+		manifest:  viewport.New(80, 20),
+		width:     80,
+		height:    24,
+	}
+	e.cursors = make([]int, len(e.tabs))
+	for t, tab := range e.tabs {
+		for i, item := range tab.Items {
+			e.byID[item.ID] = [2]int{t, i}
+		}
+	}
+	return e
+}
+
+func (e *Explorer) Init() tea.Cmd {
+	return nil
+}
+
+// visible are the current tab's items matching the filter
+func (e *Explorer) visible() []*item {
+	query := strings.ToLower(e.filter.Value())
+	return slice.Filter(func(i *item) bool {
+		return strings.Contains(strings.ToLower(i.Title), query)
+	}, e.tabs[e.tab].Items)
+}
+
+func (e *Explorer) selected() *item {
+	items := e.visible()
+	if len(items) == 0 {
+		return nil
+	}
+	return items[e.cursors[e.tab]]
+}
+
+func (e *Explorer) moveCursor(delta int) {
+	count := len(e.visible())
+	cursor := e.cursors[e.tab] + delta
+	if cursor >= count {
+		cursor = count - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	e.cursors[e.tab] = cursor
+}
+
+func (e *Explorer) switchTab(delta int) {
+	e.tab = (e.tab + delta + len(e.tabs)) % len(e.tabs)
+	e.filter.SetValue("")
+	e.moveCursor(0)
+}
+
+// jump selects the item with id, clearing the filter; it returns false for
+// items not in any tab, such as role bindings
+func (e *Explorer) jump(id string) bool {
+	position, ok := e.byID[id]
+	if !ok {
+		return false
+	}
+	e.tab = position[0]
+	e.filter.SetValue("")
+	e.cursors[e.tab] = position[1]
+	e.linkCursor = 0
+	return true
+}
+
+func (e *Explorer) showManifest() {
+	selected := e.selected()
+	if selected == nil {
+		return
+	}
+	content, ok := e.manifests[selected.ManifestID]
+	if !ok {
+		content = fmt.Sprintf("no manifest for %s", selected.ID)
+	}
+	e.manifest.SetContent(content)
+	e.manifest.GotoTop()
+	e.manifestReturn = e.mode
+	e.mode = modeManifest
+}
+
+func (e *Explorer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		e.width, e.height = msg.Width, msg.Height
+		e.manifest.Width, e.manifest.Height = msg.Width, msg.Height-4
+		return e, nil
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return e, tea.Quit
+		}
+		switch {
+		case e.filtering:
+			return e.updateFilter(msg)
+		case e.mode == modeList:
+			return e.updateList(msg)
+		case e.mode == modeDetails:
+			return e.updateDetails(msg)
+		case e.mode == modeManifest:
+			return e.updateManifest(msg)
+		}
+	}
+	return e, nil
+}
+
+func (e *Explorer) updateFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		e.filter.SetValue("")
+		fallthrough
+	case "enter":
+		e.filtering = false
+		e.filter.Blur()
+		e.moveCursor(0)
+		return e, nil
+	}
+	var cmd tea.Cmd
+	e.filter, cmd = e.filter.Update(msg)
+	e.cursors[e.tab] = 0
+	return e, cmd
+}
+
+func (e *Explorer) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q":
+		return e, tea.Quit
+	case "tab", "right", "l":
+		e.switchTab(1)
+	case "shift+tab", "left", "h":
+		e.switchTab(-1)
+	case "up", "k":
+		e.moveCursor(-1)
+	case "down", "j":
+		e.moveCursor(1)
+	case "pgup":
+		e.moveCursor(-e.listHeight())
+	case "pgdown":
+		e.moveCursor(e.listHeight())
+	case "/":
+		e.filtering = true
+		return e, e.filter.Focus()
+	case "esc":
+		e.filter.SetValue("")
+		e.moveCursor(0)
+	case "enter":
+		if e.selected() != nil {
+			e.mode = modeDetails
+			e.linkCursor = 0
+			e.history = nil
+		}
+	case "m":
+		e.showManifest()
+	}
+	return e, nil
+}
+
+func (e *Explorer) updateDetails(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	selected := e.selected()
+	switch msg.String() {
+	case "q":
+		return e, tea.Quit
+	case "up", "k":
+		if e.linkCursor > 0 {
+			e.linkCursor--
+		}
+	case "down", "j":
+		if selected != nil && e.linkCursor < len(selected.Links)-1 {
+			e.linkCursor++
+		}
+	case "enter":
+		if selected != nil && e.linkCursor < len(selected.Links) {
+			if e.jump(selected.Links[e.linkCursor].ID) {
+				e.history = append(e.history, selected.ID)
+			}
+		}
+	case "esc", "backspace":
+		if len(e.history) == 0 {
+			e.mode = modeList
+			return e, nil
+		}
+		previous := e.history[len(e.history)-1]
+		e.history = e.history[:len(e.history)-1]
+		e.jump(previous)
+	case "m":
+		e.showManifest()
+	}
+	return e, nil
+}
+
+func (e *Explorer) updateManifest(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q":
+		return e, tea.Quit
+	case "esc", "m":
+		e.mode = e.manifestReturn
+		return e, nil
+	}
+	var cmd tea.Cmd
+	e.manifest, cmd = e.manifest.Update(msg)
+	return e, cmd
+}
+
+// listHeight is the number of items shown, leaving room for tabs, filter and
+// help
+func (e *Explorer) listHeight() int {
+	if e.height < 6 {
+		return 1
+	}
+	return e.height - 5
+}
+
+func (e *Explorer) View() string {
+	var tabs []string
+	for i, tab := range e.tabs {
+		name := fmt.Sprintf("%s (%d)", tab.Name, len(tab.Items))
+		if i == e.tab {
+			tabs = append(tabs, activeTabStyle.Render(name))
+		} else {
+			tabs = append(tabs, inactiveTabStyle.Render(name))
+		}
+	}
+	header := strings.Join(tabs, " ")
+
+	switch e.mode {
+	case modeDetails:
+		return header + "\n\n" + e.detailsView()
+	case modeManifest:
+		return header + "\n" + e.manifest.View() + "\n" + helpStyle.Render("up/down: scroll  esc: back  q: quit")
+	default:
+		return header + "\n" + e.listView()
+	}
+}
+
+func (e *Explorer) listView() string {
+	items := e.visible()
+	cursor := e.cursors[e.tab]
+	height := e.listHeight()
+	start := 0
+	if cursor >= height {
+		start = cursor - height + 1
+	}
+	var lines []string
+	if e.filtering || e.filter.Value() != "" {
+		lines = append(lines, e.filter.View())
+	} else {
+		lines = append(lines, "")
+	}
+	for i := start; i < len(items) && i < start+height; i++ {
+		if i == cursor {
+			lines = append(lines, selectedStyle.Render("> "+items[i].Title))
+		} else {
+			lines = append(lines, "  "+items[i].Title)
+		}
+	}
+	if len(items) == 0 {
+		lines = append(lines, helpStyle.Render("  no matches"))
+	}
+	lines = append(lines, helpStyle.Render("tab: next kind  up/down: move  /: filter  enter: details  m: manifest  q: quit"))
+	return strings.Join(lines, "\n")
+}
+
+func (e *Explorer) detailsView() string {
+	selected := e.selected()
+	if selected == nil {
+		return ""
+	}
+	lines := []string{titleStyle.Render(selected.ID), ""}
+	lines = append(lines, selected.Details...)
+	lines = append(lines, "", titleStyle.Render("related"))
+	if len(selected.Links) == 0 {
+		lines = append(lines, helpStyle.Render("  none"))
+	}
+	for i, l := range selected.Links {
+		description := l.Description
+		if _, ok := e.byID[l.ID]; !ok {
+			description = helpStyle.Render(description)
+		}
+		if i == e.linkCursor {
+			lines = append(lines, selectedStyle.Render("> ")+description)
+		} else {
+			lines = append(lines, "  "+description)
+		}
+	}
+	lines = append(lines, "", helpStyle.Render("up/down: select  enter: go to  esc: back  m: manifest  q: quit"))
+	return strings.Join(lines, "\n")
+}
+
+type ExploreArgs struct {
+	ChartPath string
+}
+
+func RunExplore(args *ExploreArgs) {
+	objs, err := yaml.ParseManyFromFile[map[string]interface{}](args.ChartPath)
+	utils.DoOrDie(err)
+	explorer := NewExplorer(kubernetes.NewModelFromYaml(objs), objs)
+	_, err = tea.NewProgram(explorer, tea.WithAltScreen()).Run()
+	utils.DoOrDie(err)
+}
@@ -0,0 +1,163 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattfenwick/collections/pkg/yaml"
+	"github.com/mattfenwick/kube-utils/pkg/kubernetes"
+	"strings"
+	"testing"
+)
+
+const testChart = `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+stringData:
+  password: hunter2
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+      - name: api
+        image: example.com/api:1.0
+        env:
+        - name: PASSWORD
+          valueFrom:
+            secretKeyRef: {name: db, key: password}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: example.com/web:1.0
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+spec:
+  template:
+    spec:
+      containers:
+      - name: worker
+        image: example.com/worker:1.0
+        envFrom:
+        - secretRef: {name: db}
+`
+
+func newTestExplorer(t *testing.T) *Explorer {
+	objs, err := yaml.ParseMany[map[string]interface{}]([]byte(testChart))
+	if err != nil {
+		t.Fatalf("unable to parse chart: %+v", err)
+	}
+	return NewExplorer(kubernetes.NewModelFromYaml(objs), objs)
+}
+
+// press sends keys to the explorer: key names such as "enter", or text to type
+func press(e *Explorer, keys ...string) {
+	for _, key := range keys {
+		var msg tea.KeyMsg
+		switch key {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "tab":
+			msg = tea.KeyMsg{Type: tea.KeyTab}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		}
+		e.Update(msg)
+	}
+}
+
+func expectLines(t *testing.T, name string, view string, present []string, absent []string) {
+	for _, line := range present {
+		if !strings.Contains(view, line) {
+			t.Errorf("%s: expected view to contain %q, found:\n%s", name, line, view)
+		}
+	}
+	for _, line := range absent {
+		if strings.Contains(view, line) {
+			t.Errorf("%s: expected view not to contain %q, found:\n%s", name, line, view)
+		}
+	}
+}
+
+func TestExplorerFilter(t *testing.T) {
+	e := newTestExplorer(t)
+	expectLines(t, "unfiltered", e.View(),
+		[]string{"> Deployment/api", "  Deployment/web", "  Deployment/worker"}, nil)
+
+	press(e, "down", "/", "W", "o")
+	if !e.filtering || e.filter.Value() != "Wo" {
+		t.Fatalf("expected to be filtering by Wo, found %t and %q", e.filtering, e.filter.Value())
+	}
+	expectLines(t, "filtering", e.View(),
+		[]string{"/Wo", "> Deployment/worker"}, []string{"Deployment/api", "Deployment/web"})
+
+	press(e, "enter")
+	if e.filtering {
+		t.Errorf("expected enter to stop filtering")
+	}
+	expectLines(t, "filtered", e.View(), []string{"/Wo", "> Deployment/worker"}, []string{"Deployment/web"})
+
+	press(e, "enter")
+	if e.mode != modeDetails || e.selected().ID != "Deployment/worker" {
+		t.Fatalf("expected details of Deployment/worker, found mode %d and %+v", e.mode, e.selected())
+	}
+	press(e, "esc", "esc")
+	if e.mode != modeList || e.filter.Value() != "" {
+		t.Errorf("expected esc to return to the list and clear the filter, found mode %d and filter %q", e.mode, e.filter.Value())
+	}
+	expectLines(t, "cleared", e.View(), []string{"Deployment/api", "Deployment/web", "Deployment/worker"}, nil)
+
+	press(e, "/", "nothing")
+	expectLines(t, "no matches", e.View(), []string{"no matches"}, []string{"Deployment/"})
+	press(e, "esc")
+	if e.filtering || e.filter.Value() != "" || len(e.visible()) != 3 {
+		t.Errorf("expected esc to cancel the filter, found %t, %q and %d items", e.filtering, e.filter.Value(), len(e.visible()))
+	}
+}
+
+func TestExplorerSecretUsages(t *testing.T) {
+	e := newTestExplorer(t)
+	press(e, "tab", "tab")
+	if e.tabs[e.tab].Name != "secrets" {
+		t.Fatalf("expected secrets tab, found %s", e.tabs[e.tab].Name)
+	}
+	expectLines(t, "secrets", e.View(), []string{"secrets (1)", "> db"}, nil)
+
+	press(e, "enter")
+	expectLines(t, "secret details", e.View(),
+		[]string{"Secret/db", "source: chart", "keys: password", "> used by Deployment/api/api", "  used by Deployment/worker/worker"}, nil)
+
+	press(e, "down", "enter")
+	if e.tabs[e.tab].Name != "containers" || e.selected().ID != "Deployment/worker/worker" {
+		t.Fatalf("expected to jump to container Deployment/worker/worker, found %s %+v", e.tabs[e.tab].Name, e.selected())
+	}
+	expectLines(t, "container details", e.View(),
+		[]string{"Deployment/worker/worker", "image: example.com/worker:1.0", "env: envFrom secretRef db", "uses Secret/db"}, nil)
+
+	press(e, "esc")
+	if e.mode != modeDetails || e.selected().ID != "Secret/db" {
+		t.Fatalf("expected esc to go back to Secret/db, found mode %d and %+v", e.mode, e.selected())
+	}
+	press(e, "esc")
+	if e.mode != modeList || e.tabs[e.tab].Name != "secrets" {
+		t.Errorf("expected esc to return to the secrets list, found mode %d and tab %s", e.mode, e.tabs[e.tab].Name)
+	}
+}
@@ -0,0 +1,173 @@
+package tui
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/mattfenwick/collections/pkg/yaml"
+	"github.com/mattfenwick/kube-utils/pkg/kubernetes"
+	"golang.org/x/exp/maps"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"strings"
+)
+
+// link is a related item: one this depends on, or one depending on it
+type link struct {
+	ID          string
+	Description string
+}
+
+type item struct {
+	ID      string
+	Title   string
+	Details []string
+	Links   []*link
+	// ManifestID is the object whose manifest is shown: a container's is its
+	// workload's
+	ManifestID string
+}
+
+type tab struct {
+	Name  string
+	Items []*item
+}
+
+func imageID(image string) string {
+	return kubernetes.ResourceNodeID("Image", image)
+}
+
+// buildTabs lists workloads, containers, secrets, config maps and images,
+// linking each to its neighbors in the model's graph
+func buildTabs(model *kubernetes.Model) []*tab {
+	g := model.Graph()
+	dependencies, dependents := g.Adjacency(), g.ReverseAdjacency()
+	graphLinks := func(id string) []*link {
+		var links []*link
+		for _, to := range dependencies[id] {
+			links = append(links, &link{ID: to, Description: "uses " + to})
+		}
+		for _, from := range dependents[id] {
+			links = append(links, &link{ID: from, Description: "used by " + from})
+		}
+		return links
+	}
+
+	workloads := &tab{Name: "workloads"}
+	containers := &tab{Name: "containers"}
+	imageUsers := map[string][]string{}
+	for _, kind := range slice.Sort(maps.Keys(model.Pods)) {
+		for _, name := range slice.Sort(maps.Keys(model.Pods[kind])) {
+			spec := model.Pods[kind][name]
+			id := kubernetes.ResourceNodeID(kind, name)
+			workloads.Items = append(workloads.Items, &item{
+				ID:    id,
+				Title: id,
+				Details: []string{
+					fmt.Sprintf("replicas: %d", spec.Replicas),
+					fmt.Sprintf("service account: %s", spec.ServiceAccount),
+					fmt.Sprintf("containers: %s", strings.Join(slice.Map(func(c *kubernetes.Container) string { return c.Name }, spec.Containers), ", ")),
+					fmt.Sprintf("image pull secrets: %s", strings.Join(spec.ImagePullSecrets, ", ")),
+				},
+				Links:      graphLinks(id),
+				ManifestID: id,
+			})
+			for _, container := range slice.SortOn(func(c *kubernetes.Container) string { return c.Name }, spec.Containers) {
+				containerID := kubernetes.ContainerNodeID(kind, name, container.Name)
+				imageUsers[container.Image] = append(imageUsers[container.Image], containerID)
+				containers.Items = append(containers.Items, &item{
+					ID:    containerID,
+					Title: containerID,
+					Details: []string{
+						fmt.Sprintf("image: %s", container.Image),
+						fmt.Sprintf("init: %t", container.IsInit),
+						fmt.Sprintf("command: %s", strings.Join(container.Command, " ")),
+						fmt.Sprintf("args: %s", strings.Join(container.Args, " ")),
+						fmt.Sprintf("env: %s", strings.Join(slice.Map(func(e *kubernetes.EnvVar) string {
+							if e.IsEnvFrom() {
+								return e.Description()
+							}
+							return e.Name
+						}, container.Env), ", ")),
+					},
+					Links:      append(graphLinks(containerID), &link{ID: imageID(container.Image), Description: "runs " + container.Image}),
+					ManifestID: id,
+				})
+			}
+		}
+	}
+
+	secrets := &tab{Name: "secrets"}
+	configMaps := &tab{Name: "configmaps"}
+	for _, node := range g.AllNodes() {
+		_, name, _ := strings.Cut(node.ID, "/")
+		switch node.Class {
+		case kubernetes.NodeClassSecret:
+			keys, ok := model.SecretKeys[name]
+			keysDetail := strings.Join(keys, ", ")
+			if !ok {
+				keysDetail = "unknown"
+			}
+			secrets.Items = append(secrets.Items, &item{
+				ID:    node.ID,
+				Title: name,
+				Details: []string{
+					fmt.Sprintf("source: %s", strings.ReplaceAll(model.SecretSource(name), "\n", ", ")),
+					fmt.Sprintf("keys: %s", keysDetail),
+				},
+				Links:      graphLinks(node.ID),
+				ManifestID: node.ID,
+			})
+		case kubernetes.NodeClassConfigMap:
+			keys, ok := model.ConfigMapKeys[name]
+			keysDetail := strings.Join(keys, ", ")
+			if !ok {
+				keysDetail = "unknown: not in chart"
+			}
+			configMaps.Items = append(configMaps.Items, &item{
+				ID:         node.ID,
+				Title:      name,
+				Details:    []string{fmt.Sprintf("keys: %s", keysDetail)},
+				Links:      graphLinks(node.ID),
+				ManifestID: node.ID,
+			})
+		}
+	}
+
+	images := &tab{Name: "images"}
+	for _, image := range slice.Sort(maps.Keys(imageUsers)) {
+		details := []string{}
+		if ref, err := kubernetes.ParseImageReference(image); err == nil {
+			details = append(details,
+				fmt.Sprintf("registry: %s", ref.Registry),
+				fmt.Sprintf("repository: %s", ref.Repository),
+				fmt.Sprintf("tag: %s", ref.Tag),
+				fmt.Sprintf("digest: %s", ref.Digest))
+		}
+		images.Items = append(images.Items, &item{
+			ID:      imageID(image),
+			Title:   image,
+			Details: details,
+			Links: slice.Map(func(id string) *link {
+				return &link{ID: id, Description: "used by " + id}
+			}, imageUsers[image]),
+		})
+	}
+
+	return []*tab{workloads, containers, secrets, configMaps, images}
+}
+
+// buildManifests renders each object as yaml, by `<kind>/<name>`
+func buildManifests(objs []map[string]interface{}) map[string]string {
+	manifests := map[string]string{}
+	for _, obj := range objs {
+		if obj == nil {
+			continue
+		}
+		u := &unstructured.Unstructured{Object: obj}
+		manifest, err := yaml.MarshalString(obj)
+		if err != nil {
+			manifest = fmt.Sprintf("unable to render manifest: %+v", err)
+		}
+		manifests[kubernetes.ResourceNodeID(u.GetKind(), u.GetName())] = manifest
+	}
+	return manifests
+}
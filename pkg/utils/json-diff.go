@@ -62,7 +62,9 @@ func JsonDiffHelper(a interface{}, b interface{}, pathContext []string, diffs *J
 
 	logrus.Debugf("path: %+v", path)
 
-	if a == nil && b != nil {
+	if a == nil && b == nil {
+		return
+	} else if a == nil {
 		diffs.Add(&JDiff{Type: DiffTypeAdd, Old: a, New: b, Path: path})
 	} else if b == nil {
 		diffs.Add(&JDiff{Type: DiffTypeRemove, Old: a, New: b, Path: path})
@@ -85,18 +87,18 @@ func JsonDiffHelper(a interface{}, b interface{}, pathContext []string, diffs *J
 		case []interface{}:
 			switch bVal := b.(type) {
 			case []interface{}:
-				minLength := len(aVal)
-				if len(bVal) < minLength {
-					minLength = len(bVal)
+				maxLength := len(aVal)
+				if len(bVal) > maxLength {
+					maxLength = len(bVal)
 				}
-				for i, aSub := range aVal {
+				for i := 0; i < maxLength; i++ {
 					newPath := append(path, fmt.Sprintf("%d", i))
 					if i >= len(aVal) {
 						diffs.Add(&JDiff{Type: DiffTypeAdd, New: bVal[i], Path: newPath})
 					} else if i >= len(bVal) {
-						diffs.Add(&JDiff{Type: DiffTypeRemove, Old: aSub, Path: newPath})
+						diffs.Add(&JDiff{Type: DiffTypeRemove, Old: aVal[i], Path: newPath})
 					} else {
-						JsonDiffHelper(aSub, bVal[i], newPath, diffs)
+						JsonDiffHelper(aVal[i], bVal[i], newPath, diffs)
 					}
 				}
 			default:
@@ -111,6 +113,15 @@ func JsonDiffHelper(a interface{}, b interface{}, pathContext []string, diffs *J
 			default:
 				diffs.Add(&JDiff{Type: DiffTypeChange, Old: aVal, New: bVal, Path: path})
 			}
+		case float64:
+			switch bVal := b.(type) {
+			case float64:
+				if aVal != bVal {
+					diffs.Add(&JDiff{Type: DiffTypeChange, Old: aVal, New: bVal, Path: path})
+				}
+			default:
+				diffs.Add(&JDiff{Type: DiffTypeChange, Old: aVal, New: bVal, Path: path})
+			}
 		case string:
 			switch bVal := b.(type) {
 			case string:
@@ -0,0 +1,222 @@
+package webui
+
+import (
+	"fmt"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/mattfenwick/collections/pkg/yaml"
+	"github.com/mattfenwick/kube-utils/pkg/graph"
+	"github.com/mattfenwick/kube-utils/pkg/kubernetes"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/exp/maps"
+	"strings"
+	"time"
+)
+
+type EnvVarView struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+type ContainerView struct {
+	Name       string        `json:"name"`
+	Image      string        `json:"image"`
+	Init       bool          `json:"init"`
+	Secrets    []string      `json:"secrets"`
+	ConfigMaps []string      `json:"configMaps"`
+	Env        []*EnvVarView `json:"env"`
+}
+
+type WorkloadView struct {
+	Kind             string           `json:"kind"`
+	Name             string           `json:"name"`
+	Replicas         int32            `json:"replicas"`
+	ServiceAccount   string           `json:"serviceAccount,omitempty"`
+	ImagePullSecrets []string         `json:"imagePullSecrets,omitempty"`
+	Containers       []*ContainerView `json:"containers"`
+}
+
+type SecretView struct {
+	Name   string   `json:"name"`
+	Source string   `json:"source"`
+	Keys   []string `json:"keys"`
+	Usages []string `json:"usages"`
+}
+
+type ConfigMapView struct {
+	Name    string   `json:"name"`
+	InChart bool     `json:"inChart"`
+	Keys    []string `json:"keys"`
+	Usages  []string `json:"usages"`
+}
+
+// ModelView is the model as served by /api/model
+type ModelView struct {
+	Workloads  []*WorkloadView                  `json:"workloads"`
+	Secrets    []*SecretView                    `json:"secrets"`
+	ConfigMaps []*ConfigMapView                 `json:"configMaps"`
+	Images     []*kubernetes.ImageInventoryItem `json:"images"`
+	Skipped    map[string][]string              `json:"skipped"`
+}
+
+func NewModelView(model *kubernetes.Model) *ModelView {
+	view := &ModelView{Skipped: model.Skipped}
+	for _, kind := range slice.Sort(maps.Keys(model.Pods)) {
+		for _, name := range slice.Sort(maps.Keys(model.Pods[kind])) {
+			spec := model.Pods[kind][name]
+			workload := &WorkloadView{Kind: kind, Name: name, Replicas: spec.Replicas, ServiceAccount: spec.ServiceAccount, ImagePullSecrets: spec.ImagePullSecrets}
+			for _, container := range spec.Containers {
+				workload.Containers = append(workload.Containers, &ContainerView{
+					Name:       container.Name,
+					Image:      container.Image,
+					Init:       container.IsInit,
+					Secrets:    container.SecretsSlice(),
+					ConfigMaps: container.ConfigMapsSlice(),
+					Env: slice.Map(func(e *kubernetes.EnvVar) *EnvVarView {
+						return &EnvVarView{Name: e.Name, Source: e.Description()}
+					}, container.Env),
+				})
+			}
+			view.Workloads = append(view.Workloads, workload)
+		}
+	}
+
+	secretsComparison, configMapsComparison := model.GetUsedUnusedSecretsAndConfigMaps()
+	for _, name := range slice.Sort(append(append([]string{}, secretsComparison.JustA...), append(secretsComparison.Both, secretsComparison.JustB...)...)) {
+		view.Secrets = append(view.Secrets, &SecretView{Name: name, Source: model.SecretSource(name), Keys: model.SecretKeys[name], Usages: model.SecretUsages(name)})
+	}
+	for _, name := range slice.Sort(append(append([]string{}, configMapsComparison.JustA...), append(configMapsComparison.Both, configMapsComparison.JustB...)...)) {
+		keys, inChart := model.ConfigMapKeys[name]
+		view.ConfigMaps = append(view.ConfigMaps, &ConfigMapView{Name: name, InChart: inChart, Keys: keys, Usages: model.ConfigMapUsages(name)})
+	}
+
+	// unparsable images are reported by Diagnostics
+	view.Images, _ = model.ParseImageInventory()
+	return view
+}
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem found in the chart, by one of the other reports
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Check    string   `json:"check"`
+	Resource string   `json:"resource,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// Diagnostics collects problems from the secret and config map, image, env,
+// install order and graph reports
+func Diagnostics(model *kubernetes.Model, g *graph.Graph) []*Diagnostic {
+	var diagnostics []*Diagnostic
+	add := func(severity Severity, check string, resource string, message string) {
+		diagnostics = append(diagnostics, &Diagnostic{Severity: severity, Check: check, Resource: resource, Message: message})
+	}
+
+	secretsComparison, configMapsComparison := model.GetUsedUnusedSecretsAndConfigMaps()
+	for _, name := range secretsComparison.JustB {
+		add(SeverityWarning, "secrets", kubernetes.ResourceNodeID("Secret", name), "used, but not in chart")
+	}
+	for _, name := range secretsComparison.JustA {
+		add(SeverityWarning, "secrets", kubernetes.ResourceNodeID("Secret", name), "in chart, but unused")
+	}
+	for _, name := range configMapsComparison.JustB {
+		add(SeverityWarning, "configmaps", kubernetes.ResourceNodeID("ConfigMap", name), "used, but not in chart")
+	}
+	for _, name := range configMapsComparison.JustA {
+		add(SeverityWarning, "configmaps", kubernetes.ResourceNodeID("ConfigMap", name), "in chart, but unused")
+	}
+
+	inventory, invalid := model.ParseImageInventory()
+	for _, violation := range invalid {
+		add(SeverityError, "images", violation.Image, fmt.Sprintf("%s: %s", violation.Rule, violation.Details))
+	}
+	for _, violation := range kubernetes.InconsistentImageVersions(inventory) {
+		add(SeverityWarning, "images", violation.Image, fmt.Sprintf("%s: %s", violation.Rule, violation.Details))
+	}
+
+	for _, kind := range slice.Sort(maps.Keys(model.Pods)) {
+		for _, name := range slice.Sort(maps.Keys(model.Pods[kind])) {
+			for _, container := range model.Pods[kind][name].Containers {
+				env := model.ResolveContainerEnv(container)
+				if len(env.Undefined) > 0 {
+					add(SeverityWarning, "env", kubernetes.ContainerNodeID(kind, name, container.Name),
+						fmt.Sprintf("command or args reference undefined variables: %s", strings.Join(env.Undefined, ", ")))
+				}
+			}
+		}
+	}
+
+	for _, problem := range model.InstallOrder("install").Problems {
+		add(SeverityError, "install order", problem.Resource, problem.Problem)
+	}
+
+	for _, cycle := range g.Cycles() {
+		add(SeverityWarning, "graph", "", fmt.Sprintf("dependency cycle: %s", strings.Join(cycle, ", ")))
+	}
+	return diagnostics
+}
+
+// Analysis is everything served, from one read of the chart
+type Analysis struct {
+	ChartPath   string            `json:"chartPath"`
+	AnalyzedAt  time.Time         `json:"analyzedAt"`
+	Error       string            `json:"error,omitempty"`
+	Model       *ModelView        `json:"-"`
+	Graph       *graph.Graph      `json:"-"`
+	Tables      map[string]string `json:"-"`
+	Diagnostics []*Diagnostic     `json:"-"`
+}
+
+// Analyze reads and analyzes the chart; errors are recorded in the analysis, so
+// that they can be shown until the chart is fixed
+func Analyze(chartPath string) *Analysis {
+	analysis := &Analysis{ChartPath: chartPath, AnalyzedAt: time.Now()}
+	if err := analysis.run(); err != nil {
+		logrus.Debugf("unable to analyze %s: %+v", chartPath, err)
+		analysis.Error = err.Error()
+		analysis.Diagnostics = []*Diagnostic{{Severity: SeverityError, Check: "parse", Message: err.Error()}}
+	}
+	return analysis
+}
+
+func (a *Analysis) run() (err error) {
+	// malformed objects -- such as ones without metadata -- may panic
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("unable to analyze %s: %v", a.ChartPath, r)
+		}
+	}()
+
+	objs, err := yaml.ParseManyFromFile[map[string]interface{}](a.ChartPath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to read %s", a.ChartPath)
+	}
+	model, err := kubernetes.ParseModel(objs)
+	if err != nil {
+		return err
+	}
+	a.Model = NewModelView(model)
+	a.Graph = model.Graph()
+	a.Diagnostics = Diagnostics(model, a.Graph)
+
+	skipped, secrets, configMaps, images, pods := model.BuildTables()
+	a.Tables = map[string]string{
+		"skipped":    skipped,
+		"secrets":    secrets,
+		"configmaps": configMaps,
+		"images":     images,
+		"env":        model.EnvTable(),
+		"commands":   model.CommandsTable(),
+		"resources":  model.ResourceReport().Table(),
+	}
+	for kind, table := range pods {
+		a.Tables["kind: "+kind] = table
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package webui
+
+import (
+	_ "embed"
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/mattfenwick/kube-utils/pkg/graph"
+	"github.com/mattfenwick/kube-utils/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed index.html
+var indexHtml []byte
+
+// Server serves the latest analysis of a chart, re-analyzing when it changes
+type Server struct {
+	ChartPath string
+	lock      sync.RWMutex
+	analysis  *Analysis
+	// modTime and size are the chart's, as of the latest analysis
+	modTime time.Time
+	size    int64
+}
+
+func NewServer(chartPath string) *Server {
+	s := &Server{ChartPath: chartPath}
+	s.modTime, s.size = s.stat()
+	s.analysis = Analyze(chartPath)
+	return s
+}
+
+func (s *Server) stat() (time.Time, int64) {
+	info, err := os.Stat(s.ChartPath)
+	if err != nil {
+		return time.Time{}, -1
+	}
+	return info.ModTime(), info.Size()
+}
+
+func (s *Server) current() *Analysis {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.analysis
+}
+
+func (s *Server) reanalyze() {
+	analysis := Analyze(s.ChartPath)
+	if analysis.Error != "" {
+		logrus.Errorf("unable to analyze %s: %s", s.ChartPath, analysis.Error)
+	} else {
+		logrus.Infof("analyzed %s", s.ChartPath)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.analysis = analysis
+}
+
+// Watch polls the chart's modification time and size, re-analyzing when
+// either changes -- including before Watch is called -- until stop is closed
+func (s *Server) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			modTime, size := s.stat()
+			if modTime.Equal(s.modTime) && size == s.size {
+				continue
+			}
+			logrus.Infof("%s changed", s.ChartPath)
+			s.modTime, s.size = modTime, size
+			s.reanalyze()
+		}
+	}
+}
+
+func writeJson(w http.ResponseWriter, obj interface{}) {
+	bytes, err := json.MarshalWithOptions(obj, &json.MarshalOptions{EscapeHTML: true, Indent: true, Sort: false})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(bytes)
+}
+
+type GraphNodeView struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Class string `json:"class"`
+}
+
+type GraphEdgeView struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Class string `json:"class"`
+}
+
+type GraphView struct {
+	Nodes  []*GraphNodeView `json:"nodes"`
+	Edges  []*GraphEdgeView `json:"edges"`
+	Cycles [][]string       `json:"cycles"`
+}
+
+func NewGraphView(g *graph.Graph) *GraphView {
+	view := &GraphView{Cycles: g.Cycles()}
+	for _, id := range g.NodeIDs() {
+		node := &GraphNodeView{ID: id, Label: id}
+		if n, ok := g.FindNode(id); ok {
+			node.Label, node.Class = n.Label, n.Class
+		}
+		view.Nodes = append(view.Nodes, node)
+	}
+	for _, edge := range g.AllEdges() {
+		view.Edges = append(view.Edges, &GraphEdgeView{From: edge.From, To: edge.To, Class: edge.Class})
+	}
+	return view
+}
+
+// SearchResult is a node whose ID matches a search, with its neighbors and
+// the nodes affected by changing it
+type SearchResult struct {
+	ID      string   `json:"id"`
+	Class   string   `json:"class"`
+	Uses    []string `json:"uses"`
+	UsedBy  []string `json:"usedBy"`
+	Impacts []string `json:"impacts"`
+}
+
+func search(g *graph.Graph, query string) []*SearchResult {
+	query = strings.ToLower(query)
+	dependencies, dependents := g.Adjacency(), g.ReverseAdjacency()
+	results := []*SearchResult{}
+	for _, id := range g.NodeIDs() {
+		if !strings.Contains(strings.ToLower(id), query) {
+			continue
+		}
+		result := &SearchResult{ID: id, Uses: dependencies[id], UsedBy: dependents[id], Impacts: g.ReverseReachable(id)}
+		if node, ok := g.FindNode(id); ok {
+			result.Class = node.Class
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// withGraph responds with an error while the chart can't be analyzed
+func (s *Server) withGraph(w http.ResponseWriter, f func(*Analysis)) {
+	analysis := s.current()
+	if analysis.Error != "" {
+		http.Error(w, analysis.Error, http.StatusServiceUnavailable)
+		return
+	}
+	f(analysis)
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(indexHtml)
+	})
+	mux.HandleFunc("/graph", func(w http.ResponseWriter, r *http.Request) {
+		s.withGraph(w, func(a *Analysis) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(a.Graph.RenderAsHtml(filepath.Base(a.ChartPath))))
+		})
+	})
+	mux.HandleFunc("/api/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJson(w, s.current())
+	})
+	mux.HandleFunc("/api/model", func(w http.ResponseWriter, r *http.Request) {
+		s.withGraph(w, func(a *Analysis) { writeJson(w, a.Model) })
+	})
+	mux.HandleFunc("/api/graph", func(w http.ResponseWriter, r *http.Request) {
+		s.withGraph(w, func(a *Analysis) {
+			switch r.URL.Query().Get("format") {
+			case "", "json":
+				writeJson(w, NewGraphView(a.Graph))
+			case "dot":
+				w.Header().Set("Content-Type", "text/vnd.graphviz")
+				_, _ = w.Write([]byte(a.Graph.RenderAsDot()))
+			case "svg":
+				w.Header().Set("Content-Type", "image/svg+xml")
+				_, _ = w.Write([]byte(a.Graph.RenderAsSvg()))
+			default:
+				http.Error(w, "invalid format; one of [json, dot, svg]", http.StatusBadRequest)
+			}
+		})
+	})
+	mux.HandleFunc("/api/diagnostics", func(w http.ResponseWriter, r *http.Request) {
+		// parse errors are diagnostics too
+		writeJson(w, s.current().Diagnostics)
+	})
+	mux.HandleFunc("/api/tables", func(w http.ResponseWriter, r *http.Request) {
+		s.withGraph(w, func(a *Analysis) { writeJson(w, a.Tables) })
+	})
+	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		s.withGraph(w, func(a *Analysis) { writeJson(w, search(a.Graph, r.URL.Query().Get("q"))) })
+	})
+	return mux
+}
+
+type ServeArgs struct {
+	ChartPath    string
+	Address      string
+	PollInterval time.Duration
+}
+
+func RunServe(args *ServeArgs) {
+	if args.PollInterval <= 0 {
+		utils.DoOrDie(errors.Errorf("invalid poll interval %s", args.PollInterval))
+	}
+	server := NewServer(args.ChartPath)
+	if analysis := server.current(); analysis.Error != "" {
+		logrus.Errorf("unable to analyze %s: %s", args.ChartPath, analysis.Error)
+	}
+	go server.Watch(args.PollInterval, make(chan struct{}))
+
+	logrus.Infof("serving analysis of %s at http://%s/ (api: %s)", args.ChartPath, args.Address,
+		strings.Join(slice.Map(func(p string) string { return "/api/" + p }, []string{"model", "graph", "diagnostics", "tables", "search"}), ", "))
+	utils.DoOrDie(errors.Wrapf(http.ListenAndServe(args.Address, server.Handler()), "unable to serve on %s", args.Address))
+}
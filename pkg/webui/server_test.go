@@ -0,0 +1,199 @@
+package webui
+
+import (
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testChart = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  mode: debug
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: app
+        image: example.com/app:1.0
+        env:
+        - name: PASSWORD
+          valueFrom:
+            secretKeyRef: {name: db, key: password}
+        envFrom:
+        - configMapRef: {name: config}
+`
+
+func writeTestChart(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "chart.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("unable to write %s: %+v", path, err)
+	}
+	return path
+}
+
+func get(t *testing.T, handler http.Handler, url string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+	return recorder
+}
+
+func parseResponse[T any](t *testing.T, url string, response *httptest.ResponseRecorder) *T {
+	if response.Code != http.StatusOK {
+		t.Fatalf("%s: expected status 200, found %d: %s", url, response.Code, response.Body.String())
+	}
+	if contentType := response.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("%s: expected content type application/json, found %s", url, contentType)
+	}
+	obj, err := json.Parse[T](response.Body.Bytes())
+	if err != nil {
+		t.Fatalf("%s: unable to parse response: %+v", url, err)
+	}
+	return obj
+}
+
+func TestServerModel(t *testing.T) {
+	handler := NewServer(writeTestChart(t, testChart)).Handler()
+	model := parseResponse[ModelView](t, "/api/model", get(t, handler, "/api/model"))
+
+	if len(model.Workloads) != 1 {
+		t.Fatalf("expected 1 workload, found %d", len(model.Workloads))
+	}
+	workload := model.Workloads[0]
+	if workload.Kind != "Deployment" || workload.Name != "app" || workload.Replicas != 2 {
+		t.Errorf("expected Deployment/app with 2 replicas, found %s/%s with %d", workload.Kind, workload.Name, workload.Replicas)
+	}
+	if len(workload.Containers) != 1 || workload.Containers[0].Image != "example.com/app:1.0" {
+		t.Errorf("expected container with image example.com/app:1.0, found %+v", workload.Containers)
+	}
+	if len(model.Secrets) != 1 || model.Secrets[0].Name != "db" || model.Secrets[0].Source != "unknown" {
+		t.Errorf("expected secret db from an unknown source, found %+v", model.Secrets)
+	}
+	if len(model.ConfigMaps) != 1 || model.ConfigMaps[0].Name != "config" || !model.ConfigMaps[0].InChart {
+		t.Errorf("expected config map config in chart, found %+v", model.ConfigMaps)
+	}
+}
+
+func TestServerGraph(t *testing.T) {
+	handler := NewServer(writeTestChart(t, testChart)).Handler()
+
+	view := parseResponse[GraphView](t, "/api/graph", get(t, handler, "/api/graph"))
+	ids := slice.Map(func(n *GraphNodeView) string { return n.ID }, view.Nodes)
+	for _, id := range []string{"Deployment/app", "Deployment/app/app", "Secret/db", "ConfigMap/config"} {
+		if !slice.Any(func(found string) bool { return found == id }, ids) {
+			t.Errorf("expected node %s, found %+v", id, ids)
+		}
+	}
+	if len(view.Edges) == 0 {
+		t.Errorf("expected edges, found none")
+	}
+
+	for _, testCase := range []struct {
+		Format      string
+		Status      int
+		ContentType string
+		Prefix      string
+	}{
+		{Format: "json", Status: http.StatusOK, ContentType: "application/json", Prefix: "{"},
+		{Format: "dot", Status: http.StatusOK, ContentType: "text/vnd.graphviz", Prefix: "digraph"},
+		{Format: "svg", Status: http.StatusOK, ContentType: "image/svg+xml", Prefix: "<svg"},
+		{Format: "png", Status: http.StatusBadRequest, ContentType: "text/plain; charset=utf-8", Prefix: "invalid format"},
+	} {
+		response := get(t, handler, "/api/graph?format="+testCase.Format)
+		if response.Code != testCase.Status {
+			t.Errorf("%s: expected status %d, found %d", testCase.Format, testCase.Status, response.Code)
+		}
+		if contentType := response.Header().Get("Content-Type"); contentType != testCase.ContentType {
+			t.Errorf("%s: expected content type %s, found %s", testCase.Format, testCase.ContentType, contentType)
+		}
+		if body := strings.TrimSpace(response.Body.String()); !strings.HasPrefix(body, testCase.Prefix) {
+			t.Errorf("%s: expected body starting with %s, found %.40s", testCase.Format, testCase.Prefix, body)
+		}
+	}
+}
+
+func TestServerDiagnostics(t *testing.T) {
+	handler := NewServer(writeTestChart(t, testChart)).Handler()
+	diagnostics := parseResponse[[]*Diagnostic](t, "/api/diagnostics", get(t, handler, "/api/diagnostics"))
+
+	expected := &Diagnostic{Severity: SeverityWarning, Check: "secrets", Resource: "Secret/db", Message: "used, but not in chart"}
+	if !slice.Any(func(d *Diagnostic) bool { return reflect.DeepEqual(d, expected) }, *diagnostics) {
+		t.Errorf("expected diagnostic %+v, found %+v", expected, *diagnostics)
+	}
+}
+
+func TestServerSearch(t *testing.T) {
+	handler := NewServer(writeTestChart(t, testChart)).Handler()
+
+	results := *parseResponse[[]*SearchResult](t, "/api/search?q=DB", get(t, handler, "/api/search?q=DB"))
+	if len(results) != 1 || results[0].ID != "Secret/db" {
+		t.Fatalf("expected only Secret/db, found %+v", results)
+	}
+	if !reflect.DeepEqual(results[0].UsedBy, []string{"Deployment/app/app"}) {
+		t.Errorf("expected Secret/db to be used by Deployment/app/app, found %+v", results[0].UsedBy)
+	}
+	if !slice.Any(func(id string) bool { return id == "Deployment/app" }, results[0].Impacts) {
+		t.Errorf("expected Secret/db to impact Deployment/app, found %+v", results[0].Impacts)
+	}
+
+	noResults := *parseResponse[[]*SearchResult](t, "/api/search?q=nothing", get(t, handler, "/api/search?q=nothing"))
+	if len(noResults) != 0 {
+		t.Errorf("expected no results, found %+v", noResults)
+	}
+}
+
+func TestServerUnparsableChart(t *testing.T) {
+	handler := NewServer(writeTestChart(t, "kind: [unterminated")).Handler()
+
+	for _, url := range []string{"/api/model", "/api/graph?format=dot", "/api/search?q=db"} {
+		if response := get(t, handler, url); response.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected status 503, found %d", url, response.Code)
+		}
+	}
+	diagnostics := *parseResponse[[]*Diagnostic](t, "/api/diagnostics", get(t, handler, "/api/diagnostics"))
+	if len(diagnostics) != 1 || diagnostics[0].Check != "parse" || diagnostics[0].Severity != SeverityError {
+		t.Errorf("expected a parse error, found %+v", diagnostics)
+	}
+}
+
+func TestWatchReanalyzes(t *testing.T) {
+	path := writeTestChart(t, "kind: [unterminated")
+	server := NewServer(path)
+	if server.current().Error == "" {
+		t.Fatalf("expected an error analyzing an unparsable chart")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go server.Watch(10*time.Millisecond, stop)
+
+	if err := os.WriteFile(path, []byte(testChart), 0644); err != nil {
+		t.Fatalf("unable to rewrite %s: %+v", path, err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for server.current().Error != "" {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected re-analysis after rewriting chart, found error %s", server.current().Error)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if workloads := server.current().Model.Workloads; len(workloads) != 1 || workloads[0].Name != "app" {
+		t.Errorf("expected workload app after re-analysis, found %+v", workloads)
+	}
+}
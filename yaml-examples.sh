@@ -5,3 +5,54 @@ set -euo pipefail
 
 ## yaml
 go run cmd/api-inspector/main.go analyze-yaml --path ./example.yaml
+
+# fail if any apiVersions are removed in kubernetes 1.25
+go run cmd/api-inspector/main.go analyze-yaml --chart-path ./example.yaml --target-version 1.25
+
+# check apiVersions against data from kube/collect-data.sh
+go run cmd/api-inspector/main.go analyze-yaml --chart-path ./example.yaml --api-resources-path ./kind-data/v1.23.0-api-resources.txt --api-versions-path ./kind-data/v1.23.0-api-versions.txt
+
+# validate objects against the kubernetes 1.25 schemas
+go run cmd/api-inspector/main.go analyze-yaml --chart-path ./example.yaml --target-version 1.25 --validate
+
+# document a chart's values.yaml; add --readme ./README.md --check in CI
+go run cmd/api-inspector/main.go values-doc --values-path ./python/test-values.yaml
+
+# find values.yaml keys unused by templates, and template references missing from values.yaml
+go run cmd/api-inspector/main.go values-usage --chart-path ./my-chart --check
+
+# total resource requests and limits, accounting for replicas
+go run cmd/api-inspector/main.go resource-report --chart-path ./example.yaml -o csv
+
+# list images, failing if any come from other registries or use latest
+go run cmd/api-inspector/main.go image-report --chart-path ./example.yaml --allowed-registry docker.io,gcr.io/my-project --disallow-latest
+
+# count critical and high vulnerabilities per workload, from a directory of trivy or CycloneDX JSON reports
+go run cmd/api-inspector/main.go analyze-yaml --chart-path ./example.yaml --vulnerability-reports-dir ./vulnerability-reports
+
+# env vars per container, with their sources, shadowing, and interpolated commands
+go run cmd/api-inspector/main.go analyze-yaml --chart-path ./example.yaml --resources env
+
+# analyze what's deployed, using the current kubeconfig context
+go run cmd/api-inspector/main.go analyze-yaml --from-cluster --namespace my-namespace
+
+# compare rendered manifests to what's deployed, ignoring paths from a config file
+go run cmd/api-inspector/main.go drift --chart-path ./example.yaml --namespace my-namespace --config-path ./drift-config.yaml
+
+# order resources by helm hook phase and weight, flagging hooks which use secrets or config maps created later
+go run cmd/api-inspector/main.go install-order --chart-path ./example.yaml --event upgrade --check
+
+# find the workloads and containers affected by changing a secret
+go run cmd/api-inspector/main.go impact secret/my-secret --chart-path ./example.yaml
+
+# draw a chart's dependency graph as a single html file, without graphviz
+go run cmd/api-inspector/main.go graph --chart-path ./example.yaml -o html > graph.html
+
+# or as a png image
+go run cmd/api-inspector/main.go graph --chart-path ./example.yaml -o png > graph.png
+
+# browse a chart in a terminal ui: filter by name, follow links between secrets and their usages, view manifests
+go run cmd/api-inspector/main.go explore --chart-path ./example.yaml
+
+# serve a local web ui and json api, re-analyzing when the file changes
+go run cmd/api-inspector/main.go serve --chart-path ./example.yaml --address 127.0.0.1:8080